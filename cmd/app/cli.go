@@ -0,0 +1,274 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"forum/internal/entity"
+	"forum/internal/repository"
+	"forum/internal/service"
+	"forum/pkg/mailer"
+	"forum/pkg/sesm/sqlite3store"
+	"io"
+	"log"
+	"os"
+	"strings"
+)
+
+const usage = `Usage: forum [command]
+
+Without command the HTTP server is started.
+
+Commands:
+  migrate up|down <version>|version     manage database schema
+  user create -username -email -password [-role user|moderator|admin]
+  user promote -username [-role moderator|admin]
+  user reset-2fa -username               turn off two-factor auth of locked out user
+  sessions purge [-username] [-expired]
+  tags import [-file path]               one tag per line, stdin by default
+`
+
+// runCommand executes administrative subcommand given in args (without binary
+// name). Business rules are enforced by the same services that are used by
+// HTTP handlers
+func runCommand(db *sql.DB, logger *log.Logger, m mailer.Mailer, baseURL, appName string, args []string) error {
+	switch args[0] {
+	case "migrate":
+		return migrateCmd(db, logger, args[1:])
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+		return nil
+	}
+
+	// Other commands work with data, so the schema should be up to date
+	if err := runMigrations(db, logger); err != nil {
+		return err
+	}
+
+	s := service.New(repository.New(db), m, baseURL, appName)
+
+	switch args[0] {
+	case "user":
+		return userCmd(s, logger, args[1:])
+	case "sessions":
+		return sessionsCmd(db, s, logger, args[1:])
+	case "tags":
+		return tagsCmd(s, logger, args[1:])
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		return fmt.Errorf("unknown command: %s", args[0])
+	}
+}
+
+func userCmd(s *service.Services, logger *log.Logger, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: user create|promote|reset-2fa [flags]")
+	}
+
+	switch args[0] {
+	case "create":
+		fs := flag.NewFlagSet("user create", flag.ContinueOnError)
+		username := fs.String("username", "", "username of new user")
+		email := fs.String("email", "", "email of new user")
+		password := fs.String("password", "", "password of new user")
+		role := fs.String("role", entity.USER, "role of new user (user, moderator or admin)")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		form := entity.UserSignupForm{
+			Username: *username,
+			Email:    strings.ToLower(*email),
+			Password: *password,
+		}
+
+		id, err := s.User.SaveUser(&form)
+		if err != nil {
+			if msg := strings.TrimSpace(formErrors(&form)); msg != "" {
+				return fmt.Errorf("%w\n%s", err, msg)
+			}
+			return err
+		}
+
+		// Accounts created by admin don't need to confirm email address
+		if err := s.Account.MarkVerified(id); err != nil {
+			return err
+		}
+
+		if *role != entity.USER {
+			if err := s.User.SetUserRole(0, id, *role); err != nil {
+				return err
+			}
+		}
+
+		logger.Printf("Created user '%s' (id - %d, role - %s)", form.Username, id, *role)
+	case "promote":
+		fs := flag.NewFlagSet("user promote", flag.ContinueOnError)
+		username := fs.String("username", "", "username of user to promote")
+		role := fs.String("role", entity.MODERATOR, "new role (moderator or admin)")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		user, err := s.User.GetUserByUsername(*username)
+		if err != nil {
+			if errors.Is(err, entity.ErrInvalidCredentials) {
+				return entity.ErrUserNotFound
+			}
+			return err
+		}
+
+		switch *role {
+		case entity.MODERATOR:
+			err = s.User.PromoteUser(0, user.ID)
+		case entity.ADMIN:
+			err = s.User.PromoteToAdmin(0, user.ID)
+		default:
+			err = entity.ErrInvalidRole
+		}
+		if err != nil {
+			return err
+		}
+
+		logger.Printf("User '%s' is now %s", user.Username, *role)
+	case "reset-2fa":
+		fs := flag.NewFlagSet("user reset-2fa", flag.ContinueOnError)
+		username := fs.String("username", "", "username of user that lost authenticator app")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		user, err := s.User.GetUserByUsername(*username)
+		if err != nil {
+			if errors.Is(err, entity.ErrInvalidCredentials) {
+				return entity.ErrUserNotFound
+			}
+			return err
+		}
+
+		if err := s.TwoFactor.Reset(0, user.ID); err != nil {
+			return err
+		}
+
+		logger.Printf("Two-factor authentication of '%s' is turned off", user.Username)
+	default:
+		return fmt.Errorf("unknown user command: %s", args[0])
+	}
+
+	return nil
+}
+
+func sessionsCmd(db *sql.DB, s *service.Services, logger *log.Logger, args []string) error {
+	if len(args) == 0 || args[0] != "purge" {
+		return errors.New("usage: sessions purge [flags]")
+	}
+
+	fs := flag.NewFlagSet("sessions purge", flag.ContinueOnError)
+	username := fs.String("username", "", "purge only sessions of this user")
+	expired := fs.Bool("expired", false, "purge only expired sessions")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	store := sqlite3store.New(db)
+	ctx := context.Background()
+
+	switch {
+	case *username != "":
+		user, err := s.User.GetUserByUsername(*username)
+		if err != nil {
+			if errors.Is(err, entity.ErrInvalidCredentials) {
+				return entity.ErrUserNotFound
+			}
+			return err
+		}
+		if err := store.StoreDeleteAll(ctx, user.ID); err != nil {
+			return err
+		}
+		logger.Printf("Purged sessions of user '%s'", user.Username)
+	case *expired:
+		n, err := store.PurgeExpired(ctx)
+		if err != nil {
+			return err
+		}
+		logger.Printf("Purged %d expired sessions", n)
+	default:
+		n, err := store.PurgeAll(ctx)
+		if err != nil {
+			return err
+		}
+		logger.Printf("Purged %d sessions", n)
+	}
+
+	return nil
+}
+
+func tagsCmd(s *service.Services, logger *log.Logger, args []string) error {
+	if len(args) == 0 || args[0] != "import" {
+		return errors.New("usage: tags import [flags]")
+	}
+
+	fs := flag.NewFlagSet("tags import", flag.ContinueOnError)
+	file := fs.String("file", "", "file with tag names, one per line (stdin if empty)")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	var in io.Reader = os.Stdin
+	if *file != "" {
+		f, err := os.Open(*file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	var created, skipped int
+
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		name := strings.TrimSpace(scanner.Text())
+		if name == "" || strings.HasPrefix(name, "#") {
+			continue
+		}
+
+		err := s.Tag.CreateTag(0, name)
+		switch {
+		case err == nil:
+			created++
+		case errors.Is(err, entity.ErrDuplicateTag):
+			skipped++
+			logger.Printf("Tag '%s' already exists, skipping", name)
+		case errors.Is(err, entity.ErrInvalidTag):
+			skipped++
+			logger.Printf("Tag '%s' is invalid (should be from 3 to 30 characters long), skipping", name)
+		default:
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	logger.Printf("Imported %d tags, skipped %d", created, skipped)
+
+	return nil
+}
+
+// formErrors returns form validation errors one per line
+func formErrors(form *entity.UserSignupForm) string {
+	var msg string
+
+	for _, str := range form.NonFieldErrors {
+		msg += str + "\n"
+	}
+	for key, val := range form.FieldErrors {
+		msg += key + ": " + val + "\n"
+	}
+
+	return msg
+}
@@ -3,6 +3,8 @@ package main
 import (
 	"forum/config"
 	"forum/pkg/database/sqlite3"
+	"forum/pkg/mailer"
+	"forum/pkg/realip"
 	"forum/pkg/sesm"
 	"forum/pkg/sesm/sqlite3store"
 	"log"
@@ -30,11 +32,32 @@ func main() {
 		log.Fatalf("Error opening database connection:%v", err)
 	}
 
+	m, err := newMailer(cfg)
+	if err != nil {
+		db.Close()
+		logger.Fatalf("Error creating mailer:%v", err)
+	}
+
+	if len(os.Args) > 1 {
+		err := runCommand(db, logger, m, cfg.BaseURL, cfg.Name, os.Args[1:])
+		db.Close()
+		if err != nil {
+			logger.Fatalf("Command error:%v", err)
+		}
+		return
+	}
+
+	if err := runMigrations(db, logger); err != nil {
+		db.Close()
+		logger.Fatalf("Error applying migrations:%v", err)
+	}
+
 	r := repository.New(db)
-	s := service.New(r)
+	s := service.New(r, m, cfg.BaseURL, cfg.Name)
 
 	sesm := sesm.New()
 	sesm.Store = sqlite3store.New(db)
+	sesm.ClientIP = realip.FromRequest
 
 	routes := handlers.NewRouter(
 		s,
@@ -67,3 +90,22 @@ func main() {
 	err = server.ListenAndServe()
 	logger.Fatalf("Listen and serve error:%v", err)
 }
+
+// newMailer returns SMTP mailer if SMTP server is configured. Otherwise emails
+// are written to the mail file or stdout, which is enough for development
+func newMailer(cfg *config.Config) (mailer.Mailer, error) {
+	if cfg.Mail.SMTPHost != "" {
+		return mailer.NewSMTP(cfg.Mail.SMTPHost, cfg.Mail.SMTPPort, cfg.Mail.SMTPUsername, cfg.Mail.SMTPPassword, cfg.Mail.From), nil
+	}
+
+	if cfg.Mail.File == "" {
+		return mailer.NewLog(os.Stdout, cfg.Mail.From), nil
+	}
+
+	file, err := os.OpenFile(cfg.Mail.File, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	return mailer.NewLog(file, cfg.Mail.From), nil
+}
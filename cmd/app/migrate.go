@@ -0,0 +1,80 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"forum/migrations"
+	"forum/pkg/migrate"
+	"log"
+	"strconv"
+)
+
+// runMigrations applies all pending migrations and logs applied versions
+func runMigrations(db *sql.DB, logger *log.Logger) error {
+	m, err := migrate.New(db, migrations.Files)
+	if err != nil {
+		return err
+	}
+
+	applied, err := m.Up()
+	for _, v := range applied {
+		logger.Printf("Applied migration: %03d", v)
+	}
+
+	return err
+}
+
+// migrateCmd handles 'migrate' subcommand:
+//
+//	migrate up             - apply all pending migrations
+//	migrate down <version> - roll back to given version (0 rolls back everything)
+//	migrate version        - print current schema version
+func migrateCmd(db *sql.DB, logger *log.Logger, args []string) error {
+	m, err := migrate.New(db, migrations.Files)
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 0 {
+		args = []string{"up"}
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := m.Up()
+		for _, v := range applied {
+			logger.Printf("Applied migration: %03d", v)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			logger.Print("Nothing to migrate")
+		}
+	case "down":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: migrate down <version>")
+		}
+		version, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid version: %s", args[1])
+		}
+		rolledBack, err := m.Down(version)
+		for _, v := range rolledBack {
+			logger.Printf("Rolled back migration: %03d", v)
+		}
+		if err != nil {
+			return err
+		}
+	case "version":
+		version, err := m.Version()
+		if err != nil {
+			return err
+		}
+		fmt.Println(version)
+	default:
+		return fmt.Errorf("unknown migrate command: %s", args[0])
+	}
+
+	return nil
+}
@@ -2,8 +2,12 @@ package config
 
 import (
 	"bufio"
+	"forum/pkg/oauth"
+	"forum/pkg/realip"
 	"log"
+	"net/netip"
 	"os"
+	"regexp"
 	"strconv"
 	"strings"
 )
@@ -13,32 +17,46 @@ type (
 		App
 		Http
 		Database
+		Mail
 		ExternalAuth
 	}
 
 	App struct {
-		Name    string
-		Version string
+		Name     string
+		Version  string
+		PageSize int
+		BaseURL  string
 	}
 
+	// Every client can make RateLimit requests per RateInterval seconds on
+	// average and up to RateBurst requests at once. Client address is read
+	// from forwarding headers only if they are sent by TrustedProxies
 	Http struct {
-		Addr         string
-		RateInterval int
-		RateLimit    int
-		RatePenalty  int
+		Addr           string
+		RateInterval   int
+		RateLimit      int
+		RateBurst      int
+		TrustedProxies []netip.Prefix
 	}
 
 	Database struct {
 		DSN string
 	}
 
+	// Mail is sent through SMTP server if SMTPHost is set, otherwise it's
+	// written to File (or stdout if it's not set either)
+	Mail struct {
+		SMTPHost     string
+		SMTPPort     int
+		SMTPUsername string
+		SMTPPassword string
+		From         string
+		File         string
+	}
+
+	// ExternalAuth holds login providers listed in OAUTH_PROVIDERS
 	ExternalAuth struct {
-		GoogleRedirectURL  string
-		GoogleClientID     string
-		GoogleClientSecret string
-		GithubRedirectURL  string
-		GithubClientID     string
-		GithubClientSecret string
+		Providers []oauth.ProviderConfig
 	}
 )
 
@@ -52,36 +70,152 @@ func Load() *Config {
 	if err != nil {
 		log.Fatal(err)
 	}
-	ratePenalty, err := strconv.Atoi(os.Getenv("HTTP_RATE_PENALTY"))
+	if rateInterval <= 0 || rateLimit <= 0 {
+		log.Fatal("HTTP_RATE_INTERVAL and HTTP_RATE_LIMIT must be positive")
+	}
+
+	// Optional, burst is equal to the limit by default
+	var rateBurst int
+	if s := os.Getenv("HTTP_RATE_BURST"); s != "" {
+		rateBurst, err = strconv.Atoi(s)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// Optional, forwarding headers aren't trusted by default
+	trustedProxies, err := realip.ParsePrefixes(os.Getenv("HTTP_TRUSTED_PROXIES"))
 	if err != nil {
 		log.Fatal(err)
 	}
+
+	// Optional, services fall back to default page size if it's not set
+	var pageSize int
+	if s := os.Getenv("APP_PAGE_SIZE"); s != "" {
+		pageSize, err = strconv.Atoi(s)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// Optional, links in emails point to local server by default
+	baseURL := strings.TrimSuffix(os.Getenv("APP_BASE_URL"), "/")
+	if baseURL == "" {
+		baseURL = "http://localhost:" + os.Getenv("HTTP_ADDR")
+	}
+
+	smtpPort := 587
+	if s := os.Getenv("MAIL_SMTP_PORT"); s != "" {
+		smtpPort, err = strconv.Atoi(s)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	mailFrom := os.Getenv("MAIL_FROM")
+	if mailFrom == "" {
+		mailFrom = "noreply@localhost"
+	}
+
 	return &Config{
 		App{
-			Name:    os.Getenv("APP_NAME"),
-			Version: os.Getenv("APP_VERSION"),
+			Name:     os.Getenv("APP_NAME"),
+			Version:  os.Getenv("APP_VERSION"),
+			PageSize: pageSize,
+			BaseURL:  baseURL,
 		},
 		Http{
-			Addr:         os.Getenv("HTTP_ADDR"),
-			RateInterval: rateInterval,
-			RateLimit:    rateLimit,
-			RatePenalty:  ratePenalty,
+			Addr:           os.Getenv("HTTP_ADDR"),
+			RateInterval:   rateInterval,
+			RateLimit:      rateLimit,
+			RateBurst:      rateBurst,
+			TrustedProxies: trustedProxies,
 		},
 		Database{
 			DSN: os.Getenv("SQLITE3_DSN"),
 		},
+		Mail{
+			SMTPHost:     os.Getenv("MAIL_SMTP_HOST"),
+			SMTPPort:     smtpPort,
+			SMTPUsername: os.Getenv("MAIL_SMTP_USERNAME"),
+			SMTPPassword: os.Getenv("MAIL_SMTP_PASSWORD"),
+			From:         mailFrom,
+			File:         os.Getenv("MAIL_FILE"),
+		},
 		ExternalAuth{
-			GoogleRedirectURL:  os.Getenv("GOOGLE_REDIRECT_URL"),
-			GoogleClientID:     os.Getenv("GOOGLE_CLIENT_ID"),
-			GoogleClientSecret: os.Getenv("GOOGLE_CLIENT_SECRET"),
-
-			GithubRedirectURL:  os.Getenv("GITHUB_REDIRECT_URL"),
-			GithubClientID:     os.Getenv("GITHUB_CLIENT_ID"),
-			GithubClientSecret: os.Getenv("GITHUB_CLIENT_SECRET"),
+			Providers: loadProviders(baseURL),
 		},
 	}
 }
 
+var providerNameRX = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// loadProviders reads settings of every provider from OAUTH_<NAME>_*
+// variables. Known providers (see oauth.Presets) only need client
+// credentials, others need OAUTH_<NAME>_ISSUER of OpenID Connect provider
+// or explicit endpoints. GOOGLE_* and GITHUB_* variables are still read for
+// these providers
+func loadProviders(baseURL string) []oauth.ProviderConfig {
+	var providers []oauth.ProviderConfig
+
+	for _, name := range strings.Split(getEnv("OAUTH_PROVIDERS", "google,github"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if !providerNameRX.MatchString(name) {
+			log.Fatalf("invalid oauth provider name: %q", name)
+		}
+
+		prefix := strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		env := func(key string) string {
+			if v := os.Getenv("OAUTH_" + prefix + key); v != "" {
+				return v
+			}
+			if name == "google" || name == "github" {
+				return os.Getenv(prefix + key)
+			}
+			return ""
+		}
+
+		p := oauth.ProviderConfig{
+			Name:         name,
+			DisplayName:  env("DISPLAY_NAME"),
+			ClientID:     env("CLIENT_ID"),
+			ClientSecret: env("CLIENT_SECRET"),
+			RedirectURL:  env("REDIRECT_URL"),
+			Issuer:       env("ISSUER"),
+			AuthURL:      env("AUTH_URL"),
+			TokenURL:     env("TOKEN_URL"),
+			UserInfoURL:  env("USER_INFO_URL"),
+			JWKSURL:      env("JWKS_URL"),
+			Scopes:       strings.Fields(strings.ReplaceAll(env("SCOPES"), ",", " ")),
+			Claims: oauth.Claims{
+				Subject:       env("CLAIM_SUBJECT"),
+				Email:         env("CLAIM_EMAIL"),
+				Username:      env("CLAIM_USERNAME"),
+				EmailVerified: env("CLAIM_EMAIL_VERIFIED"),
+			},
+			DisablePKCE: env("PKCE") == "false",
+			EmailDomain: env("EMAIL_DOMAIN"),
+		}
+		if p.RedirectURL == "" {
+			p.RedirectURL = baseURL + "/callback/" + name
+		}
+
+		providers = append(providers, p)
+	}
+
+	return providers
+}
+
+// getEnv returns value of optional environment variable or fallback if it's not set
+func getEnv(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
 // This init function parses ".env" file and sets key-value pairs in it into system environment
 func init() {
 	file, err := os.Open(".env")
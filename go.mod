@@ -3,7 +3,7 @@ module forum
 go 1.21
 
 require (
-	github.com/gofrs/uuid v4.4.0+incompatible // indirect
-	github.com/mattn/go-sqlite3 v1.14.19 // indirect
-	golang.org/x/crypto v0.18.0 // indirect
+	github.com/gofrs/uuid v4.4.0+incompatible
+	github.com/mattn/go-sqlite3 v1.14.19
+	golang.org/x/crypto v0.18.0
 )
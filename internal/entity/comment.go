@@ -2,6 +2,8 @@ package entity
 
 import (
 	"forum/internal/validator"
+	"forum/pkg/diff"
+	"html/template"
 	"time"
 )
 
@@ -12,26 +14,62 @@ type CommentEntity struct {
 	Username  string
 	Content   string
 	CreatedAt time.Time
+	UpdatedAt time.Time // zero if comment was never edited
 	PostID    int
+	ParentID  int // 0 for top level comments
 	Likes     int
 	Dislikes  int
 }
 
 // CommentView is returned by services, storing all comment related data that
-// will be outputed to end user
+// will be outputed to end user.
+//
+// Replies are filled only when comments are returned as a tree. Replies that
+// are nested too deep aren't included - their number is stored in HiddenReplies
+// instead, so they can be shown on separate thread page.
+//
+// EditedAgo tells how long ago comment was edited, it's empty for comments that
+// were never edited
 type CommentView struct {
-	ID        int
-	Username  string
-	Content   string
-	CreatedAt time.Time
-	PostID    int
-	Likes     int
-	Dislikes  int
+	ID            int
+	Username      string
+	Content       string
+	ContentHTML   template.HTML
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	EditedAgo     string
+	PostID        int
+	ParentID      int
+	Likes         int
+	Dislikes      int
+	Depth         int
+	Replies       []CommentView
+	HiddenReplies int
 }
 
 // CommentCreateForm is accepted by services by pointer only for form error messages
-// handling, so they ar written in Validator's FieldErrors or NonFieldErrors fields
+// handling, so they ar written in Validator's FieldErrors or NonFieldErrors fields.
+// ParentID is id of comment that is replied to (0 for top level comment)
 type CommentCreateForm struct {
-	Content string
+	Content  string
+	ParentID int
 	validator.Validator
 }
+
+// CommentRevision is one saved version of comment. Revisions are numbered
+// from 1, the first one is the original text. Diff shows changes made
+// since the previous revision
+type CommentRevision struct {
+	ID        int
+	CommentID int
+	Number    int
+	Content   string
+	CreatedAt time.Time
+	Diff      []diff.Op
+}
+
+// CommentHistory is returned to handlers for comment revisions page
+type CommentHistory struct {
+	Comment   CommentView
+	Revisions []CommentRevision
+}
@@ -13,6 +13,7 @@ const (
 	COMMENT_LIKE     = "comment_like"
 	COMMENT_DISLIKE  = "comment_dislike"
 	COMMENTED        = "commented"
+	REPLIED          = "replied"
 	REPORT           = "report"
 	REJECT_PROMOTION = "reject_promotion"
 	REJECT_REPORT    = "reject_report"
@@ -20,9 +21,87 @@ const (
 	DELETE_COMMENT   = "delete_comment"
 	PROMOTED         = "promoted"
 	DEMOTED          = "demoted"
+	LOGIN_LOCKED     = "login_locked"
+	WARNED           = "warned"
+	MUTED            = "muted"
+	BANNED           = "banned"
 
 	POST    = "post"
 	COMMENT = "comment"
 
 	DIRECT = "direct"
 )
+
+// Post listing sort options and time windows of top sorting
+const (
+	SORT_NEW           = "new"
+	SORT_TOP           = "top"
+	SORT_COMMENTED     = "commented"
+	SORT_CONTROVERSIAL = "controversial"
+	SORT_HOT           = "hot"
+
+	PERIOD_DAY   = "day"
+	PERIOD_WEEK  = "week"
+	PERIOD_MONTH = "month"
+	PERIOD_YEAR  = "year"
+	PERIOD_ALL   = "all"
+)
+
+// Kinds of sanctions given to users by admins
+const (
+	SANCTION_WARNING = "warning"
+	SANCTION_MUTE    = "mute"
+	SANCTION_BAN     = "ban"
+)
+
+// Actions recorded in moderation log and types of their targets. Posts and
+// comments are targets of POST and COMMENT types
+const (
+	ACTION_PROMOTE_USER     = "promote_user"
+	ACTION_DEMOTE_USER      = "demote_user"
+	ACTION_SET_ROLE         = "set_role"
+	ACTION_REJECT_PROMOTION = "reject_promotion"
+	ACTION_REJECT_REPORT    = "reject_report"
+	ACTION_CREATE_TAG       = "create_tag"
+	ACTION_DELETE_TAG       = "delete_tag"
+	ACTION_DELETE_POST      = "delete_post"
+	ACTION_DELETE_COMMENT   = "delete_comment"
+	ACTION_WARN_USER        = "warn_user"
+	ACTION_MUTE_USER        = "mute_user"
+	ACTION_BAN_USER         = "ban_user"
+	ACTION_REVOKE_SANCTION  = "revoke_sanction"
+	ACTION_CLEAR_LOCKOUT    = "clear_lockout"
+	ACTION_CHANGE_SETTING   = "change_setting"
+	ACTION_RESET_TWO_FACTOR = "reset_two_factor"
+
+	TARGET_USER      = "user"
+	TARGET_PROMOTION = "promotion"
+	TARGET_REPORT    = "report"
+	TARGET_TAG       = "tag"
+	TARGET_SANCTION  = "sanction"
+	TARGET_LOCKOUT   = "lockout"
+	TARGET_SETTING   = "setting"
+)
+
+// ModerationActions and ModerationTargets are all actions and target types
+// of moderation log, the order is the order of the admin page filter
+var (
+	ModerationActions = []string{
+		ACTION_PROMOTE_USER, ACTION_DEMOTE_USER, ACTION_SET_ROLE,
+		ACTION_REJECT_PROMOTION, ACTION_REJECT_REPORT, ACTION_CREATE_TAG,
+		ACTION_DELETE_TAG, ACTION_DELETE_POST, ACTION_DELETE_COMMENT,
+		ACTION_WARN_USER, ACTION_MUTE_USER, ACTION_BAN_USER,
+		ACTION_REVOKE_SANCTION, ACTION_CLEAR_LOCKOUT, ACTION_CHANGE_SETTING,
+		ACTION_RESET_TWO_FACTOR,
+	}
+	ModerationTargets = []string{
+		TARGET_USER, TARGET_PROMOTION, TARGET_REPORT, TARGET_TAG, POST,
+		COMMENT, TARGET_SANCTION, TARGET_LOCKOUT, TARGET_SETTING,
+	}
+)
+
+// Purposes of one-time tokens sent to users by email
+const (
+	TOKEN_PASSWORD_RESET     = "password_reset"
+	TOKEN_EMAIL_VERIFICATION = "email_verification"
+)
@@ -8,6 +8,8 @@ var (
 	ErrInvalidFormData = errors.New("entity: some form data is invalid")
 	ErrInvalidPathID   = errors.New("entity: invalid id in request path")
 	ErrInvalidURLPath  = errors.New("entity: invalid url path")
+	ErrInvalidCursor   = errors.New("entity: invalid page cursor")
+	ErrInvalidSort     = errors.New("entity: invalid sort option")
 )
 
 // Post related errors
@@ -19,6 +21,7 @@ var (
 	ErrPostNotFound     = errors.New("entity: post not found")
 	ErrCommentNotFound  = errors.New("entity: comment not found")
 	ErrTagNotFound      = errors.New("entity: tag not found")
+	ErrRevisionNotFound = errors.New("entity: revision not found")
 	ErrInvalidTag       = errors.New("entity: tag name is invalid")
 	ErrDuplicateTag     = errors.New("entity: duplicate tag")
 )
@@ -39,6 +42,24 @@ var (
 	ErrDuplicateReport       = errors.New("entity: duplicate report")
 	ErrReportNotFound        = errors.New("entity: report not found")
 	ErrUserNotFound          = errors.New("entity: user not found")
+	ErrInvalidRole           = errors.New("entity: invalid role")
+	ErrAlreadyPromoted       = errors.New("entity: user already has this role or a higher one")
+	ErrInvalidToken          = errors.New("entity: invalid or expired token")
+	ErrTokenRecentlySent     = errors.New("entity: token was sent recently")
+	ErrAlreadyVerified       = errors.New("entity: email is already verified")
+	ErrIdentityNotLinked     = errors.New("entity: identity isn't linked to any user")
+	ErrIdentityLinked        = errors.New("entity: identity is linked to another user")
+	ErrProviderLinked        = errors.New("entity: user already has identity of this provider")
+	ErrLastLoginMethod       = errors.New("entity: last way to log in can't be removed")
+	ErrTwoFactorEnabled      = errors.New("entity: two-factor authentication is already enabled")
+	ErrTwoFactorDisabled     = errors.New("entity: two-factor authentication isn't enabled")
+	ErrTwoFactorRequired     = errors.New("entity: two-factor authentication is required for the role")
+	ErrInvalidPasscode       = errors.New("entity: invalid or already used one-time code")
+	ErrLoginLocked           = errors.New("entity: too many failed login attempts")
+	ErrLockoutNotFound       = errors.New("entity: lockout not found")
+	ErrSanctionNotFound      = errors.New("entity: sanction not found")
+	ErrCannotSanction        = errors.New("entity: user can't be sanctioned")
+	ErrInvalidModerationLog  = errors.New("entity: invalid moderation log filter")
 )
 
 // Notification related errors
@@ -0,0 +1,34 @@
+package entity
+
+import (
+	"forum/internal/validator"
+	"time"
+)
+
+// Identity is account of external login provider linked to the user
+type Identity struct {
+	ID        int
+	UserID    int
+	Provider  string
+	Subject   string // id of the user at provider
+	Email     string
+	CreatedAt time.Time
+}
+
+// ExternalIdentity is user data returned by external login provider
+type ExternalIdentity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	Username      string
+	EmailVerified bool
+	Placeholder   bool // email is made up, because provider didn't share it
+}
+
+// ExternalSignupForm creates account for identity that isn't linked to any
+// user. Username is suggested by provider and can be changed by the user
+type ExternalSignupForm struct {
+	Username string
+	Identity ExternalIdentity
+	validator.Validator
+}
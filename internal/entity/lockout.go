@@ -0,0 +1,17 @@
+package entity
+
+import "time"
+
+// Lockout counts failed logins of the account or of the client address in
+// a row. Logins are refused until LockedUntil
+type Lockout struct {
+	ID          int
+	UserID      int    // zero for lockout of address
+	Username    string // not in db
+	IP          string // empty for lockout of account
+	Failures    int
+	LastFailure time.Time
+	LockedUntil time.Time // zero if failures are below the limit
+	Locked      bool      // not in db, lock didn't end yet
+	Notified    bool      // owner of the account was told about the lockout
+}
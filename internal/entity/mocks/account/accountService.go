@@ -0,0 +1,81 @@
+package account
+
+import (
+	"errors"
+	"forum/internal/entity"
+	repo "forum/internal/repository/user"
+	service "forum/internal/service/account"
+)
+
+// mockToken is the only token that is accepted by the mock
+const mockToken = "mocktoken"
+
+type AccountServiceMock struct {
+	ur repo.IUserRepository
+}
+
+func NewAccountServiceMock(r repo.IUserRepository) *AccountServiceMock {
+	return &AccountServiceMock{
+		ur: r,
+	}
+}
+
+var _ service.IAccountService = (*AccountServiceMock)(nil)
+
+func (as *AccountServiceMock) RequestPasswordReset(form *entity.ForgotPasswordForm) error {
+	return nil
+}
+
+func (as *AccountServiceMock) CheckResetToken(token string) error {
+	if token != mockToken {
+		return entity.ErrInvalidToken
+	}
+	return nil
+}
+
+func (as *AccountServiceMock) ResetPassword(form *entity.ResetPasswordForm) (int, error) {
+	if form.Token != mockToken {
+		return 0, entity.ErrInvalidToken
+	}
+	return 1, nil
+}
+
+func (as *AccountServiceMock) SendVerification(userID int) error {
+	return nil
+}
+
+func (as *AccountServiceMock) ChangeEmail(userID int, form *entity.ChangeEmailForm) error {
+	if !service.IsRightEmail(form) {
+		return entity.ErrInvalidFormData
+	}
+	if err := as.ur.UpdateEmail(userID, form.Email); err != nil {
+		if errors.Is(err, entity.ErrDuplicateEmail) {
+			form.AddFieldError("email", "Email address is already in use")
+		}
+		return err
+	}
+	return nil
+}
+
+func (as *AccountServiceMock) VerifyEmail(token string) (int, error) {
+	if token != mockToken {
+		return 0, entity.ErrInvalidToken
+	}
+	return 1, nil
+}
+
+func (as *AccountServiceMock) MarkVerified(userID int) error {
+	return as.ur.SetVerified(userID)
+}
+
+func (as *AccountServiceMock) IsVerified(userID int) (bool, error) {
+	u, err := as.ur.GetByID(userID)
+	if err != nil {
+		if errors.Is(err, entity.ErrInvalidCredentials) {
+			return false, entity.ErrUserNotFound
+		}
+		return false, err
+	}
+
+	return !u.VerifiedAt.IsZero(), nil
+}
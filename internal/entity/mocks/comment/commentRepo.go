@@ -24,8 +24,8 @@ func NewCommentRepoMock() *CommentRepoMock {
 
 var _ comment.ICommentRepository = (*CommentRepoMock)(nil)
 
-func (r *CommentRepoMock) Insert(c entity.CommentCreateForm, postID int, userID int) error {
-	return nil
+func (r *CommentRepoMock) Insert(c entity.CommentCreateForm, postID int, userID int) (int, error) {
+	return mockComment.ID + 1, nil
 }
 
 func (r *CommentRepoMock) GetAllForPost(postID int) (*[]entity.CommentEntity, error) {
@@ -39,3 +39,40 @@ func (r *CommentRepoMock) GetAllUserCommentsForPost(userID, postID int) (*[]enti
 func (r *CommentRepoMock) Exists(commentID int) (bool, error) {
 	return true, nil
 }
+
+func (r *CommentRepoMock) Delete(commentID, userID int) error {
+	return nil
+}
+
+func (r *CommentRepoMock) DeleteByPrivileged(commentID int, e entity.ModerationEntry) error {
+	return nil
+}
+
+func (r *CommentRepoMock) GetAuthorID(commentID int) (int, error) {
+	if commentID != mockComment.ID {
+		return 0, entity.ErrCommentNotFound
+	}
+	return 1, nil
+}
+
+func (r *CommentRepoMock) GetByID(commentID int) (entity.CommentEntity, error) {
+	if commentID != mockComment.ID {
+		return entity.CommentEntity{}, entity.ErrCommentNotFound
+	}
+	return mockComment, nil
+}
+
+func (r *CommentRepoMock) Update(commentID int, content string) error {
+	return nil
+}
+
+func (r *CommentRepoMock) GetPostID(commentID int) (int, error) {
+	if commentID != mockComment.ID {
+		return 0, entity.ErrCommentNotFound
+	}
+	return mockComment.PostID, nil
+}
+
+func (r *CommentRepoMock) GetRevisions(commentID int) (*[]entity.CommentRevision, error) {
+	return &[]entity.CommentRevision{}, nil
+}
@@ -18,9 +18,9 @@ func NewCommentServiceMock(r repo.ICommentRepository) *CommentServiceMock {
 
 var _ service.ICommentService = (*CommentServiceMock)(nil)
 
-func (cs *CommentServiceMock) SaveComment(c *entity.CommentCreateForm, postID int, userID int) error {
+func (cs *CommentServiceMock) SaveComment(c *entity.CommentCreateForm, postID int, userID int) (int, error) {
 	if !service.IsRightComment(c) {
-		return entity.ErrInvalidFormData
+		return 0, entity.ErrInvalidFormData
 	}
 
 	return cs.cr.Insert(*c, postID, userID)
@@ -35,6 +35,18 @@ func (cs *CommentServiceMock) GetAllCommentsForPost(postID int) (*[]entity.Comme
 	return service.ConvertEntitiesToViews(comments)
 }
 
+func (cs *CommentServiceMock) GetCommentThread(postID, commentID int) (*[]entity.CommentView, error) {
+	c, err := cs.GetComment(commentID)
+	if err != nil {
+		return nil, err
+	}
+	return &[]entity.CommentView{c}, nil
+}
+
+func (cs *CommentServiceMock) GetVisibleThread(postID, commentID, threadID int) (int, error) {
+	return threadID, nil
+}
+
 func (cs *CommentServiceMock) GetAllUserCommentsForPost(userID, postID int) (*[]entity.CommentView, error) {
 	comments, err := cs.cr.GetAllUserCommentsForPost(userID, postID)
 	if err != nil {
@@ -47,3 +59,38 @@ func (cs *CommentServiceMock) GetAllUserCommentsForPost(userID, postID int) (*[]
 func (cs *CommentServiceMock) ExistsComment(commentID int) (bool, error) {
 	return cs.cr.Exists(commentID)
 }
+
+func (cs *CommentServiceMock) DeleteComment(commentID, userID int) error {
+	return cs.cr.Delete(commentID, userID)
+}
+
+func (cs *CommentServiceMock) DeleteCommentPrivileged(commentID int, userID int, userRole, reason string) error {
+	return cs.cr.DeleteByPrivileged(commentID, entity.ModerationEntry{})
+}
+
+func (cs *CommentServiceMock) GetAuthorID(commentID int) (int, error) {
+	return cs.cr.GetAuthorID(commentID)
+}
+
+func (cs *CommentServiceMock) GetComment(commentID int) (entity.CommentView, error) {
+	c, err := cs.cr.GetByID(commentID)
+	if err != nil {
+		return entity.CommentView{}, err
+	}
+	return service.ConvertEntityToView(c), nil
+}
+
+func (cs *CommentServiceMock) UpdateComment(commentID int, content string) error {
+	return cs.cr.Update(commentID, content)
+}
+
+func (cs *CommentServiceMock) GetPostID(commentID int) (int, error) {
+	return cs.cr.GetPostID(commentID)
+}
+
+func (cs *CommentServiceMock) GetCommentHistory(commentID int, userRole string) (entity.CommentHistory, error) {
+	if userRole != entity.MODERATOR && userRole != entity.ADMIN {
+		return entity.CommentHistory{}, entity.ErrForbiddenAccess
+	}
+	return entity.CommentHistory{}, entity.ErrRevisionNotFound
+}
@@ -18,7 +18,7 @@ func (r *ImageRepoMock) Create(i entity.ImageEntity) error {
 	return nil
 }
 
-func (r *ImageRepoMock) ReadName(postID int) (string, error) {
+func (r *ImageRepoMock) GetName(postID int) (string, error) {
 	if postID == 0 {
 		return "mockImage", nil
 	}
@@ -24,5 +24,5 @@ func (is *ImageServiceMock) ProcessImage(file multipart.File, fileHeader *multip
 }
 
 func (is *ImageServiceMock) Get(postID int) (string, error) {
-	return is.ir.ReadName(postID)
+	return is.ir.GetName(postID)
 }
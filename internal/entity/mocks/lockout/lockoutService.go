@@ -0,0 +1,37 @@
+package lockout
+
+import (
+	"forum/internal/entity"
+	service "forum/internal/service/lockout"
+	"time"
+)
+
+// LockoutServiceMock never locks logins
+type LockoutServiceMock struct {
+}
+
+func NewLockoutServiceMock() *LockoutServiceMock {
+	return &LockoutServiceMock{}
+}
+
+var _ service.ILockoutService = (*LockoutServiceMock)(nil)
+
+func (ls *LockoutServiceMock) Check(identifier, ip string) (time.Duration, error) {
+	return 0, nil
+}
+
+func (ls *LockoutServiceMock) RecordFailure(identifier, ip string) error {
+	return nil
+}
+
+func (ls *LockoutServiceMock) RecordSuccess(userID int) error {
+	return nil
+}
+
+func (ls *LockoutServiceMock) GetLockouts() ([]entity.Lockout, error) {
+	return nil, nil
+}
+
+func (ls *LockoutServiceMock) Clear(actorID, lockoutID int) error {
+	return nil
+}
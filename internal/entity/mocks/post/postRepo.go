@@ -40,23 +40,23 @@ func (r *PostRepoMock) Get(postID int) (entity.PostEntity, error) {
 	}
 }
 
-func (r *PostRepoMock) GetAll() (*[]entity.PostEntity, error) {
+func (r *PostRepoMock) GetAll(page entity.PageRequest) (*[]entity.PostEntity, error) {
 	return &[]entity.PostEntity{mockPost}, nil
 }
 
-func (r *PostRepoMock) GetAllByTagId(tagID int) (*[]entity.PostEntity, error) {
+func (r *PostRepoMock) GetAllByTagId(tagID int, page entity.PageRequest) (*[]entity.PostEntity, error) {
 	return &[]entity.PostEntity{mockPost}, nil
 }
 
-func (r *PostRepoMock) GetAllByUserID(userID int) (*[]entity.PostEntity, error) {
+func (r *PostRepoMock) GetAllByUserID(userID int, page entity.PageRequest) (*[]entity.PostEntity, error) {
 	return &[]entity.PostEntity{mockPost}, nil
 }
 
-func (r *PostRepoMock) GetAllByUserReaction(userID int) (*[]entity.PostEntity, error) {
+func (r *PostRepoMock) GetAllByUserReaction(userID int, page entity.PageRequest) (*[]entity.PostEntity, error) {
 	return &[]entity.PostEntity{mockPost}, nil
 }
 
-func (r *PostRepoMock) GetAllCommentedPosts(userID int) (*[]entity.PostEntity, error) {
+func (r *PostRepoMock) GetAllCommentedPosts(userID int, page entity.PageRequest) (*[]entity.PostEntity, error) {
 	return &[]entity.PostEntity{mockPost}, nil
 }
 
@@ -67,6 +67,25 @@ func (r *PostRepoMock) Exists(postID int) (bool, error) {
 	return true, nil
 }
 
-func (r *PostRepoMock) Delete(postID int) error {
+func (r *PostRepoMock) Delete(postID int, userID int) error {
 	return nil
 }
+
+func (r *PostRepoMock) DeleteByPrivileged(postID int, e entity.ModerationEntry) error {
+	return nil
+}
+
+func (r *PostRepoMock) GetAuthorID(postID int) (int, error) {
+	if postID != mockPost.ID {
+		return 0, entity.ErrPostNotFound
+	}
+	return mockPost.UserID, nil
+}
+
+func (r *PostRepoMock) Update(p entity.PostCreateForm, tagIDs []int, deleteImage bool) error {
+	return nil
+}
+
+func (r *PostRepoMock) GetRevisions(postID int) (*[]entity.PostRevision, error) {
+	return &[]entity.PostRevision{}, nil
+}
@@ -45,57 +45,80 @@ func (ps *PostServiceMock) GetPost(postID int) (entity.PostView, error) {
 		return entity.PostView{}, entity.ErrInvalidPostID
 	}
 
-	return entity.PostView{
-		ID:          postEntity.ID,
-		Title:       postEntity.Title,
-		Content:     postEntity.Content,
-		Username:    postEntity.Username,
-		Likes:       postEntity.Likes,
-		Dislikes:    postEntity.Dislikes,
-		PostTags:    service.ConvertToStrArr(postEntity.PostTags),
-		CommentsLen: postEntity.CommentsLen,
-	}, nil
+	views, err := service.ConvertEntitiesToViews(&[]entity.PostEntity{postEntity})
+	if err != nil {
+		return entity.PostView{}, err
+	}
+
+	return (*views)[0], nil
 }
 
-func (ps *PostServiceMock) GetAllPosts() (*[]entity.PostView, error) {
-	posts, _ := ps.pr.GetAll()
-	return service.ConvertEntitiesToViews(posts)
+func (ps *PostServiceMock) GetAllPosts(p entity.PageParams) (*[]entity.PostView, entity.Page, error) {
+	req, err := service.NewPageRequest(p)
+	if err != nil {
+		return nil, entity.Page{}, err
+	}
+	posts, _ := ps.pr.GetAll(req)
+	return mockPage(posts, req)
 }
 
-func (ps *PostServiceMock) GetAllPostsByTagId(tagID int) (*[]entity.PostView, error) {
-	posts, _ := ps.pr.GetAllByTagId(tagID)
-	return service.ConvertEntitiesToViews(posts)
+func (ps *PostServiceMock) GetAllPostsByTagId(tagID int, p entity.PageParams) (*[]entity.PostView, entity.Page, error) {
+	req, err := service.NewPageRequest(p)
+	if err != nil {
+		return nil, entity.Page{}, err
+	}
+	posts, _ := ps.pr.GetAllByTagId(tagID, req)
+	return mockPage(posts, req)
 }
 
-func (ps *PostServiceMock) GetAllPostsByUserId(userID int) (*[]entity.PostView, error) {
-	posts, _ := ps.pr.GetAllByUserID(userID)
-	return service.ConvertEntitiesToViews(posts)
+func (ps *PostServiceMock) GetAllPostsByUserId(userID int, p entity.PageParams) (*[]entity.PostView, entity.Page, error) {
+	req, err := service.NewPageRequest(p)
+	if err != nil {
+		return nil, entity.Page{}, err
+	}
+	posts, _ := ps.pr.GetAllByUserID(userID, req)
+	return mockPage(posts, req)
 }
 
-func (ps *PostServiceMock) GetAllPostsByUserReaction(userID int) (*[]entity.PostView, error) {
-	posts, _ := ps.pr.GetAllByUserReaction(userID)
-	return service.ConvertEntitiesToViews(posts)
+func (ps *PostServiceMock) GetAllPostsByUserReaction(userID int, p entity.PageParams) (*[]entity.PostView, entity.Page, error) {
+	req, err := service.NewPageRequest(p)
+	if err != nil {
+		return nil, entity.Page{}, err
+	}
+	posts, _ := ps.pr.GetAllByUserReaction(userID, req)
+	return mockPage(posts, req)
 }
 
-func (ps *PostServiceMock) GetAllCommentedPostsWithComments(userID int) (*[]entity.PostView, *[][]entity.CommentView, error) {
-	postsEntities, err := ps.pr.GetAllCommentedPosts(userID)
+func (ps *PostServiceMock) GetAllCommentedPostsWithComments(userID int, p entity.PageParams) (*[]entity.PostView, *[][]entity.CommentView, entity.Page, error) {
+	req, err := service.NewPageRequest(p)
+	if err != nil {
+		return nil, nil, entity.Page{}, err
+	}
+
+	postsEntities, err := ps.pr.GetAllCommentedPosts(userID, req)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, entity.Page{}, err
 	}
-	posts, _ := service.ConvertEntitiesToViews(postsEntities)
+	posts, page, _ := mockPage(postsEntities, req)
 
 	var allComments [][]entity.CommentView
 
 	for _, p := range *posts {
 		comments, err := ps.commentService.GetAllUserCommentsForPost(userID, p.ID)
 		if err != nil {
-			return nil, nil, err
+			return nil, nil, entity.Page{}, err
 		}
 
 		allComments = append(allComments, *comments)
 	}
 
-	return posts, &allComments, nil
+	return posts, &allComments, page, nil
+}
+
+// mockPage returns posts as the only page of listing
+func mockPage(posts *[]entity.PostEntity, req entity.PageRequest) (*[]entity.PostView, entity.Page, error) {
+	views, err := service.ConvertEntitiesToViews(posts)
+	return views, entity.Page{Sort: req.Sort, Period: req.Period}, err
 }
 
 func (ps *PostServiceMock) ExistsPost(postID int) (bool, error) {
@@ -105,7 +128,7 @@ func (ps *PostServiceMock) ExistsPost(postID int) (bool, error) {
 // TODO: Add mock checks here
 func (ps *PostServiceMock) CheckPostAttrs(p *entity.PostCreateForm, withImage bool) (bool, error) {
 	if !service.IsRightPost(p, withImage) {
-		return false, entity.ErrInvalidFormData
+		return false, nil
 	}
 
 	areTagsExist, err := ps.tagService.AreTagsExist(p.Tags)
@@ -113,9 +136,25 @@ func (ps *PostServiceMock) CheckPostAttrs(p *entity.PostCreateForm, withImage bo
 		return false, err
 	}
 
-	return false, nil
+	return true, nil
+}
+
+func (ps *PostServiceMock) DeletePost(postID int, userID int) error {
+	return ps.pr.Delete(postID, userID)
+}
+
+func (ps *PostServiceMock) DeletePostPrivileged(postID int, userID int, userRole, reason string) error {
+	return ps.pr.DeleteByPrivileged(postID, entity.ModerationEntry{})
+}
+
+func (ps *PostServiceMock) GetAuthorID(postID int) (int, error) {
+	return ps.pr.GetAuthorID(postID)
+}
+
+func (ps *PostServiceMock) UpdatePost(p entity.PostCreateForm, deleteImageStr string) error {
+	return ps.pr.Update(p, nil, deleteImageStr == "yes")
 }
 
-func (ps *PostServiceMock) DeletePost(postID int) error {
-	return ps.pr.Delete(postID)
+func (ps *PostServiceMock) GetPostHistory(postID, userID int, userRole string, from, to int) (entity.PostHistory, error) {
+	return entity.PostHistory{}, entity.ErrRevisionNotFound
 }
@@ -58,7 +58,7 @@ func (rs *ReactionServiceMock) SetPostReaction(reaction string, postID int, user
 }
 
 // Same principle to reactions handling in posts
-func (rs *ReactionServiceMock) SetCommentReaction(reaction string, commentID int, userID int) error {
+func (rs *ReactionServiceMock) SetCommentReaction(reaction string, commentID int, postID int, userID int) error {
 	var isLike bool
 	switch reaction {
 	case "like":
@@ -0,0 +1,36 @@
+package sanction
+
+import (
+	"forum/internal/entity"
+	service "forum/internal/service/sanction"
+)
+
+// SanctionServiceMock has no sanctions in force for any user
+type SanctionServiceMock struct {
+}
+
+func NewSanctionServiceMock() *SanctionServiceMock {
+	return &SanctionServiceMock{}
+}
+
+var _ service.ISanctionService = (*SanctionServiceMock)(nil)
+
+func (ss *SanctionServiceMock) Issue(f *entity.SanctionForm, issuerID int) error {
+	return nil
+}
+
+func (ss *SanctionServiceMock) Revoke(actorID, sanctionID int) error {
+	return nil
+}
+
+func (ss *SanctionServiceMock) GetRestrictions(userID int) (entity.Restrictions, error) {
+	return entity.Restrictions{}, nil
+}
+
+func (ss *SanctionServiceMock) GetCurrent() (map[int][]entity.Sanction, error) {
+	return map[int][]entity.Sanction{}, nil
+}
+
+func (ss *SanctionServiceMock) Describe(s entity.Sanction) string {
+	return s.Reason
+}
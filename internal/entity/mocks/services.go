@@ -1,11 +1,15 @@
 package mocks
 
 import (
+	"forum/internal/entity/mocks/account"
 	"forum/internal/entity/mocks/comment"
 	"forum/internal/entity/mocks/image"
+	"forum/internal/entity/mocks/lockout"
 	"forum/internal/entity/mocks/post"
 	"forum/internal/entity/mocks/reaction"
+	"forum/internal/entity/mocks/sanction"
 	"forum/internal/entity/mocks/tag"
+	"forum/internal/entity/mocks/twofactor"
 	"forum/internal/entity/mocks/user"
 	"forum/internal/repository"
 	"forum/internal/service"
@@ -13,10 +17,14 @@ import (
 
 func NewServicesMock(r *repository.Repositories) *service.Services {
 	return &service.Services{
-		Post:     post.NewPostServiceMock(r.Post, image.NewImageServiceMock(r.Image), tag.NewTagServiceMock(r.Tag), comment.NewCommentServiceMock(r.Comment)),
-		User:     user.NewUserServiceMock(r.User),
-		Tag:      tag.NewTagServiceMock(r.Tag),
-		Comment:  comment.NewCommentServiceMock(r.Comment),
-		Reaction: reaction.NewReactionServiceMock(r.Reaction),
+		Post:      post.NewPostServiceMock(r.Post, image.NewImageServiceMock(r.Image), tag.NewTagServiceMock(r.Tag), comment.NewCommentServiceMock(r.Comment)),
+		User:      user.NewUserServiceMock(r.User),
+		Tag:       tag.NewTagServiceMock(r.Tag),
+		Comment:   comment.NewCommentServiceMock(r.Comment),
+		Reaction:  reaction.NewReactionServiceMock(r.Reaction),
+		Account:   account.NewAccountServiceMock(r.User),
+		TwoFactor: twofactor.NewTwoFactorServiceMock(),
+		Lockout:   lockout.NewLockoutServiceMock(),
+		Sanction:  sanction.NewSanctionServiceMock(),
 	}
 }
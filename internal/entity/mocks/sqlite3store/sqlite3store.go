@@ -1,35 +1,76 @@
-package sqlite3store
-
-import (
-	"context"
-	"time"
-)
-
-var (
-	userIDMock   = 1
-	userRoleMock = "guest"
-	expiryMock   = time.Now().Add(1_000_000 * time.Hour)
-)
-
-type SQLite3StoreMock struct {
-}
-
-func New() *SQLite3StoreMock {
-	return &SQLite3StoreMock{}
-}
-
-func (s *SQLite3StoreMock) StoreFind(ctx context.Context, sessionID string) (int, string, time.Time, error) {
-	return userIDMock, userRoleMock, expiryMock, nil
-}
-
-func (s *SQLite3StoreMock) StoreCommit(ctx context.Context, sessionID string, userID int, userRole string, expiry time.Time) error {
-	return nil
-}
-
-func (s *SQLite3StoreMock) StoreDeleteAll(ctx context.Context, userID int) error {
-	return nil
-}
-
-func (s *SQLite3StoreMock) StoreDelete(ctx context.Context, sessionID string) error {
-	return nil
-}
+package sqlite3store
+
+import (
+	"context"
+	"forum/pkg/sesm"
+	"sync"
+	"time"
+)
+
+var (
+	userIDMock   = 1
+	userRoleMock = "guest"
+	expiryMock   = time.Now().Add(1_000_000 * time.Hour)
+)
+
+// SQLite3StoreMock keeps committed sessions in memory, sessions that were
+// never committed belong to the mock user
+type SQLite3StoreMock struct {
+	mu       sync.Mutex
+	sessions map[string]sesm.Record
+}
+
+func New() *SQLite3StoreMock {
+	return &SQLite3StoreMock{sessions: make(map[string]sesm.Record)}
+}
+
+func (s *SQLite3StoreMock) StoreFind(ctx context.Context, sessionID string) (sesm.Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if rec, ok := s.sessions[sessionID]; ok {
+		return rec, nil
+	}
+	return sesm.Record{UserID: userIDMock, UserRole: userRoleMock, Expiry: expiryMock}, nil
+}
+
+func (s *SQLite3StoreMock) StoreCommit(ctx context.Context, sessionID string, rec sesm.Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sessions[sessionID] = rec
+	return nil
+}
+
+func (s *SQLite3StoreMock) StoreDeleteAll(ctx context.Context, userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, rec := range s.sessions {
+		if rec.UserID == userID {
+			delete(s.sessions, id)
+		}
+	}
+	return nil
+}
+
+func (s *SQLite3StoreMock) StoreDelete(ctx context.Context, sessionID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.sessions, sessionID)
+	return nil
+}
+
+func (s *SQLite3StoreMock) StoreFindAll(ctx context.Context, userID int) (map[string]sesm.Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records := make(map[string]sesm.Record)
+	for id, rec := range s.sessions {
+		if rec.UserID == userID {
+			records[id] = rec
+		}
+	}
+	return records, nil
+}
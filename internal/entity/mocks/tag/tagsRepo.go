@@ -37,3 +37,24 @@ func (r *TagRepoMock) IsExist(id int) (bool, error) {
 	}
 	return false, nil
 }
+
+func (r *TagRepoMock) Get(tagID int) (entity.TagEntity, error) {
+	if tagID != mockTag.ID {
+		return entity.TagEntity{}, entity.ErrTagNotFound
+	}
+	return mockTag, nil
+}
+
+func (r *TagRepoMock) Delete(tagID int, e entity.ModerationEntry) error {
+	if tagID != mockTag.ID {
+		return entity.ErrTagNotFound
+	}
+	return nil
+}
+
+func (r *TagRepoMock) Create(tag string, e entity.ModerationEntry) error {
+	if tag == mockTag.Name {
+		return entity.ErrDuplicateTag
+	}
+	return nil
+}
@@ -28,7 +28,7 @@ func (ts *TagServiceMock) AreTagsExist(tags []string) (bool, error) {
 	for _, tagIDStr := range tags {
 		tagID, err := strconv.Atoi(tagIDStr)
 		if err != nil {
-			return false, entity.ErrInvalidFormData
+			return false, entity.ErrInvalidTags
 		}
 		tagIDs = append(tagIDs, tagID)
 	}
@@ -38,3 +38,14 @@ func (ts *TagServiceMock) AreTagsExist(tags []string) (bool, error) {
 func (ts *TagServiceMock) IsExist(id int) (bool, error) {
 	return ts.tr.IsExist(id)
 }
+
+func (ts *TagServiceMock) DeleteTag(actorID, tagID int) error {
+	return ts.tr.Delete(tagID, entity.ModerationEntry{})
+}
+
+func (ts *TagServiceMock) CreateTag(actorID int, tag string) error {
+	if !service.IsValidTag(tag) {
+		return entity.ErrInvalidTag
+	}
+	return ts.tr.Create(tag, entity.ModerationEntry{})
+}
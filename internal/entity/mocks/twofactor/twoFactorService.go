@@ -0,0 +1,57 @@
+package twofactor
+
+import (
+	"forum/internal/entity"
+	service "forum/internal/service/twofactor"
+)
+
+// TwoFactorServiceMock has two-factor authentication disabled for every user
+// and not required for any role
+type TwoFactorServiceMock struct {
+}
+
+func NewTwoFactorServiceMock() *TwoFactorServiceMock {
+	return &TwoFactorServiceMock{}
+}
+
+var _ service.ITwoFactorService = (*TwoFactorServiceMock)(nil)
+
+func (ts *TwoFactorServiceMock) Status(userID int, role string) (entity.TwoFactorStatus, error) {
+	return entity.TwoFactorStatus{}, nil
+}
+
+func (ts *TwoFactorServiceMock) IsEnabled(userID int) (bool, error) {
+	return false, nil
+}
+
+func (ts *TwoFactorServiceMock) Begin(userID int, account string) (entity.TwoFactorSetup, error) {
+	return entity.TwoFactorSetup{}, nil
+}
+
+func (ts *TwoFactorServiceMock) Confirm(userID int, passcode string) ([]string, error) {
+	return nil, entity.ErrInvalidPasscode
+}
+
+func (ts *TwoFactorServiceMock) Verify(userID int, passcode string) error {
+	return entity.ErrTwoFactorDisabled
+}
+
+func (ts *TwoFactorServiceMock) RegenerateRecoveryCodes(userID int, passcode string) ([]string, error) {
+	return nil, entity.ErrTwoFactorDisabled
+}
+
+func (ts *TwoFactorServiceMock) Disable(userID int, role, passcode string) error {
+	return entity.ErrTwoFactorDisabled
+}
+
+func (ts *TwoFactorServiceMock) Reset(actorID, userID int) error {
+	return nil
+}
+
+func (ts *TwoFactorServiceMock) IsRequired(role string) (bool, error) {
+	return false, nil
+}
+
+func (ts *TwoFactorServiceMock) SetRequired(actorID int, required bool) error {
+	return nil
+}
@@ -7,11 +7,12 @@ import (
 )
 
 var mockUser = entity.UserEntity{
-	ID:        1,
-	Username:  "yuta",
-	Email:     "yuta@gmail.com",
-	Password:  "yuta12345",
-	CreatedAt: time.Date(2003, time.July, 6, 0, 0, 0, 0, time.Local),
+	ID:         1,
+	Username:   "yuta",
+	Email:      "yuta@gmail.com",
+	Password:   "yuta12345",
+	CreatedAt:  time.Date(2003, time.July, 6, 0, 0, 0, 0, time.Local),
+	VerifiedAt: time.Date(2003, time.July, 6, 0, 0, 0, 0, time.Local),
 }
 
 type UserRepoMock struct{}
@@ -45,6 +46,13 @@ func (r *UserRepoMock) GetByUsername(username string) (entity.UserEntity, error)
 	return entity.UserEntity{}, entity.ErrInvalidCredentials
 }
 
+func (r *UserRepoMock) GetByID(userID int) (entity.UserEntity, error) {
+	if userID == mockUser.ID {
+		return mockUser, nil
+	}
+	return entity.UserEntity{}, entity.ErrInvalidCredentials
+}
+
 func (r *UserRepoMock) GetUsernameByID(userID int) (string, error) {
 	if userID == 2 {
 		return "", entity.ErrInvalidCredentials
@@ -67,10 +75,94 @@ func (r *UserRepoMock) CreateNotification(n entity.Notification) error {
 	return nil
 }
 
-func (r *UserRepoMock) GetRequests() (*[]entity.Notification, error) {
-	return nil, nil
+func (r *UserRepoMock) CreatePromotion(userID int) error {
+	return nil
+}
+
+func (r *UserRepoMock) CreateReport(report entity.Report) error {
+	return nil
+}
+
+func (r *UserRepoMock) DeleteReport(reportID int) error {
+	return nil
+}
+
+func (r *UserRepoMock) DeletePromotion(promotionID int) error {
+	return nil
+}
+
+func (r *UserRepoMock) RejectReport(reportID int, e entity.ModerationEntry) error {
+	return nil
+}
+
+func (r *UserRepoMock) RejectPromotion(promotionID int, e entity.ModerationEntry) error {
+	return nil
+}
+
+func (r *UserRepoMock) GetNotifications(userID int) (*[]entity.Notification, error) {
+	return &[]entity.Notification{}, nil
+}
+
+func (r *UserRepoMock) DeleteNotification(notificationID int) error {
+	return nil
+}
+
+func (r *UserRepoMock) GetRequests() (*[]entity.Request, error) {
+	return &[]entity.Request{}, nil
 }
 
-func (r *UserRepoMock) Promote(userID int) error {
+func (r *UserRepoMock) GetReports() (*[]entity.Report, error) {
+	return &[]entity.Report{}, nil
+}
+
+func (r *UserRepoMock) GetPromotion(promotionID int) (entity.Request, error) {
+	return entity.Request{}, entity.ErrPromotionNotFound
+}
+
+func (r *UserRepoMock) GetReport(reportID int) (entity.Report, error) {
+	return entity.Report{}, entity.ErrReportNotFound
+}
+
+func (r *UserRepoMock) Promote(userID int, role string, e entity.ModerationEntry) error {
+	return nil
+}
+
+func (r *UserRepoMock) SetRole(userID int, role string, e entity.ModerationEntry) error {
+	return nil
+}
+
+func (r *UserRepoMock) GetUsers() (*[]entity.UserEntity, error) {
+	return &[]entity.UserEntity{mockUser}, nil
+}
+
+func (r *UserRepoMock) FindNotification(nType string, userFrom, userTo int) (int, error) {
+	return 0, entity.ErrNotificationNotFound
+}
+
+func (r *UserRepoMock) GetNotificationsCount(userID int) (int, error) {
+	return 0, nil
+}
+
+func (r *UserRepoMock) UpdatePassword(userID int, hashedPassword []byte) error {
+	if userID != mockUser.ID {
+		return entity.ErrUserNotFound
+	}
+	return nil
+}
+
+func (r *UserRepoMock) SetVerified(userID int) error {
+	if userID != mockUser.ID {
+		return entity.ErrUserNotFound
+	}
+	return nil
+}
+
+func (r *UserRepoMock) UpdateEmail(userID int, email string) error {
+	if userID != mockUser.ID {
+		return entity.ErrUserNotFound
+	}
+	if email == mockUser.Email {
+		return entity.ErrDuplicateEmail
+	}
 	return nil
 }
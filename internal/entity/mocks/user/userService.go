@@ -79,14 +79,74 @@ func (us *UserServiceMock) SendNotification(notification entity.Notification) er
 	return us.ur.CreateNotification(notification)
 }
 
-func (us *UserServiceMock) GetRequests(role string) (*[]entity.Notification, error) {
-	if role != entity.ADMIN {
-		return nil, entity.ErrForbiddenAccess
-	}
+func (us *UserServiceMock) GetUserByUsername(username string) (entity.UserEntity, error) {
+	return us.ur.GetByUsername(username)
+}
+
+func (us *UserServiceMock) SendPromotion(userID int) error {
+	return us.ur.CreatePromotion(userID)
+}
+
+func (us *UserServiceMock) SendReport(report entity.Report) error {
+	return us.ur.CreateReport(report)
+}
+
+func (us *UserServiceMock) DeleteReport(reportID int) error {
+	return us.ur.DeleteReport(reportID)
+}
 
+func (us *UserServiceMock) DeletePromotion(promotionID int) error {
+	return us.ur.DeletePromotion(promotionID)
+}
+
+func (us *UserServiceMock) RejectReport(actorID, reportID int) error {
+	return us.ur.RejectReport(reportID, entity.ModerationEntry{})
+}
+
+func (us *UserServiceMock) RejectPromotion(actorID, promotionID int) error {
+	return us.ur.RejectPromotion(promotionID, entity.ModerationEntry{})
+}
+
+func (us *UserServiceMock) GetRequests() (*[]entity.Request, error) {
 	return us.ur.GetRequests()
 }
 
-func (us *UserServiceMock) PromoteUser(userID int) error {
-	return us.ur.Promote(userID)
+func (us *UserServiceMock) GetReports() (*[]entity.Report, error) {
+	return us.ur.GetReports()
+}
+
+func (us *UserServiceMock) PromoteUser(actorID, userID int) error {
+	return us.ur.Promote(userID, entity.MODERATOR, entity.ModerationEntry{})
+}
+
+func (us *UserServiceMock) PromoteToAdmin(actorID, userID int) error {
+	return us.ur.Promote(userID, entity.ADMIN, entity.ModerationEntry{})
+}
+
+func (us *UserServiceMock) DemoteUser(actorID, userID int) error {
+	return us.ur.SetRole(userID, entity.USER, entity.ModerationEntry{})
+}
+
+func (us *UserServiceMock) SetUserRole(actorID, userID int, role string) error {
+	return us.ur.SetRole(userID, role, entity.ModerationEntry{})
+}
+
+func (us *UserServiceMock) GetNotifications(userID int) (*[]entity.Notification, error) {
+	return us.ur.GetNotifications(userID)
+}
+
+func (us *UserServiceMock) DeleteNotification(notificationID int) error {
+	return us.ur.DeleteNotification(notificationID)
+}
+
+func (us *UserServiceMock) GetUsers() (*[]entity.UserEntity, error) {
+	return us.ur.GetUsers()
+}
+
+func (us *UserServiceMock) FindNotification(nType string, userFrom, userTo int) (int, error) {
+	return us.ur.FindNotification(nType, userFrom, userTo)
+}
+
+func (us *UserServiceMock) GetNotificationsCount(userID int) (int, error) {
+	return us.ur.GetNotificationsCount(userID)
 }
@@ -0,0 +1,30 @@
+package entity
+
+import "time"
+
+// ModerationEntry is a record of privileged action in moderation log.
+// Before is JSON snapshot of the target before the action, it's empty if
+// target didn't exist before
+type ModerationEntry struct {
+	ID         int
+	ActorID    int    // 0 for actions made from command line
+	ActorName  string // username at the moment of the action
+	Action     string
+	TargetType string
+	TargetID   int
+	Reason     string
+	Before     string
+	CreatedAt  time.Time
+}
+
+// ModerationFilter selects entries of moderation log, empty fields don't
+// filter. Entries are returned newest first, starting right before entry
+// with id Before (or from the newest one if it's 0)
+type ModerationFilter struct {
+	Actor      string // username of the actor
+	Action     string
+	TargetType string
+	TargetID   int
+	Before     int
+	Limit      int
+}
@@ -0,0 +1,47 @@
+package entity
+
+import "time"
+
+// PostCursor points at the post on the border of a page. Post listings are
+// ordered by (score, created_at, id) in descending order, where score depends
+// on sorting (it is always 0 for newest first). Ref is the moment hot scores
+// and top periods are calculated for, so it stays the same while paging
+type PostCursor struct {
+	Score     float64
+	CreatedAt time.Time
+	ID        int
+	Ref       time.Time
+}
+
+// PageRequest is accepted by post repositories. Posts are returned right
+// after Cursor (or right before it if Backward is set), but always in listing
+// order. Nil Cursor means the first page
+type PageRequest struct {
+	Cursor   *PostCursor
+	Backward bool
+	Limit    int
+	Sort     string
+	Period   string
+	Ref      time.Time
+}
+
+// PageParams is accepted by post services. After and Before are opaque
+// cursors taken from links of the previously shown page, so at most one
+// of them is set. Empty Sort and Period mean defaults
+type PageParams struct {
+	After  string
+	Before string
+	Limit  int
+	Sort   string
+	Period string
+}
+
+// Page is returned from services along with listings. Next and Prev are
+// cursors of neighbour pages, empty if there is no such page. Sort and
+// Period are the ones that were actually applied
+type Page struct {
+	Next   string
+	Prev   string
+	Sort   string
+	Period string
+}
@@ -2,6 +2,8 @@ package entity
 
 import (
 	"forum/internal/validator"
+	"forum/pkg/diff"
+	"html/template"
 	"mime/multipart"
 	"time"
 )
@@ -12,6 +14,7 @@ type PostEntity struct {
 	Title       string
 	Content     string
 	CreatedAt   time.Time
+	UpdatedAt   time.Time
 	UserID      int
 	Username    string
 	Likes       int
@@ -19,14 +22,18 @@ type PostEntity struct {
 	PostTags    string
 	CommentsLen int
 	ImageName   string
+	Score       float64
 }
 
-// PostView is returned to handlers from service and outputed in pages
+// PostView is returned to handlers from service and outputed in pages.
+// ContentHTML is sanitized html rendered from markdown of Content
 type PostView struct {
 	ID          int
 	Title       string
 	Content     string
+	ContentHTML template.HTML
 	CreatedAt   time.Time
+	UpdatedAt   time.Time
 	Username    string
 	Likes       int
 	Dislikes    int
@@ -50,3 +57,30 @@ type PostCreateForm struct {
 	ImageName  string
 	validator.Validator
 }
+
+// PostRevision is one saved version of post. Revisions are numbered from 1,
+// the first one is the original post and the last one is the current state
+type PostRevision struct {
+	ID        int
+	PostID    int
+	Number    int
+	Title     string
+	Content   string
+	Tags      string
+	ImageName string
+	Editor    string
+	CreatedAt time.Time
+}
+
+// PostHistory is returned to handlers for revisions page. Diffs show changes
+// made between From and To revisions
+type PostHistory struct {
+	Post         PostView
+	Revisions    []PostRevision
+	From         PostRevision
+	To           PostRevision
+	TitleDiff    []diff.Op
+	ContentDiff  []diff.Op
+	TagsDiff     []diff.Op
+	ImageChanged bool
+}
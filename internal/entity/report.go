@@ -7,7 +7,8 @@ type Report struct {
 	Reason     string
 	UserFrom   int
 	Username   string // not in db
-	SourceID   int
+	SourceID   int    // id of post, for reports of comments too
 	SourceType string
+	CommentID  int // id of reported comment, 0 for reports of posts
 	CreatedAt  time.Time
 }
@@ -0,0 +1,44 @@
+package entity
+
+import (
+	"forum/internal/validator"
+	"time"
+)
+
+// Sanction is given to the user by admin. Warning only notifies the user,
+// muted user can't post, comment or react and banned user can't log in
+type Sanction struct {
+	ID         int
+	UserID     int
+	IssuedBy   int
+	IssuerName string // not in db
+	Kind       string
+	Reason     string
+	ExpiresAt  time.Time // zero for warnings and permanent sanctions
+	CreatedAt  time.Time
+}
+
+// Restrictions are sanctions that are in force for the user, zero sanction
+// if there is no such sanction
+type Restrictions struct {
+	Ban  Sanction
+	Mute Sanction
+}
+
+func (r Restrictions) Banned() bool {
+	return r.Ban.ID != 0
+}
+
+func (r Restrictions) Muted() bool {
+	return r.Mute.ID != 0
+}
+
+// SanctionForm is accepted by services as pointer to save validation errors.
+// Days is duration of mute or ban, 0 is permanent ban
+type SanctionForm struct {
+	UserID int
+	Kind   string
+	Days   int
+	Reason string
+	validator.Validator
+}
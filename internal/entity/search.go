@@ -0,0 +1,45 @@
+package entity
+
+import (
+	"forum/internal/validator"
+	"html/template"
+	"time"
+)
+
+// SearchQuery is accepted by search service and repo. Service accepts pointer
+// for form error messages handling (as other forms do)
+type SearchQuery struct {
+	Query   string
+	TagID   int
+	Author  string
+	From    string // Date in 'YYYY-MM-DD' format, inclusive
+	To      string // Date in 'YYYY-MM-DD' format, inclusive
+	Page    int
+	HasNext bool
+	Limit   int
+	Offset  int
+	validator.Validator
+}
+
+// SearchResultEntity is returned from repo to service. Snippet contains raw
+// user text with matched terms wrapped into highlight markers
+type SearchResultEntity struct {
+	PostID    int
+	CommentID int
+	Title     string
+	Snippet   string
+	Username  string
+	CreatedAt time.Time
+	Rank      float64
+}
+
+// SearchResultView is returned from service to handlers. CommentID is 0 if
+// post itself was matched
+type SearchResultView struct {
+	PostID    int
+	CommentID int
+	Title     string
+	Snippet   template.HTML
+	Username  string
+	CreatedAt time.Time
+}
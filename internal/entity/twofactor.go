@@ -0,0 +1,36 @@
+package entity
+
+import (
+	"forum/internal/validator"
+	"time"
+)
+
+// TwoFactor is TOTP secret of the user. It's used at login only after it's
+// confirmed with a code from authenticator app
+type TwoFactor struct {
+	UserID      int
+	Secret      string
+	LastCounter int64     // counter of the last accepted code
+	ConfirmedAt time.Time // zero until enrolment is confirmed
+	CreatedAt   time.Time
+}
+
+// TwoFactorStatus is shown to the user in settings
+type TwoFactorStatus struct {
+	Enabled       bool
+	Required      bool // role of the user can't work without 2FA
+	RecoveryCodes int  // number of unused recovery codes
+}
+
+// PasscodeForm holds code from authenticator app or recovery code
+type PasscodeForm struct {
+	Passcode string
+	validator.Validator
+}
+
+// TwoFactorSetup is secret of unfinished enrolment that is shown to the user
+// as QR code and as text to be typed into authenticator app
+type TwoFactorSetup struct {
+	Secret string
+	URI    string
+}
@@ -7,12 +7,13 @@ import (
 
 // UserEntity is returned by repos (not pointer, because data is read only)
 type UserEntity struct {
-	ID        int
-	Username  string
-	Email     string
-	Password  string
-	Role      string
-	CreatedAt time.Time
+	ID         int
+	Username   string
+	Email      string
+	Password   string
+	Role       string
+	CreatedAt  time.Time
+	VerifiedAt time.Time // zero if email address isn't verified
 }
 
 // UserSignupForm is accepted by services as pointers to save form validation
@@ -25,6 +26,27 @@ type UserSignupForm struct {
 	validator.Validator
 }
 
+// ForgotPasswordForm is accepted by services as pointer to save validation errors
+type ForgotPasswordForm struct {
+	Email string
+	validator.Validator
+}
+
+// ResetPasswordForm is accepted by services as pointer to save validation errors.
+// Token is the raw token from the link sent by email
+type ResetPasswordForm struct {
+	Token           string
+	Password        string
+	ConfirmPassword string
+	validator.Validator
+}
+
+// ChangeEmailForm is accepted by services as pointer to save validation errors
+type ChangeEmailForm struct {
+	Email string
+	validator.Validator
+}
+
 // UserLoginForm is accepted by services as pointers, by repos as copies for the
 // same purposes as UserSignupForm
 type UserLoginForm struct {
@@ -2,10 +2,11 @@ package handlers
 
 import (
 	"errors"
-	"fmt"
 	"forum/internal/entity"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 )
 
 func (r *Routes) requests(w http.ResponseWriter, req *http.Request) {
@@ -89,19 +90,24 @@ func (r *Routes) promoteUser(w http.ResponseWriter, req *http.Request) {
 		}
 	}
 
-	err := r.services.User.PromoteUser(userTo)
+	adminID := r.sesm.GetUserID(req.Context())
+
+	err := r.services.User.PromoteUser(adminID, userTo)
 	if err != nil {
-		if errors.Is(err, entity.ErrUserNotFound) {
+		switch {
+		case errors.Is(err, entity.ErrUserNotFound):
 			r.logger.Print("promoteUser: user not found")
 			r.notFound(w)
 			return
+		case errors.Is(err, entity.ErrAlreadyPromoted):
+			r.logger.Print("promoteUser: user is already promoted")
+			r.badRequest(w)
+			return
 		}
 		r.serverError(w, req, err)
 		return
 	}
 
-	adminID := r.sesm.GetUserID(req.Context())
-
 	notification := entity.Notification{
 		Type:     entity.PROMOTED,
 		UserFrom: adminID,
@@ -114,6 +120,8 @@ func (r *Routes) promoteUser(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	r.flash(req, flashSuccess, "User is promoted.")
+
 	if promotionType == entity.DIRECT {
 		http.Redirect(w, req, "/admin/users", http.StatusSeeOther)
 	} else {
@@ -134,7 +142,9 @@ func (r *Routes) demoteUser(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	err := r.services.User.DemoteUser(userTo)
+	adminID := r.sesm.GetUserID(req.Context())
+
+	err := r.services.User.DemoteUser(adminID, userTo)
 	if err != nil {
 		if errors.Is(err, entity.ErrUserNotFound) {
 			r.logger.Print("promoteUser: user not found")
@@ -145,8 +155,6 @@ func (r *Routes) demoteUser(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	adminID := r.sesm.GetUserID(req.Context())
-
 	notification := entity.Notification{
 		Type:     entity.DEMOTED,
 		UserFrom: adminID,
@@ -158,14 +166,15 @@ func (r *Routes) demoteUser(w http.ResponseWriter, req *http.Request) {
 		switch {
 		case errors.Is(err, entity.ErrDuplicateNotification):
 			r.logger.Print("demoteUser: duplicate notification")
-			w.WriteHeader(http.StatusBadRequest)
-			fmt.Fprint(w, "Demotion of user notification is already sent")
+			r.flash(req, flashWarning, "User is demoted, notification about demotion was already sent.")
+			http.Redirect(w, req, "/admin/users", http.StatusSeeOther)
 		default:
 			r.serverError(w, req, err)
 		}
 		return
 	}
 
+	r.flash(req, flashSuccess, "User is demoted.")
 	http.Redirect(w, req, "/admin/users", http.StatusSeeOther)
 }
 
@@ -193,7 +202,9 @@ func (r *Routes) rejectPromotion(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	err := r.services.User.DeletePromotion(promotionID)
+	adminID := r.sesm.GetUserID(req.Context())
+
+	err := r.services.User.RejectPromotion(adminID, promotionID)
 	if err != nil {
 		if errors.Is(err, entity.ErrPromotionNotFound) {
 			r.notFound(w)
@@ -203,8 +214,6 @@ func (r *Routes) rejectPromotion(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	adminID := r.sesm.GetUserID(req.Context())
-
 	notification := entity.Notification{
 		Type:     entity.REJECT_PROMOTION,
 		UserFrom: adminID,
@@ -217,6 +226,7 @@ func (r *Routes) rejectPromotion(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	r.flash(req, flashSuccess, "Promotion request is rejected.")
 	http.Redirect(w, req, "/admin/requests", http.StatusSeeOther)
 }
 
@@ -244,16 +254,6 @@ func (r *Routes) rejectReport(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	err := r.services.User.DeleteReport(reportID)
-	if err != nil {
-		if errors.Is(err, entity.ErrReportNotFound) {
-			r.notFound(w)
-			return
-		}
-		r.serverError(w, req, err)
-		return
-	}
-
 	sourceID, err := strconv.Atoi(req.PostForm.Get("sourceID"))
 	if err != nil {
 		r.logger.Print("rejectReport:", err)
@@ -263,6 +263,16 @@ func (r *Routes) rejectReport(w http.ResponseWriter, req *http.Request) {
 
 	adminID := r.sesm.GetUserID(req.Context())
 
+	err = r.services.User.RejectReport(adminID, reportID)
+	if err != nil {
+		if errors.Is(err, entity.ErrReportNotFound) {
+			r.notFound(w)
+			return
+		}
+		r.serverError(w, req, err)
+		return
+	}
+
 	notification := entity.Notification{
 		Type:     entity.REJECT_REPORT,
 		SourceID: sourceID,
@@ -276,6 +286,7 @@ func (r *Routes) rejectReport(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	r.flash(req, flashSuccess, "Report is rejected.")
 	http.Redirect(w, req, "/user/notifications", http.StatusSeeOther)
 }
 
@@ -299,9 +310,111 @@ func (r *Routes) users(w http.ResponseWriter, req *http.Request) {
 
 	data.Models.Users = *users
 
+	data.Models.Sanctions, err = r.services.Sanction.GetCurrent()
+	if err != nil {
+		r.serverError(w, req, err)
+		return
+	}
+
 	r.render(w, req, http.StatusOK, "users.html", data)
 }
 
+// sanctionIssue warns, mutes or bans the user. Banned user is logged out
+// on all devices
+func (r *Routes) sanctionIssue(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		r.methodNotAllowed(w)
+		return
+	}
+	if err := req.ParseForm(); err != nil {
+		r.badRequest(w)
+		return
+	}
+
+	userID, ok := getIdFromPath(req, 4)
+	if !ok {
+		r.logger.Print("sanctionIssue: invalid url path")
+		r.notFound(w)
+		return
+	}
+
+	f := entity.SanctionForm{
+		UserID: userID,
+		Kind:   req.PostForm.Get("kind"),
+		Reason: strings.TrimSpace(req.PostForm.Get("reason")),
+	}
+	if days := req.PostForm.Get("days"); days != "" {
+		var err error
+		f.Days, err = strconv.Atoi(days)
+		if err != nil {
+			r.logger.Print("sanctionIssue: invalid days")
+			r.badRequest(w)
+			return
+		}
+	}
+
+	err := r.services.Sanction.Issue(&f, r.sesm.GetUserID(req.Context()))
+	if err != nil {
+		switch {
+		case errors.Is(err, entity.ErrInvalidFormData):
+			r.logger.Print("sanctionIssue: invalid form fill")
+			msg := strings.TrimSpace(getErrorMessage(&f.Validator))
+			r.flash(req, flashError, strings.ReplaceAll(msg, "\n", ". "))
+		case errors.Is(err, entity.ErrCannotSanction):
+			r.logger.Print("sanctionIssue: admin can't be sanctioned")
+			r.flash(req, flashError, "Admins can't be sanctioned.")
+		case errors.Is(err, entity.ErrUserNotFound):
+			r.logger.Print("sanctionIssue: user not found")
+			r.notFound(w)
+			return
+		default:
+			r.serverError(w, req, err)
+			return
+		}
+		http.Redirect(w, req, "/admin/users", http.StatusSeeOther)
+		return
+	}
+
+	if f.Kind == entity.SANCTION_BAN {
+		if err := r.sesm.DeleteAllTokens(req.Context(), userID); err != nil {
+			r.serverError(w, req, err)
+			return
+		}
+	}
+
+	r.flash(req, flashSuccess, "Sanction is issued.")
+	http.Redirect(w, req, "/admin/users", http.StatusSeeOther)
+}
+
+// sanctionRevoke ends sanction before it expires
+func (r *Routes) sanctionRevoke(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		r.methodNotAllowed(w)
+		return
+	}
+
+	sanctionID, ok := getIdFromPath(req, 5)
+	if !ok {
+		r.logger.Print("sanctionRevoke: invalid url path")
+		r.notFound(w)
+		return
+	}
+
+	err := r.services.Sanction.Revoke(r.sesm.GetUserID(req.Context()), sanctionID)
+	if err != nil {
+		if errors.Is(err, entity.ErrSanctionNotFound) {
+			r.logger.Print("sanctionRevoke: sanction not found")
+			r.notFound(w)
+			return
+		}
+		r.serverError(w, req, err)
+		return
+	}
+
+	r.flash(req, flashSuccess, "Sanction is revoked.")
+	http.Redirect(w, req, "/admin/users", http.StatusSeeOther)
+}
+
 func (r *Routes) tags(w http.ResponseWriter, req *http.Request) {
 	if req.Method != http.MethodGet {
 		r.methodNotAllowed(w)
@@ -329,7 +442,7 @@ func (r *Routes) tagDelete(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	err := r.services.Tag.DeleteTag(tagID)
+	err := r.services.Tag.DeleteTag(r.sesm.GetUserID(req.Context()), tagID)
 	if err != nil {
 		if errors.Is(err, entity.ErrTagNotFound) {
 			r.logger.Print("tagDelete: tag not found")
@@ -340,6 +453,7 @@ func (r *Routes) tagDelete(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	r.flash(req, flashSuccess, "Tag is deleted.")
 	http.Redirect(w, req, "/admin/tags", http.StatusSeeOther)
 }
 
@@ -355,22 +469,160 @@ func (r *Routes) tagCreate(w http.ResponseWriter, req *http.Request) {
 
 	newTag := req.PostForm.Get("newTag")
 
-	err := r.services.Tag.CreateTag(newTag)
+	err := r.services.Tag.CreateTag(r.sesm.GetUserID(req.Context()), newTag)
 	if err != nil {
 		switch {
 		case errors.Is(err, entity.ErrInvalidTag):
 			r.logger.Print("tagCreate: invalid tag name")
-			w.WriteHeader(http.StatusBadRequest)
-			fmt.Fprint(w, "Invalid tag name. Should be from 3 to 30 characters long.")
+			r.flash(req, flashError, "Invalid tag name. Should be from 3 to 30 characters long.")
 		case errors.Is(err, entity.ErrDuplicateTag):
 			r.logger.Print("tagCreate: duplicate tag name")
-			w.WriteHeader(http.StatusBadRequest)
-			fmt.Fprint(w, "Tag with such name already exists.")
+			r.flash(req, flashError, "Tag with such name already exists.")
 		default:
 			r.serverError(w, req, err)
+			return
 		}
+		http.Redirect(w, req, "/admin/tags", http.StatusSeeOther)
 		return
 	}
 
+	r.flash(req, flashSuccess, "Tag is created.")
 	http.Redirect(w, req, "/admin/tags", http.StatusSeeOther)
 }
+
+// lockouts shows accounts and addresses with recent failed logins
+func (r *Routes) lockouts(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		r.methodNotAllowed(w)
+		return
+	}
+
+	data, err := r.newTemplateData(req)
+	if err != nil {
+		r.serverError(w, req, err)
+		return
+	}
+
+	lockouts, err := r.services.Lockout.GetLockouts()
+	if err != nil {
+		r.serverError(w, req, err)
+		return
+	}
+
+	data.Models.Lockouts = lockouts
+
+	r.render(w, req, http.StatusOK, "lockouts.html", data)
+}
+
+// lockoutClear unlocks account or address and forgets its failed logins
+func (r *Routes) lockoutClear(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		r.methodNotAllowed(w)
+		return
+	}
+
+	lockoutID, ok := getIdFromPath(req, 5)
+	if !ok {
+		r.logger.Print("lockoutClear: invalid url path")
+		r.notFound(w)
+		return
+	}
+
+	err := r.services.Lockout.Clear(r.sesm.GetUserID(req.Context()), lockoutID)
+	if err != nil {
+		if errors.Is(err, entity.ErrLockoutNotFound) {
+			r.logger.Print("lockoutClear: lockout not found")
+			r.notFound(w)
+			return
+		}
+		r.serverError(w, req, err)
+		return
+	}
+
+	r.flash(req, flashSuccess, "Lockout is cleared.")
+	http.Redirect(w, req, "/admin/lockouts", http.StatusSeeOther)
+}
+
+// moderationLog shows privileged actions of moderators and admins, newest
+// first, filtered by actor, action and target
+func (r *Routes) moderationLog(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		r.methodNotAllowed(w)
+		return
+	}
+
+	data, err := r.newTemplateData(req)
+	if err != nil {
+		r.serverError(w, req, err)
+		return
+	}
+
+	params := req.URL.Query()
+
+	f := entity.ModerationFilter{
+		Actor:      strings.TrimSpace(params.Get("actor")),
+		Action:     params.Get("action"),
+		TargetType: params.Get("target"),
+	}
+
+	if targetID := params.Get("targetID"); targetID != "" {
+		id, ok := getValidID(targetID)
+		if !ok {
+			r.logger.Print("moderationLog: invalid target id")
+			r.badRequest(w)
+			return
+		}
+		f.TargetID = id
+	}
+
+	if before := params.Get("before"); before != "" {
+		id, ok := getValidID(before)
+		if !ok {
+			r.logger.Print("moderationLog: invalid before id")
+			r.badRequest(w)
+			return
+		}
+		f.Before = id
+	}
+
+	entries, next, err := r.services.Moderation.GetLog(f)
+	if err != nil {
+		if errors.Is(err, entity.ErrInvalidModerationLog) {
+			r.logger.Print("moderationLog: invalid filter")
+			r.badRequest(w)
+			return
+		}
+		r.serverError(w, req, err)
+		return
+	}
+
+	data.Models.Moderation = entries
+	data.Models.ModerationView = f
+	data.Models.ModerationNext = next
+
+	r.render(w, req, http.StatusOK, "moderation.html", data)
+}
+
+// moderationURL returns url of moderation log page with the same filter,
+// starting right before entry with id before
+func moderationURL(f entity.ModerationFilter, before int) string {
+	params := url.Values{}
+
+	if f.Actor != "" {
+		params.Set("actor", f.Actor)
+	}
+	if f.Action != "" {
+		params.Set("action", f.Action)
+	}
+	if f.TargetType != "" {
+		params.Set("target", f.TargetType)
+	}
+	if f.TargetID != 0 {
+		params.Set("targetID", strconv.Itoa(f.TargetID))
+	}
+	if before != 0 {
+		params.Set("before", strconv.Itoa(before))
+	}
+
+	return "/admin/moderation?" + params.Encode()
+}
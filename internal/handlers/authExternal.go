@@ -1,211 +1,221 @@
 package handlers
 
 import (
-	"encoding/json"
+	"crypto/subtle"
+	"encoding/gob"
 	"errors"
 	"fmt"
 	"forum/internal/entity"
-	"forum/pkg/pswd"
-	"io"
+	"forum/pkg/oauth"
 	"net/http"
-	"net/url"
 	"strings"
 )
 
-const (
-	ACCESS_TOKEN         = "access_token"
-	GOOGLE_USER_INFO_URL = "https://www.googleapis.com/oauth2/v2/userinfo"
-	GOOGLE_TOKEN_URL     = "https://accounts.google.com/o/oauth2/token"
-	GITHUB_USER_INFO_URL = "https://api.github.com/user"
-	GITHUB_TOKEN_URL     = "https://github.com/login/oauth/access_token"
-)
+var errInvalidOAuthState = errors.New("handlers: invalid oauth state")
 
-// SSO is external auth login handler that handles registration (or authorization if user
-// already exists) to the website
-func (r *Routes) SSO(w http.ResponseWriter, req *http.Request, form *entity.UserSignupForm) {
-	id, err := r.services.User.SaveUser(form)
-	if err != nil {
-		if errors.Is(err, entity.ErrDuplicateEmail) || errors.Is(err, entity.ErrDuplicateUsername) {
-			user, err := r.services.User.GetUserByEmail(form.Email)
-			if err != nil {
-				r.serverError(w, req, err)
-				return
-			}
+func init() {
+	// Identity of unfinished signup is kept in session
+	gob.Register(entity.ExternalIdentity{})
+}
+
+// legacyCallbacks are callback addresses used before providers became
+// configurable, they may still be registered at providers
+var legacyCallbacks = map[string]string{
+	"/callbackGoogle": "google",
+	"/callbackGithub": "github",
+}
 
-			id = user.ID
-		} else {
-			r.serverError(w, req, err)
-			return
-		}
+// SSO logs in user that identity is linked to. Identity that isn't linked
+// to any user gets new account. Accounts are never matched by email or
+// username, user links identity to existing account from settings instead
+func (r *Routes) SSO(w http.ResponseWriter, req *http.Request, identity entity.ExternalIdentity) {
+	userID, err := r.services.Identity.Login(identity)
+	switch {
+	case err == nil:
+		r.logIn(w, req, userID, "", false)
+		return
+	case !errors.Is(err, entity.ErrIdentityNotLinked):
+		r.serverError(w, req, err)
+		return
 	}
 
-	role, err := r.services.User.GetUserRole(id)
-	if err != nil {
+	r.externalSignup(w, req, &entity.ExternalSignupForm{Username: identity.Username, Identity: identity})
+}
+
+// externalSignup creates account for identity. If username given by
+// provider can't be used, user is asked for another one
+func (r *Routes) externalSignup(w http.ResponseWriter, req *http.Request, form *entity.ExternalSignupForm) {
+	userID, err := r.services.Identity.SignUp(form)
+	if err == nil {
+		r.sesm.Remove(req.Context(), oauthIdentityKey)
+		r.logIn(w, req, userID, "", false)
+		return
+	}
+
+	status := http.StatusBadRequest
+	switch {
+	case errors.Is(err, entity.ErrDuplicateEmail):
+		r.logger.Print("externalSignup: email is already in use")
+		r.sesm.Remove(req.Context(), oauthIdentityKey)
+		status = http.StatusConflict
+	case errors.Is(err, entity.ErrInvalidFormData), errors.Is(err, entity.ErrDuplicateUsername):
+		r.logger.Print("externalSignup: invalid form fill")
+		r.sesm.Put(req.Context(), oauthIdentityKey, form.Identity)
+	default:
 		r.serverError(w, req, err)
 		return
 	}
 
-	err = r.sesm.RenewToken(req.Context(), id)
+	data, err := r.newTemplateData(req)
 	if err != nil {
 		r.serverError(w, req, err)
 		return
 	}
+	data.Form = form
+	if status == http.StatusConflict {
+		data.Notice = fmt.Sprintf("An account with email %s already exists. Log in to it and connect %s in settings.",
+			form.Identity.Email, r.providerName(form.Identity.Provider))
+	}
 
-	r.sesm.PutUserID(req.Context(), id)
-	r.sesm.PutUserRole(req.Context(), role)
-
-	http.Redirect(w, req, "/", http.StatusSeeOther)
+	r.render(w, req, status, "external.html", data)
 }
 
-/*
-	GOOGLE LOGIN
-*/
+// userExternal completes signup through external provider with username
+// picked by the user
+func (r *Routes) userExternal(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		r.methodNotAllowed(w)
+		return
+	}
+	if err := req.ParseForm(); err != nil {
+		r.logger.Print("userExternal: invalid form fill (parse error)")
+		r.badRequest(w)
+		return
+	}
 
-func (r *Routes) googlelogin(w http.ResponseWriter, req *http.Request) {
-	url := fmt.Sprintf("https://accounts.google.com/o/oauth2/auth?client_id=%s&redirect_uri=%s&response_type=code&scope=email profile",
-		r.cfg.ExternalAuth.GoogleClientID, r.cfg.ExternalAuth.GoogleRedirectURL)
+	identity, ok := r.sesm.Get(req.Context(), oauthIdentityKey).(entity.ExternalIdentity)
+	if !ok {
+		http.Redirect(w, req, "/", http.StatusSeeOther)
+		return
+	}
 
-	http.Redirect(w, req, url, http.StatusTemporaryRedirect)
+	r.externalSignup(w, req, &entity.ExternalSignupForm{
+		Username: req.PostForm.Get("username"),
+		Identity: identity,
+	})
 }
 
-func (r *Routes) googleCallback(w http.ResponseWriter, req *http.Request) {
-	code := req.URL.Query().Get("code")
-	clientData := fmt.Sprintf("code=%s&client_id=%s&client_secret=%s&redirect_uri=%s&grant_type=authorization_code",
-		code, r.cfg.ExternalAuth.GoogleClientID, r.cfg.ExternalAuth.GoogleClientSecret, r.cfg.ExternalAuth.GoogleRedirectURL)
-
-	resp, err := http.Post(GOOGLE_TOKEN_URL, "application/x-www-form-urlencoded", strings.NewReader(clientData))
-	if err != nil {
-		r.serverError(w, req, err)
-		return
+// providerName returns name of provider shown to users
+func (r *Routes) providerName(name string) string {
+	if p, ok := r.providers.Get(name); ok {
+		return p.DisplayName()
 	}
-	defer resp.Body.Close()
+	return name
+}
 
-	var tokenResponse map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&tokenResponse); err != nil {
-		r.serverError(w, req, err)
+// oauthLogin starts login through provider from the path (/login/{provider}).
+// State and PKCE verifier of the attempt are kept in session until provider
+// redirects user back
+func (r *Routes) oauthLogin(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		r.methodNotAllowed(w)
 		return
 	}
 
-	accessToken, ok := tokenResponse[ACCESS_TOKEN].(string)
+	p, ok := r.providers.Get(strings.TrimPrefix(req.URL.Path, "/login/"))
 	if !ok {
-		r.forbidden(w)
+		r.notFound(w)
 		return
 	}
 
-	newReq, err := http.NewRequest("GET", GOOGLE_USER_INFO_URL, nil)
+	state, err := oauth.RandomString()
 	if err != nil {
 		r.serverError(w, req, err)
 		return
 	}
-	newReq.Header.Add("Authorization", "Bearer "+accessToken)
-
-	userInfoResp, err := http.DefaultClient.Do(newReq)
+	verifier, err := oauth.RandomString()
 	if err != nil {
 		r.serverError(w, req, err)
 		return
 	}
-	defer userInfoResp.Body.Close()
 
-	var googleInfo struct {
-		Name  string `json:"name"`
-		Email string `json:"email"`
-	}
-	if err := json.NewDecoder(userInfoResp.Body).Decode(&googleInfo); err != nil {
-		r.serverError(w, req, err)
-		return
-	}
+	// State is bound to provider, so callback of another provider can't
+	// complete the attempt
+	state = p.Name() + "." + state
 
-	form := entity.UserSignupForm{
-		Username: googleInfo.Name,
-		Email:    googleInfo.Email,
-	}
-	form.Password, err = pswd.GenerateRandomPassword(8)
+	authURL, err := p.AuthCodeURL(req.Context(), state, verifier)
 	if err != nil {
 		r.serverError(w, req, err)
 		return
 	}
 
-	r.SSO(w, req, &form)
-}
-
-/*
-	GITHUB LOGIN
-*/
-
-func (r *Routes) githublogin(w http.ResponseWriter, req *http.Request) {
-	redirectURL := fmt.Sprintf(
-		"https://github.com/login/oauth/authorize?client_id=%s&redirect_uri=%s",
-		r.cfg.ExternalAuth.GithubClientID, r.cfg.ExternalAuth.GithubRedirectURL,
-	)
-
-	http.Redirect(w, req, redirectURL, http.StatusTemporaryRedirect)
+	r.sesm.Put(req.Context(), oauthStateKey, state)
+	r.sesm.Put(req.Context(), oauthVerifierKey, verifier)
 
+	http.Redirect(w, req, authURL, http.StatusTemporaryRedirect)
 }
 
-func (r *Routes) githubCallback(w http.ResponseWriter, req *http.Request) {
-	code := req.URL.Query().Get("code")
-	payload := url.Values{
-		"client_id":     {r.cfg.ExternalAuth.GithubClientID},
-		"client_secret": {r.cfg.ExternalAuth.GithubClientSecret},
-		"code":          {code},
-		"redirect_uri":  {r.cfg.ExternalAuth.GithubRedirectURL},
-	}
-	resp, err := http.PostForm(GITHUB_TOKEN_URL, payload)
-	if err != nil {
-		r.serverError(w, req, err)
-		return
+// oauthCallback completes login through provider from the path
+// (/callback/{provider})
+func (r *Routes) oauthCallback(w http.ResponseWriter, req *http.Request) {
+	name, ok := legacyCallbacks[req.URL.Path]
+	if !ok {
+		name = strings.TrimPrefix(req.URL.Path, "/callback/")
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		r.serverError(w, req, err)
+	p, ok := r.providers.Get(name)
+	if !ok {
+		r.notFound(w)
 		return
 	}
 
-	tokenResp, err := url.ParseQuery(string(body))
+	id, err := r.oauthIdentify(req, p)
 	if err != nil {
-		r.serverError(w, req, err)
+		r.oauthCallbackError(w, req, err)
 		return
 	}
-	accessToken := tokenResp.Get(ACCESS_TOKEN)
 
-	newreq, err := http.NewRequest("GET", GITHUB_USER_INFO_URL, nil)
-	if err != nil {
-		r.serverError(w, req, err)
-		return
+	identity := entity.ExternalIdentity{
+		Provider:      id.Provider,
+		Subject:       id.Subject,
+		Email:         id.Email,
+		Username:      id.Username,
+		EmailVerified: id.EmailVerified,
+		Placeholder:   id.Placeholder,
 	}
 
-	newreq.Header.Set("Authorization", "token "+accessToken)
-	client := &http.Client{}
-	resp, err = client.Do(newreq)
-	if err != nil {
-		r.serverError(w, req, err)
+	// Logged in user connects provider from settings
+	if r.isAuthenticated(req) {
+		r.oauthConnect(w, req, identity)
 		return
 	}
-	defer resp.Body.Close()
 
-	var githubInfo struct {
-		Login string `json:"login"`
-		Email string `json:"email"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&githubInfo); err != nil {
-		r.serverError(w, req, err)
-		return
-	}
+	r.SSO(w, req, identity)
+}
 
-	if githubInfo.Email == "" {
-		githubInfo.Email = githubInfo.Login + "@github.com"
-	}
-	form := entity.UserSignupForm{
-		Username: githubInfo.Login,
-		Email:    githubInfo.Email,
+// oauthIdentify checks that callback belongs to the attempt started in this
+// session and returns identity of the user
+func (r *Routes) oauthIdentify(req *http.Request, p *oauth.Provider) (oauth.Identity, error) {
+	state := r.sesm.PopString(req.Context(), oauthStateKey)
+	verifier := r.sesm.PopString(req.Context(), oauthVerifierKey)
+
+	q := req.URL.Query()
+	if state == "" || !strings.HasPrefix(state, p.Name()+".") ||
+		subtle.ConstantTimeCompare([]byte(q.Get("state")), []byte(state)) != 1 {
+		return oauth.Identity{}, errInvalidOAuthState
 	}
-	form.Password, err = pswd.GenerateRandomPassword(8)
-	if err != nil {
-		r.serverError(w, req, err)
+
+	return p.Identify(req.Context(), q.Get("code"), state, verifier)
+}
+
+// oauthCallbackError responds to failed callback. Invalid state, code or ID
+// token means that request wasn't started by this user, so it's forbidden
+func (r *Routes) oauthCallbackError(w http.ResponseWriter, req *http.Request, err error) {
+	if errors.Is(err, errInvalidOAuthState) || errors.Is(err, oauth.ErrExchange) ||
+		errors.Is(err, oauth.ErrInvalidIDToken) {
+		r.logger.Printf("oauthCallback: %v", err)
+		r.forbidden(w)
 		return
 	}
-
-	r.SSO(w, req, &form)
+	r.serverError(w, req, err)
 }
@@ -43,7 +43,29 @@ func (r *Routes) commentCreate(w http.ResponseWriter, req *http.Request) {
 		Content: content,
 	}
 
-	err = r.services.Comment.SaveComment(comment, postID, userID)
+	// Replies have id of the comment they answer
+	if parentIDStr := req.PostForm.Get("parentID"); parentIDStr != "" {
+		parentID, ok := getValidID(parentIDStr)
+		if !ok || parentID == 0 {
+			r.logger.Print("commentCreate: invalid parent comment id")
+			r.badRequest(w)
+			return
+		}
+		comment.ParentID = parentID
+	}
+
+	// Page the comment is left from - the post page or one of its threads
+	var threadID int
+	if threadStr := req.PostForm.Get("thread"); threadStr != "" {
+		threadID, ok = getValidID(threadStr)
+		if !ok {
+			r.logger.Print("commentCreate: invalid thread id")
+			r.badRequest(w)
+			return
+		}
+	}
+
+	commentID, err := r.services.Comment.SaveComment(comment, postID, userID)
 	if err != nil {
 		switch {
 		case errors.Is(err, entity.ErrInvalidFormData):
@@ -67,21 +89,63 @@ func (r *Routes) commentCreate(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	notification := entity.Notification{
-		Type:       entity.COMMENTED,
-		SourceID:   postID,
-		SourceType: entity.COMMENT,
-		UserFrom:   userID,
-		UserTo:     authorID,
+	var parentAuthorID int
+
+	if comment.ParentID != 0 {
+		parentAuthorID, err = r.services.Comment.GetAuthorID(comment.ParentID)
+		if err != nil {
+			r.serverError(w, req, err)
+			return
+		}
+
+		// Author of the replied comment is notified unless it's reply to himself
+		if parentAuthorID != userID {
+			notification := entity.Notification{
+				Type:       entity.REPLIED,
+				SourceID:   postID,
+				SourceType: entity.COMMENT,
+				UserFrom:   userID,
+				UserTo:     parentAuthorID,
+			}
+
+			err = r.services.User.SendNotification(notification)
+			if err != nil {
+				r.serverError(w, req, err)
+				return
+			}
+		}
 	}
 
-	err = r.services.User.SendNotification(notification)
+	// Post author who was replied to directly has been already notified
+	if parentAuthorID != authorID {
+		notification := entity.Notification{
+			Type:       entity.COMMENTED,
+			SourceID:   postID,
+			SourceType: entity.COMMENT,
+			UserFrom:   userID,
+			UserTo:     authorID,
+		}
+
+		err = r.services.User.SendNotification(notification)
+		if err != nil {
+			r.serverError(w, req, err)
+			return
+		}
+	}
+
+	// User is returned to the page the comment was left from, unless it's
+	// nested too deep to be shown there
+	threadID, err = r.services.Comment.GetVisibleThread(postID, commentID, threadID)
 	if err != nil {
 		r.serverError(w, req, err)
 		return
 	}
 
-	redirectURL := fmt.Sprintf("/post/view/%d", postID)
+	redirectURL := fmt.Sprintf("/post/view/%d#comment-%d", postID, commentID)
+	if threadID != 0 {
+		redirectURL = fmt.Sprintf("/post/view/%d?thread=%d#comment-%d", postID, threadID, commentID)
+	}
+
 	w.Header().Set("Content-Type", "text/plain")
 	fmt.Fprint(w, redirectURL)
 }
@@ -132,6 +196,7 @@ func (r *Routes) commentDelete(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	r.flash(req, flashSuccess, "Comment is deleted.")
 	http.Redirect(w, req, fmt.Sprintf("/post/view/%d", postID), http.StatusSeeOther)
 }
 
@@ -174,7 +239,9 @@ func (r *Routes) commentDeletePrivileged(w http.ResponseWriter, req *http.Reques
 
 	userID := r.sesm.GetUserID(req.Context())
 
-	err := r.services.Comment.DeleteCommentPrivileged(commentID, userID, userRole)
+	reason := strings.TrimSpace(req.PostForm.Get("reason"))
+
+	err := r.services.Comment.DeleteCommentPrivileged(commentID, userID, userRole, reason)
 	if err != nil {
 		if errors.Is(err, entity.ErrCommentNotFound) {
 			r.notFound(w)
@@ -184,6 +251,7 @@ func (r *Routes) commentDeletePrivileged(w http.ResponseWriter, req *http.Reques
 		return
 	}
 
+	r.flash(req, flashSuccess, "Comment is deleted.")
 	http.Redirect(w, req, fmt.Sprintf("/post/view/%d", postID), http.StatusSeeOther)
 }
 
@@ -233,20 +301,22 @@ func (r *Routes) commentReport(w http.ResponseWriter, req *http.Request) {
 		UserFrom:   userID,
 		SourceID:   postID,
 		SourceType: entity.COMMENT,
+		CommentID:  commentID,
 	}
 
 	err = r.services.User.SendReport(report)
 	if err != nil {
 		if errors.Is(err, entity.ErrDuplicateReport) {
 			r.logger.Print("commentReport: report is already sent")
-			w.WriteHeader(http.StatusBadRequest)
-			fmt.Fprint(w, "Report is already sent")
+			r.flash(req, flashWarning, "Report is already sent.")
+			http.Redirect(w, req, fmt.Sprintf("/post/view/%d", postID), http.StatusSeeOther)
 			return
 		}
 		r.serverError(w, req, err)
 		return
 	}
 
+	r.flash(req, flashSuccess, "Report is sent to admins.")
 	http.Redirect(w, req, fmt.Sprintf("/post/view/%d", postID), http.StatusSeeOther)
 }
 
@@ -329,3 +399,44 @@ func (r *Routes) commentEditPost(w http.ResponseWriter, req *http.Request) {
 
 	http.Redirect(w, req, fmt.Sprintf("/post/view/%d", postID), http.StatusSeeOther)
 }
+
+// commentRevisions shows all versions of comment, so moderators can see what
+// reported comment said before it was edited
+func (r *Routes) commentRevisions(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		r.methodNotAllowed(w)
+		return
+	}
+
+	commentID, ok := getIdFromPath(req, 5)
+	if !ok {
+		r.logger.Print("commentRevisions: invalid url path")
+		r.notFound(w)
+		return
+	}
+
+	data, err := r.newTemplateData(req)
+	if err != nil {
+		r.serverError(w, req, err)
+		return
+	}
+
+	userRole := r.sesm.GetUserRole(req.Context())
+
+	history, err := r.services.Comment.GetCommentHistory(commentID, userRole)
+	if err != nil {
+		switch {
+		case errors.Is(err, entity.ErrCommentNotFound):
+			r.notFound(w)
+		case errors.Is(err, entity.ErrForbiddenAccess):
+			r.forbidden(w)
+		default:
+			r.serverError(w, req, err)
+		}
+		return
+	}
+
+	data.Models.CommentHistory = history
+
+	r.render(w, req, http.StatusOK, "comment_revisions.html", data)
+}
@@ -22,6 +22,8 @@ func TestCommentCreate(t *testing.T) {
 		name     string
 		content  string
 		postID   string
+		parentID string
+		thread   string
 		wantCode int
 		wantBody string
 	}{
@@ -30,7 +32,33 @@ func TestCommentCreate(t *testing.T) {
 			content:  validContent,
 			postID:   validPostID,
 			wantCode: http.StatusOK,
-			wantBody: "/post/view/1",
+			wantBody: "/post/view/1#comment-2",
+		},
+		{
+			name:     "Reply on post page",
+			content:  validContent,
+			postID:   validPostID,
+			parentID: "1",
+			thread:   "0",
+			wantCode: http.StatusOK,
+			wantBody: "/post/view/1#comment-2",
+		},
+		{
+			name:     "Reply on thread page",
+			content:  validContent,
+			postID:   validPostID,
+			parentID: "1",
+			thread:   "1",
+			wantCode: http.StatusOK,
+			wantBody: "/post/view/1?thread=1#comment-2",
+		},
+		{
+			name:     "Invalid thread",
+			content:  validContent,
+			postID:   validPostID,
+			parentID: "1",
+			thread:   "nah",
+			wantCode: http.StatusBadRequest,
 		},
 		{
 			name:     "Blank content",
@@ -63,6 +91,12 @@ func TestCommentCreate(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			form := url.Values{}
 			form.Add("commentContent", tt.content)
+			if tt.parentID != "" {
+				form.Add("parentID", tt.parentID)
+			}
+			if tt.thread != "" {
+				form.Add("thread", tt.thread)
+			}
 
 			code, _, body := ts.postForm(t, fmt.Sprintf("/post/comment/%s", tt.postID), form)
 			assert.Equal(t, code, tt.wantCode)
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"encoding/gob"
+	"net/http"
+)
+
+// Kinds of flash messages, they are parts of CSS classes of the messages
+const (
+	flashSuccess = "success"
+	flashWarning = "warning"
+	flashError   = "error"
+)
+
+// flashKey is the key of flash messages in session
+const flashKey = "flash"
+
+// flashMessage is shown once on the next rendered page, usually after
+// redirect
+type flashMessage struct {
+	Kind    string
+	Message string
+}
+
+func init() {
+	gob.Register([]flashMessage{})
+}
+
+// flash adds message that is shown on the next rendered page
+func (r *Routes) flash(req *http.Request, kind, message string) {
+	flashes, _ := r.sesm.Get(req.Context(), flashKey).([]flashMessage)
+	flashes = append(flashes, flashMessage{Kind: kind, Message: message})
+
+	r.sesm.Put(req.Context(), flashKey, flashes)
+}
+
+// popFlashes returns messages added by previous requests and removes them
+// from session, so each message is shown once
+func (r *Routes) popFlashes(req *http.Request) []flashMessage {
+	flashes, _ := r.sesm.Pop(req.Context(), flashKey).([]flashMessage)
+	return flashes
+}
@@ -4,10 +4,13 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"forum/internal/entity"
 	"forum/internal/validator"
+	"forum/pkg/realip"
 	"forum/web"
 	"html/template"
 	"net/http"
+	"net/url"
 	"runtime/debug"
 	"strconv"
 	"strings"
@@ -24,24 +27,91 @@ func (r *Routes) newTemplateData(req *http.Request) (templateData, error) {
 		return templateData{}, err
 	}
 
+	csrfToken, err := r.sesm.CSRFToken(req.Context())
+	if err != nil {
+		return templateData{}, err
+	}
+
 	var notificationsCount int
+	var isVerified bool
 
 	if userID != 0 {
 		notificationsCount, err = r.services.User.GetNotificationsCount(userID)
 		if err != nil {
 			return templateData{}, err
 		}
+		isVerified, err = r.services.Account.IsVerified(userID)
+		if err != nil {
+			return templateData{}, err
+		}
 	}
 
 	return templateData{
 		IsAuthenticated:    r.isAuthenticated(req),
+		IsVerified:         isVerified,
 		Models:             Models{Tags: *tags},
 		Username:           username,
 		UserRole:           userRole,
 		NotificationsCount: notificationsCount,
+		Providers:          r.providers.List(),
+		CSRFToken:          csrfToken,
 	}, nil
 }
 
+// identityOf returns identity of given provider or nil if it isn't linked
+func identityOf(identities []entity.Identity, provider string) *entity.Identity {
+	for i := range identities {
+		if identities[i].Provider == provider {
+			return &identities[i]
+		}
+	}
+	return nil
+}
+
+// device returns short description of browser and system from user agent
+func device(userAgent string) string {
+	if userAgent == "" {
+		return "Unknown device"
+	}
+
+	// Order matters, user agents of most browsers mention other browsers
+	browser := "Unknown browser"
+	for _, b := range []struct{ token, name string }{
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+		{"curl/", "curl"},
+	} {
+		if strings.Contains(userAgent, b.token) {
+			browser = b.name
+			break
+		}
+	}
+
+	system := ""
+	for _, s := range []struct{ token, name string }{
+		{"Android", "Android"},
+		{"iPhone", "iOS"},
+		{"iPad", "iPadOS"},
+		{"Windows", "Windows"},
+		{"Mac OS X", "macOS"},
+		{"CrOS", "ChromeOS"},
+		{"Linux", "Linux"},
+	} {
+		if strings.Contains(userAgent, s.token) {
+			system = s.name
+			break
+		}
+	}
+
+	if system == "" {
+		return browser
+	}
+	return browser + " on " + system
+}
+
 func (r *Routes) isAuthenticated(req *http.Request) bool {
 	return r.sesm.ExistsUserID(req.Context())
 }
@@ -50,10 +120,11 @@ func (r *Routes) serverError(w http.ResponseWriter, req *http.Request, err error
 	var (
 		method = req.Method
 		uri    = req.RequestURI
+		ip     = realip.FromRequest(req)
 		trace  = string(debug.Stack())
 	)
 
-	r.logger.Printf(err.Error()+"; method - %s, uri - %s, stack - %s", method, uri, trace)
+	r.logger.Printf(err.Error()+"; method - %s, uri - %s, ip - %s, stack - %s", method, uri, ip, trace)
 
 	errInfo := errData{
 		ErrCode: http.StatusInternalServerError,
@@ -94,15 +165,50 @@ func (r *Routes) forbidden(w http.ResponseWriter) {
 	r.clientError(w, http.StatusForbidden)
 }
 
-func (r *Routes) rateLimitExceeded(w http.ResponseWriter, timeLeft time.Duration) {
+// csrfFailed is sent when form doesn't carry CSRF token of the session. It
+// happens when form was sent by another site or page was opened before login
+func (r *Routes) csrfFailed(w http.ResponseWriter) {
 	errInfo := errData{
-		ErrCode: http.StatusTooManyRequests,
-		ErrMsg:  fmt.Sprintf("You're blocked!\nWait - %.1fs", timeLeft.Seconds()),
+		ErrCode: http.StatusForbidden,
+		ErrMsg:  "The form has expired.\nGo back, reload the page and try again",
 	}
 
 	r.renderErrorPage(w, errInfo)
 }
 
+func (r *Routes) rateLimitExceeded(w http.ResponseWriter, req *http.Request, retryAfter time.Duration) {
+	msg := fmt.Sprintf("Too many requests, try again in %d s", seconds(retryAfter))
+
+	// Scripts show response text as error
+	if req.Header.Get(csrfHeader) != "" {
+		w.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprint(w, msg)
+		return
+	}
+
+	r.renderErrorPage(w, errData{
+		ErrCode: http.StatusTooManyRequests,
+		ErrMsg:  msg,
+	})
+}
+
+// sanctioned tells banned or muted user why request is refused
+func (r *Routes) sanctioned(w http.ResponseWriter, req *http.Request, s entity.Sanction) {
+	msg := r.services.Sanction.Describe(s)
+
+	// Scripts show response text as error
+	if req.Header.Get(csrfHeader) != "" {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, msg)
+		return
+	}
+
+	r.renderErrorPage(w, errData{
+		ErrCode: http.StatusForbidden,
+		ErrMsg:  msg,
+	})
+}
+
 // Render templates by retrieving necessary template from template cache.
 //
 // First execute into dummy buffer for any execution error catch (to set appropriate header)
@@ -114,6 +220,9 @@ func (r *Routes) render(w http.ResponseWriter, req *http.Request, status int, pa
 		return
 	}
 
+	// Messages are taken out of session before it's saved by WriteHeader
+	data.Flashes = r.popFlashes(req)
+
 	buf := new(bytes.Buffer)
 	if err := tmpl.ExecuteTemplate(buf, "base", data); err != nil {
 		r.serverError(w, req, err)
@@ -207,3 +316,50 @@ func (r *Routes) getBaseInfo(req *http.Request) (int, templateData, error) {
 
 	return userID, data, nil
 }
+
+// getPageParams reads sorting and cursors of requested page from url query.
+// Page size is taken from config
+func (r *Routes) getPageParams(req *http.Request) entity.PageParams {
+	query := req.URL.Query()
+
+	return entity.PageParams{
+		After:  query.Get("after"),
+		Before: query.Get("before"),
+		Limit:  r.cfg.PageSize,
+		Sort:   query.Get("sort"),
+		Period: query.Get("t"),
+	}
+}
+
+// pageURL returns relative url of listing page with the same sorting and
+// given cursor ("after" or "before"). Used in templates for pagination
+func pageURL(page entity.Page, key, cursor string) string {
+	params := url.Values{}
+
+	if page.Sort != entity.SORT_NEW {
+		params.Set("sort", page.Sort)
+	}
+	if page.Period != "" {
+		params.Set("t", page.Period)
+	}
+	params.Set(key, cursor)
+
+	return "?" + params.Encode()
+}
+
+// Keys of values kept in session
+const (
+	// State and PKCE verifier of unfinished login through external provider
+	oauthStateKey    = "oauthState"
+	oauthVerifierKey = "oauthVerifier"
+
+	// Identity of external signup that waits for username
+	oauthIdentityKey = "oauthIdentity"
+
+	// User that passed the first login step and waits for the second one,
+	// email or username entered on the first step and number of failed
+	// attempts of the second step
+	pendingUserKey       = "pendingUserID"
+	pendingIdentifierKey = "pendingIdentifier"
+	pendingAttemptsKey   = "pendingAttempts"
+)
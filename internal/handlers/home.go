@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"errors"
+	"forum/internal/entity"
 	"net/http"
 	"strings"
 )
@@ -30,18 +32,30 @@ func (r *Routes) home(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	// Search form on home page may be submitted to home page itself
+	if req.URL.Query().Has("q") {
+		r.search(w, req)
+		return
+	}
+
 	data, err := r.newTemplateData(req)
 	if err != nil {
 		r.serverError(w, req, err)
 		return
 	}
 
-	posts, err := r.services.Post.GetAllPosts()
+	posts, page, err := r.services.Post.GetAllPosts(r.getPageParams(req))
 	if err != nil {
+		if errors.Is(err, entity.ErrInvalidCursor) || errors.Is(err, entity.ErrInvalidSort) {
+			r.logger.Print("home: invalid page parameters")
+			r.badRequest(w)
+			return
+		}
 		r.serverError(w, req, err)
 		return
 	}
 	data.Models.Posts = *posts
+	data.Models.Page = page
 
 	r.render(w, req, http.StatusOK, "home.html", data)
 }
@@ -76,13 +90,19 @@ func (r *Routes) sortedByTag(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	posts, err := r.services.Post.GetAllPostsByTagId(tagID)
+	posts, page, err := r.services.Post.GetAllPostsByTagId(tagID, r.getPageParams(req))
 	if err != nil {
+		if errors.Is(err, entity.ErrInvalidCursor) || errors.Is(err, entity.ErrInvalidSort) {
+			r.logger.Print("sortedByTag: invalid page parameters")
+			r.badRequest(w)
+			return
+		}
 		r.serverError(w, req, err)
 		return
 	}
 
 	data.Models.Posts = *posts
+	data.Models.Page = page
 
 	r.render(w, req, http.StatusOK, "home.html", data)
 }
@@ -4,11 +4,19 @@ import (
 	"fmt"
 	"forum/internal/entity"
 	"forum/pkg/rate"
+	"forum/pkg/realip"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 )
 
+const (
+	csrfField  = "csrf_token"
+	csrfHeader = "X-CSRF-Token"
+)
+
 // requireAuthentication middleware checks if user if authenticated
 // and if not, redirects to login page.
 //
@@ -27,6 +35,122 @@ func (r *Routes) requireAuthentication(next http.Handler) http.Handler {
 	})
 }
 
+// requireVerifiedEmail middleware allows only users with confirmed email
+// address to pass. It's used after requireAuthentication
+func (r *Routes) requireVerifiedEmail(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		verified, err := r.services.Account.IsVerified(r.sesm.GetUserID(req.Context()))
+		if err != nil {
+			r.serverError(w, req, err)
+			return
+		}
+
+		if !verified {
+			r.logger.Print("requireVerifiedEmail: email isn't verified")
+
+			// Forms are submitted by scripts that show response text as error
+			if req.Method != http.MethodGet {
+				w.WriteHeader(http.StatusForbidden)
+				fmt.Fprint(w, "Confirm your email address to create posts and comments")
+				return
+			}
+
+			data, err := r.newTemplateData(req)
+			if err != nil {
+				r.serverError(w, req, err)
+				return
+			}
+			r.render(w, req, http.StatusForbidden, "verify.html", data)
+			return
+		}
+
+		next.ServeHTTP(w, req)
+	})
+}
+
+// requireTwoFactor middleware makes moderators and admins set up two-factor
+// authentication when admin made it mandatory for them. It's used after
+// requireAuthentication
+func (r *Routes) requireTwoFactor(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		required, err := r.services.TwoFactor.IsRequired(r.sesm.GetUserRole(req.Context()))
+		if err != nil {
+			r.serverError(w, req, err)
+			return
+		}
+
+		if required {
+			enabled, err := r.services.TwoFactor.IsEnabled(r.sesm.GetUserID(req.Context()))
+			if err != nil {
+				r.serverError(w, req, err)
+				return
+			}
+
+			if !enabled {
+				r.logger.Print("requireTwoFactor: 2FA isn't set up")
+
+				// Forms are submitted by scripts that show response text as error
+				if req.Method != http.MethodGet {
+					w.WriteHeader(http.StatusForbidden)
+					fmt.Fprint(w, "Set up two-factor authentication in settings first")
+					return
+				}
+
+				http.Redirect(w, req, "/user/settings/2fa", http.StatusSeeOther)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, req)
+	})
+}
+
+// verifyCSRF middleware rejects state-changing requests that don't carry
+// CSRF token of the session. Pages get the token with template data, forms
+// send it back in hidden field and scripts in header
+func (r *Routes) verifyCSRF(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		token := req.Header.Get(csrfHeader)
+		fromScript := token != ""
+		if !fromScript {
+			token = csrfFormValue(req)
+		}
+
+		if !r.sesm.VerifyCSRFToken(req.Context(), token) {
+			r.logger.Print("verifyCSRF: invalid csrf token")
+
+			// Scripts show response text as error
+			if fromScript {
+				w.WriteHeader(http.StatusForbidden)
+				fmt.Fprint(w, "The form has expired, reload the page and try again")
+				return
+			}
+
+			r.csrfFailed(w)
+			return
+		}
+
+		next.ServeHTTP(w, req)
+	})
+}
+
+// csrfFormValue reads CSRF token from submitted form. Files of multipart
+// forms are kept on disk, as handlers of uploads do
+func csrfFormValue(req *http.Request) string {
+	if strings.HasPrefix(req.Header.Get("Content-Type"), "multipart/form-data") {
+		if err := req.ParseMultipartForm(10); err != nil {
+			return ""
+		}
+	}
+	return req.PostFormValue(csrfField)
+}
+
 // secureHeaders middleware sets several headers to secure every response
 func (r *Routes) secureHeaders(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
@@ -54,54 +178,106 @@ func (r *Routes) recoverPanic(next http.Handler) http.Handler {
 	})
 }
 
-// limitRate middleware checks every request's rate, if it is more than limit that set it
-// would block user for given amount of time (in seconds)
-func (r *Routes) limitRate(next http.Handler) http.Handler {
-	rl := rate.NewRateLimiter(time.Duration(r.cfg.RateInterval)*time.Second, r.cfg.RateLimit)
+// Limits of state-changing requests. Login and signup forms can be used to
+// guess passwords, so they are limited more strictly
+var (
+	authLimit  = rate.Limit{Rate: 10, Period: time.Minute, Burst: 5}
+	writeLimit = rate.Limit{Rate: 30, Period: time.Minute, Burst: 10}
+)
+
+// authPaths are forms limited with authLimit
+var authPaths = map[string]bool{
+	"/user/login":     true,
+	"/user/login/2fa": true,
+	"/user/signup":    true,
+	"/user/forgot":    true,
+	"/user/reset":     true,
+	"/user/external":  true,
+}
+
+// limiters keep token buckets of every class of requests
+type limiters struct {
+	read  *rate.Limiter // every request, per client
+	auth  *rate.Limiter // login and signup forms, per client
+	write *rate.Limiter // other state-changing requests, per user
+}
 
+func newLimiters(read rate.Limit) limiters {
+	return limiters{
+		read:  rate.NewLimiter(read),
+		auth:  rate.NewLimiter(authLimit),
+		write: rate.NewLimiter(writeLimit),
+	}
+}
+
+// limitRate middleware limits requests of every client, static files
+// aren't limited
+func (r *Routes) limitRate(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
 		if strings.HasPrefix(req.URL.Path, "/static/") {
 			next.ServeHTTP(w, req)
 			return
 		}
 
-		ip := req.RemoteAddr
+		if !r.allow(w, req, r.limiters.read, realip.FromRequest(req)) {
+			return
+		}
 
-		r.rateMu.Lock()
-		user, ok := r.userRateLimits[ip]
-		r.rateMu.Unlock()
+		next.ServeHTTP(w, req)
+	})
+}
 
-		if ok {
-			r.rateMu.Lock()
+// limitActions middleware limits state-changing requests. Login and signup
+// forms are limited per client, other requests are limited per user, so
+// users behind one address don't throttle each other
+func (r *Routes) limitActions(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+			next.ServeHTTP(w, req)
+			return
+		}
 
-			if time.Since(user.lastReq) < user.penalty {
-				r.rateMu.Unlock()
-				r.rateLimitExceeded(w, user.penalty-time.Since(user.lastReq))
-				return
-			}
-			r.rateMu.Unlock()
+		limiter, key := r.limiters.write, realip.FromRequest(req)
+		if authPaths[req.URL.Path] {
+			limiter = r.limiters.auth
+		} else if userID := r.sesm.GetUserID(req.Context()); userID != 0 {
+			key = "user:" + strconv.Itoa(userID)
 		}
 
-		if rl.Limit() {
-			r.updateRateLimit(ip, time.Now(), time.Duration(r.cfg.RatePenalty)*time.Second)
-			r.rateLimitExceeded(w, time.Duration(r.cfg.RatePenalty)*time.Second)
+		if !r.allow(w, req, limiter, key) {
 			return
 		}
 
-		r.updateRateLimit(ip, time.Now(), 0)
-
 		next.ServeHTTP(w, req)
 	})
 }
 
-func (r *Routes) updateRateLimit(ip string, lastReq time.Time, penalty time.Duration) {
-	r.rateMu.Lock()
-	defer r.rateMu.Unlock()
+// allow takes token of the key from limiter and reports whether request is
+// allowed. RateLimit headers are sent with every response, error is sent if
+// request isn't allowed
+func (r *Routes) allow(w http.ResponseWriter, req *http.Request, limiter *rate.Limiter, key string) bool {
+	res := limiter.Allow(key)
+
+	h := w.Header()
+	h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+	h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	h.Set("RateLimit-Reset", strconv.Itoa(seconds(res.Reset)))
 
-	r.userRateLimits[ip] = userRateLimit{
-		lastReq: lastReq,
-		penalty: penalty,
+	if res.Allowed {
+		return true
 	}
+
+	r.logger.Printf("allow: rate limit exceeded by %s", key)
+	h.Set("Retry-After", strconv.Itoa(seconds(res.RetryAfter)))
+	r.rateLimitExceeded(w, req, res.RetryAfter)
+
+	return false
+}
+
+// seconds rounds duration up to whole seconds, as rate limit headers need
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
 }
 
 func (r *Routes) detectGuest(next http.Handler) http.Handler {
@@ -115,6 +291,58 @@ func (r *Routes) detectGuest(next http.Handler) http.Handler {
 	})
 }
 
+// checkBan middleware logs banned user out on all devices. Sessions are
+// deleted when user is banned, but it also ends sessions that were started
+// before the ban was saved
+func (r *Routes) checkBan(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		userID := r.sesm.GetUserID(req.Context())
+		if userID == 0 {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		restrictions, err := r.services.Sanction.GetRestrictions(userID)
+		if err != nil {
+			r.serverError(w, req, err)
+			return
+		}
+
+		if restrictions.Banned() {
+			r.logger.Print("checkBan: user is banned")
+
+			if err := r.sesm.DeleteAllTokens(req.Context(), userID); err != nil {
+				r.serverError(w, req, err)
+				return
+			}
+			r.sanctioned(w, req, restrictions.Ban)
+			return
+		}
+
+		next.ServeHTTP(w, req)
+	})
+}
+
+// requireUnmuted middleware doesn't let muted users post, comment and
+// react. It's used after requireAuthentication
+func (r *Routes) requireUnmuted(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		restrictions, err := r.services.Sanction.GetRestrictions(r.sesm.GetUserID(req.Context()))
+		if err != nil {
+			r.serverError(w, req, err)
+			return
+		}
+
+		if restrictions.Muted() {
+			r.logger.Print("requireUnmuted: user is muted")
+			r.sanctioned(w, req, restrictions.Mute)
+			return
+		}
+
+		next.ServeHTTP(w, req)
+	})
+}
+
 func (r *Routes) requireAdminRights(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
 		userRole := r.sesm.GetUserRole(req.Context())
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"forum/internal/assert"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestVerifyCSRF(t *testing.T) {
+	r := newTestRoutes(t)
+	ts := newTestServer(t, r.Register())
+	defer ts.Close()
+
+	sessionToken := ts.csrfToken(t)
+
+	// Client of test server keeps cookies, so requests are sent by another
+	// one with only cookies of the case
+	client := &http.Client{CheckRedirect: ts.Client().CheckRedirect}
+
+	// Guest without session gets token in cookie instead
+	res, err := client.Get(ts.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	var guestCookie *http.Cookie
+	for _, c := range res.Cookies() {
+		switch c.Name {
+		case r.sesm.CSRFCookieName:
+			guestCookie = c
+		case sessionNameInCookie:
+			t.Fatal("session is created for guest")
+		}
+	}
+	if guestCookie == nil {
+		t.Fatal("no csrf cookie for guest")
+	}
+
+	tests := []struct {
+		name     string
+		url      string
+		session  bool // request is sent with session cookie
+		cookie   *http.Cookie
+		header   string
+		field    string
+		wantCode int
+	}{
+		{
+			name:     "Session token in header",
+			url:      "/user/verify/resend",
+			session:  true,
+			header:   sessionToken,
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "Session token in form",
+			url:      "/user/verify/resend",
+			session:  true,
+			field:    sessionToken,
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "No token",
+			url:      "/user/verify/resend",
+			session:  true,
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "Wrong token",
+			url:      "/user/verify/resend",
+			session:  true,
+			header:   "wrong",
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "Guest token with session",
+			url:      "/user/verify/resend",
+			session:  true,
+			cookie:   guestCookie,
+			field:    guestCookie.Value,
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "Guest token",
+			url:      "/user/login",
+			cookie:   guestCookie,
+			field:    guestCookie.Value,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Guest token without cookie",
+			url:      "/user/login",
+			field:    guestCookie.Value,
+			wantCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			if tt.field != "" {
+				form.Add(csrfField, tt.field)
+			}
+
+			req, err := http.NewRequest("POST", ts.URL+tt.url, strings.NewReader(form.Encode()))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if tt.header != "" {
+				req.Header.Set(csrfHeader, tt.header)
+			}
+			if tt.session {
+				req.AddCookie(&http.Cookie{Name: sessionNameInCookie, Value: sessionCookieValue})
+			}
+			if tt.cookie != nil {
+				req.AddCookie(&http.Cookie{Name: tt.cookie.Name, Value: tt.cookie.Value})
+			}
+
+			res, err := client.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res.Body.Close()
+
+			assert.Equal(t, res.StatusCode, tt.wantCode)
+		})
+	}
+}
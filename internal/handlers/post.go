@@ -39,10 +39,34 @@ func (r *Routes) postView(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	comments, err := r.services.Comment.GetAllCommentsForPost(postID)
-	if err != nil {
-		r.serverError(w, req, err)
-		return
+	var comments *[]entity.CommentView
+
+	// Deep threads are shown separately, starting from given comment
+	if threadStr := req.URL.Query().Get("thread"); threadStr != "" {
+		threadID, ok := getValidID(threadStr)
+		if !ok {
+			r.logger.Print("postView: invalid thread id")
+			r.badRequest(w)
+			return
+		}
+
+		comments, err = r.services.Comment.GetCommentThread(postID, threadID)
+		if err != nil {
+			if errors.Is(err, entity.ErrCommentNotFound) {
+				r.logger.Print("postView: no comment for thread")
+				r.notFound(w)
+				return
+			}
+			r.serverError(w, req, err)
+			return
+		}
+		data.Models.Thread = threadID
+	} else {
+		comments, err = r.services.Comment.GetAllCommentsForPost(postID)
+		if err != nil {
+			r.serverError(w, req, err)
+			return
+		}
 	}
 
 	data.Models.Post = post
@@ -157,13 +181,19 @@ func (r *Routes) postsPersonal(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	userPosts, err := r.services.Post.GetAllPostsByUserId(userID)
+	userPosts, page, err := r.services.Post.GetAllPostsByUserId(userID, r.getPageParams(req))
 	if err != nil {
+		if errors.Is(err, entity.ErrInvalidCursor) || errors.Is(err, entity.ErrInvalidSort) {
+			r.logger.Print("postsPersonal: invalid page parameters")
+			r.badRequest(w)
+			return
+		}
 		r.serverError(w, req, err)
 		return
 	}
 
 	data.Models.Posts = *userPosts
+	data.Models.Page = page
 
 	r.render(w, req, http.StatusOK, "home.html", data)
 }
@@ -180,13 +210,19 @@ func (r *Routes) postsReacted(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	reactedPosts, err := r.services.Post.GetAllPostsByUserReaction(userID)
+	reactedPosts, page, err := r.services.Post.GetAllPostsByUserReaction(userID, r.getPageParams(req))
 	if err != nil {
+		if errors.Is(err, entity.ErrInvalidCursor) || errors.Is(err, entity.ErrInvalidSort) {
+			r.logger.Print("postsReacted: invalid page parameters")
+			r.badRequest(w)
+			return
+		}
 		r.serverError(w, req, err)
 		return
 	}
 
 	data.Models.Posts = *reactedPosts
+	data.Models.Page = page
 
 	r.render(w, req, http.StatusOK, "home.html", data)
 }
@@ -203,12 +239,19 @@ func (r *Routes) postsCommented(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	posts, comments, err := r.services.Post.GetAllCommentedPostsWithComments(userID)
+	posts, comments, page, err := r.services.Post.GetAllCommentedPostsWithComments(userID, r.getPageParams(req))
 	if err != nil {
+		if errors.Is(err, entity.ErrInvalidCursor) || errors.Is(err, entity.ErrInvalidSort) {
+			r.logger.Print("postsCommented: invalid page parameters")
+			r.badRequest(w)
+			return
+		}
 		r.serverError(w, req, err)
+		return
 	}
 
 	data.Models.Posts = *posts
+	data.Models.Page = page
 	for i := range data.Models.Posts {
 		data.Models.Posts[i].Comments = (*comments)[i]
 	}
@@ -253,6 +296,7 @@ func (r *Routes) postDelete(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	r.flash(req, flashSuccess, "Post is deleted.")
 	http.Redirect(w, req, "/", http.StatusSeeOther)
 }
 
@@ -286,7 +330,9 @@ func (r *Routes) postDeletePrivileged(w http.ResponseWriter, req *http.Request)
 	userRole := r.sesm.GetUserRole(req.Context())
 	userID := r.sesm.GetUserID(req.Context())
 
-	err := r.services.Post.DeletePostPrivileged(postID, userID, userRole)
+	reason := strings.TrimSpace(req.PostForm.Get("reason"))
+
+	err := r.services.Post.DeletePostPrivileged(postID, userID, userRole, reason)
 	if err != nil {
 		if errors.Is(err, entity.ErrPostNotFound) {
 			r.notFound(w)
@@ -296,6 +342,7 @@ func (r *Routes) postDeletePrivileged(w http.ResponseWriter, req *http.Request)
 		return
 	}
 
+	r.flash(req, flashSuccess, "Post is deleted.")
 	http.Redirect(w, req, "/", http.StatusSeeOther)
 }
 
@@ -334,14 +381,15 @@ func (r *Routes) postReport(w http.ResponseWriter, req *http.Request) {
 	if err != nil {
 		if errors.Is(err, entity.ErrDuplicateReport) {
 			r.logger.Print("postReport: report is already sent")
-			w.WriteHeader(http.StatusBadRequest)
-			fmt.Fprint(w, "Report is already sent")
+			r.flash(req, flashWarning, "Report is already sent.")
+			http.Redirect(w, req, fmt.Sprintf("/post/view/%d", postID), http.StatusSeeOther)
 			return
 		}
 		r.serverError(w, req, err)
 		return
 	}
 
+	r.flash(req, flashSuccess, "Report is sent to admins.")
 	http.Redirect(w, req, fmt.Sprintf("/post/view/%d", postID), http.StatusSeeOther)
 }
 
@@ -415,6 +463,7 @@ func (r *Routes) postEditPost(w http.ResponseWriter, req *http.Request) {
 		ID:         postID,
 		Title:      title,
 		Content:    content,
+		UserID:     r.sesm.GetUserID(req.Context()),
 		Tags:       tags,
 		File:       file,
 		FileHeader: fileHeader,
@@ -458,3 +507,57 @@ func (r *Routes) postEditPost(w http.ResponseWriter, req *http.Request) {
 	w.Header().Set("Content-Type", "text/plain")
 	fmt.Fprint(w, redirectURL)
 }
+
+// postRevisions shows edit history of post with word-level diff between two
+// revisions given by 'from' and 'to' numbers
+func (r *Routes) postRevisions(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		r.methodNotAllowed(w)
+		return
+	}
+
+	postID, ok := getIdFromPath(req, 4)
+	if !ok {
+		r.logger.Print("postRevisions: invalid url path")
+		r.notFound(w)
+		return
+	}
+
+	var revs [2]int
+	for i, key := range []string{"from", "to"} {
+		if s := req.URL.Query().Get(key); s != "" {
+			if revs[i], ok = getValidID(s); !ok {
+				r.logger.Print("postRevisions: invalid revision number")
+				r.badRequest(w)
+				return
+			}
+		}
+	}
+
+	data, err := r.newTemplateData(req)
+	if err != nil {
+		r.serverError(w, req, err)
+		return
+	}
+
+	userID := r.sesm.GetUserID(req.Context())
+	userRole := r.sesm.GetUserRole(req.Context())
+
+	history, err := r.services.Post.GetPostHistory(postID, userID, userRole, revs[0], revs[1])
+	if err != nil {
+		switch {
+		case errors.Is(err, entity.ErrPostNotFound), errors.Is(err, entity.ErrRevisionNotFound):
+			r.notFound(w)
+		case errors.Is(err, entity.ErrForbiddenAccess):
+			r.forbidden(w)
+		default:
+			r.serverError(w, req, err)
+		}
+		return
+	}
+
+	data.Models.Post = history.Post
+	data.Models.History = history
+
+	r.render(w, req, http.StatusOK, "revisions.html", data)
+}
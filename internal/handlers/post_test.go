@@ -144,7 +144,6 @@ func TestPostCreatePost(t *testing.T) {
 			content:  validContent,
 			tags:     []string{"-1"},
 			wantCode: http.StatusBadRequest,
-			wantBody: "invalid post tags",
 		},
 	}
 
@@ -155,7 +154,7 @@ func TestPostCreatePost(t *testing.T) {
 			form.Add("content", tt.content)
 			form["tags"] = tt.tags
 
-			code, _, body := ts.postForm(t, "/post/create", form)
+			code, _, body := ts.postMultipart(t, "/post/create", form)
 			assert.Equal(t, code, tt.wantCode)
 
 			if tt.wantBody != "" {
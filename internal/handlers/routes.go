@@ -4,27 +4,25 @@ import (
 	"forum/config"
 	"forum/internal/service"
 	"forum/pkg/mids"
+	"forum/pkg/oauth"
+	"forum/pkg/rate"
+	"forum/pkg/realip"
 	"forum/pkg/sesm"
 	"html/template"
 	"log"
 	"net/http"
-	"sync"
 	"time"
 )
 
-type userRateLimit struct {
-	lastReq time.Time
-	penalty time.Duration
-}
-
 type Routes struct {
-	services       *service.Services
-	tempCache      map[string]*template.Template
-	sesm           *sesm.SessionManager
-	logger         *log.Logger
-	cfg            *config.Config
-	userRateLimits map[string]userRateLimit
-	rateMu         *sync.Mutex
+	services  *service.Services
+	tempCache map[string]*template.Template
+	sesm      *sesm.SessionManager
+	logger    *log.Logger
+	cfg       *config.Config
+	providers *oauth.Registry
+	limiters  limiters
+	realIP    *realip.Resolver
 }
 
 func NewRouter(
@@ -42,13 +40,18 @@ func NewRouter(
 	}
 
 	return &Routes{
-		services:       services,
-		tempCache:      tempCache,
-		sesm:           sesm,
-		logger:         logger,
-		cfg:            cfg,
-		userRateLimits: make(map[string]userRateLimit),
-		rateMu:         &sync.Mutex{},
+		services:  services,
+		tempCache: tempCache,
+		sesm:      sesm,
+		logger:    logger,
+		cfg:       cfg,
+		providers: oauth.NewRegistry(cfg.ExternalAuth.Providers, nil),
+		limiters: newLimiters(rate.Limit{
+			Rate:   cfg.RateLimit,
+			Period: time.Duration(cfg.RateInterval) * time.Second,
+			Burst:  cfg.RateBurst,
+		}),
+		realIP: realip.New(cfg.TrustedProxies),
 	}
 }
 
@@ -60,48 +63,82 @@ func (r *Routes) Register() http.Handler {
 	fileServer := http.FileServer(http.Dir("./web/static/"))
 	router.Handle("/static/", r.preventDirListing(http.StripPrefix("/static", fileServer)))
 
-	// Dynamic middleware chain for routes that don't require authentication
-	dynamic := mids.New(r.sesm.LoadAndSave, r.detectGuest)
+	// Dynamic middleware chain for routes that don't require authentication.
+	// Banned users are logged out. Every state-changing request is rate
+	// limited and has to carry CSRF token of the session
+	dynamic := mids.New(r.sesm.LoadAndSave, r.detectGuest, r.checkBan, r.limitActions, r.verifyCSRF)
 
 	// GUEST MODE
 	router.Handle("/", dynamic.ThenFunc(r.home))
 	router.Handle("/sortByTags/", dynamic.ThenFunc(r.sortedByTag))
+	router.Handle("/search", dynamic.ThenFunc(r.search))
 	router.Handle("/user/login", dynamic.ThenFunc(r.userLoginPost))
+	router.Handle("/user/login/2fa", dynamic.ThenFunc(r.userLoginTwoFactor))
 	router.Handle("/user/signup", dynamic.ThenFunc(r.userSignupPost))
-	router.Handle("/post/view/", dynamic.ThenFunc(r.postView)) // postID at the end
+	router.Handle("/user/forgot", dynamic.ThenFunc(r.userForgot))
+	router.Handle("/user/reset", dynamic.ThenFunc(r.userReset))   // token in query
+	router.Handle("/user/verify", dynamic.ThenFunc(r.userVerify)) // token in query
+	router.Handle("/post/view/", dynamic.ThenFunc(r.postView))    // postID at the end
 
 	// EXTERNAL AUTH
-	router.Handle("/login/google", dynamic.ThenFunc(r.googlelogin))
-	router.Handle("/callbackGoogle", dynamic.ThenFunc(r.googleCallback))
-	router.Handle("/login/github", dynamic.ThenFunc(r.githublogin))
-	router.Handle("/callbackGithub", dynamic.ThenFunc(r.githubCallback))
-
-	// Protected appends dynamic middleware chain and used for routes
-	// that require authentication
-	protected := dynamic.Append(r.requireAuthentication)
+	router.Handle("/login/", dynamic.ThenFunc(r.oauthLogin))       // provider name at the end
+	router.Handle("/callback/", dynamic.ThenFunc(r.oauthCallback)) // provider name at the end
+	router.Handle("/callbackGoogle", dynamic.ThenFunc(r.oauthCallback))
+	router.Handle("/callbackGithub", dynamic.ThenFunc(r.oauthCallback))
+	router.Handle("/user/external", dynamic.ThenFunc(r.userExternal))
+
+	// Authenticated appends dynamic middleware chain and used for routes
+	// that require authentication, but must work before two-factor
+	// authentication is set up
+	authenticated := dynamic.Append(r.requireAuthentication)
+
+	// Protected appends authenticated middleware chain and used for routes
+	// that require authentication. Staff has to set up two-factor
+	// authentication first if admin made it mandatory
+	protected := authenticated.Append(r.requireTwoFactor)
+
+	// Unmuted appends protected middleware chain and used for reactions that
+	// muted users can't leave
+	unmuted := protected.Append(r.requireUnmuted)
+
+	// Verified appends unmuted middleware chain and used for routes that
+	// create content, so they require confirmed email address
+	verified := unmuted.Append(r.requireVerifiedEmail)
 
 	// POST
 	router.Handle("/post/myPosts", protected.ThenFunc(r.postsPersonal))
 	router.Handle("/post/myReacted", protected.ThenFunc(r.postsReacted))
 	router.Handle("/post/myCommented", protected.ThenFunc(r.postsCommented))
-	router.Handle("/post/create", protected.ThenFunc(r.postCreate))
-	router.Handle("/post/edit/", protected.ThenFunc(r.postEdit))         // postID at the end
-	router.Handle("/post/delete/", protected.ThenFunc(r.postDelete))     // postID at the end
-	router.Handle("/post/report/", protected.ThenFunc(r.postReport))     // postID at the end
-	router.Handle("/post/reaction/", protected.ThenFunc(r.postReaction)) // postID at the end
+	router.Handle("/post/create", verified.ThenFunc(r.postCreate))
+	router.Handle("/post/edit/", verified.ThenFunc(r.postEdit))            // postID at the end
+	router.Handle("/post/delete/", protected.ThenFunc(r.postDelete))       // postID at the end
+	router.Handle("/post/report/", protected.ThenFunc(r.postReport))       // postID at the end
+	router.Handle("/post/reaction/", unmuted.ThenFunc(r.postReaction))     // postID at the end
+	router.Handle("/post/revisions/", protected.ThenFunc(r.postRevisions)) // postID at the end
 
 	// COMMENT
-	router.Handle("/post/comment/", protected.ThenFunc(r.commentCreate))            // postID at the end
-	router.Handle("/post/comment/edit/", protected.ThenFunc(r.commentEdit))         // postID at the end
-	router.Handle("/post/comment/reaction/", protected.ThenFunc(r.commentReaction)) // postID at the end
-	router.Handle("/post/comment/delete/", protected.ThenFunc(r.commentDelete))     // commentID at the end
-	router.Handle("/post/comment/report/", protected.ThenFunc(r.commentReport))     // commentID at the end
+	router.Handle("/post/comment/", verified.ThenFunc(r.commentCreate))               // postID at the end
+	router.Handle("/post/comment/edit/", verified.ThenFunc(r.commentEdit))            // postID at the end
+	router.Handle("/post/comment/reaction/", unmuted.ThenFunc(r.commentReaction))     // postID at the end
+	router.Handle("/post/comment/delete/", protected.ThenFunc(r.commentDelete))       // commentID at the end
+	router.Handle("/post/comment/report/", protected.ThenFunc(r.commentReport))       // commentID at the end
+	router.Handle("/post/comment/revisions/", protected.ThenFunc(r.commentRevisions)) // commentID at the end
 
 	// USER
 	router.Handle("/user/promote", protected.ThenFunc(r.userPromote))
 	router.Handle("/user/notifications", protected.ThenFunc(r.notifications))
 	router.Handle("/user/deleteNotification/", protected.ThenFunc(r.deleteNotification)) // notificationID at the end
-	router.Handle("/user/logout", protected.ThenFunc(r.userLogout))
+	router.Handle("/user/logout", authenticated.ThenFunc(r.userLogout))
+	router.Handle("/user/verify/resend", protected.ThenFunc(r.userVerifyResend))
+	router.Handle("/user/settings", protected.ThenFunc(r.userSettings))
+	router.Handle("/user/settings/email", protected.ThenFunc(r.userSettingsEmail))
+	router.Handle("/user/settings/disconnect", protected.ThenFunc(r.userSettingsDisconnect))
+	router.Handle("/user/settings/2fa", authenticated.ThenFunc(r.userTwoFactor))
+	router.Handle("/user/settings/2fa/disable", protected.ThenFunc(r.userTwoFactorDisable))
+	router.Handle("/user/settings/2fa/recovery", protected.ThenFunc(r.userTwoFactorRecovery))
+	router.Handle("/user/sessions", protected.ThenFunc(r.userSessions))
+	router.Handle("/user/sessions/revoke", protected.ThenFunc(r.userSessionsRevoke))
+	router.Handle("/user/sessions/revokeOthers", protected.ThenFunc(r.userSessionsRevokeOthers))
 
 	// ADMIN
 	requireAdmin := protected.Append(r.requireAdminRights)
@@ -113,12 +150,18 @@ func (r *Routes) Register() http.Handler {
 	router.Handle("/admin/rejectPromotion/", requireAdmin.ThenFunc(r.rejectPromotion)) // userID at the end
 	router.Handle("/admin/rejectReport/", requireAdmin.ThenFunc(r.rejectReport))       // userID at the end
 	router.Handle("/admin/users", requireAdmin.ThenFunc(r.users))
+	router.Handle("/admin/sanction/", requireAdmin.ThenFunc(r.sanctionIssue))         // userID at the end
+	router.Handle("/admin/sanction/revoke/", requireAdmin.ThenFunc(r.sanctionRevoke)) // sanctionID at the end
 	router.Handle("/admin/tags", requireAdmin.ThenFunc(r.tags))
 	router.Handle("/admin/tags/delete/", requireAdmin.ThenFunc(r.tagDelete)) // tagID at the end
 	router.Handle("/admin/tags/create", requireAdmin.ThenFunc(r.tagCreate))
+	router.Handle("/admin/settings", requireAdmin.ThenFunc(r.adminSettings))
+	router.Handle("/admin/lockouts", requireAdmin.ThenFunc(r.lockouts))
+	router.Handle("/admin/lockouts/clear/", requireAdmin.ThenFunc(r.lockoutClear)) // lockoutID at the end
+	router.Handle("/admin/moderation", requireAdmin.ThenFunc(r.moderationLog))
 
 	// Standard middleware chain applied to router itself -> used in all routes
-	standard := mids.New(r.recoverPanic, r.limitRate, r.secureHeaders)
+	standard := mids.New(r.recoverPanic, r.realIP.Middleware, r.limitRate, r.secureHeaders)
 
 	return standard.Then(router)
 }
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"errors"
+	"forum/internal/entity"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+func (r *Routes) search(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		r.methodNotAllowed(w)
+		return
+	}
+
+	data, err := r.newTemplateData(req)
+	if err != nil {
+		r.serverError(w, req, err)
+		return
+	}
+
+	params := req.URL.Query()
+
+	form := entity.SearchQuery{
+		Query:  params.Get("q"),
+		Author: params.Get("author"),
+		From:   params.Get("from"),
+		To:     params.Get("to"),
+		Page:   1,
+	}
+
+	if tag := params.Get("tag"); tag != "" {
+		tagID, ok := getValidID(tag)
+		if !ok {
+			r.logger.Print("search: invalid tag id")
+			r.badRequest(w)
+			return
+		}
+		form.TagID = tagID
+	}
+
+	if page := params.Get("page"); page != "" {
+		pageNum, ok := getValidID(page)
+		if !ok || pageNum == 0 {
+			r.logger.Print("search: invalid page number")
+			r.badRequest(w)
+			return
+		}
+		form.Page = pageNum
+	}
+
+	results, err := r.services.Search.Search(&form)
+	if err != nil {
+		if errors.Is(err, entity.ErrInvalidFormData) {
+			data.Models.Search = form
+			r.render(w, req, http.StatusBadRequest, "search.html", data)
+			return
+		}
+		r.serverError(w, req, err)
+		return
+	}
+
+	data.Models.Search = form
+	data.Models.SearchResults = *results
+
+	r.render(w, req, http.StatusOK, "search.html", data)
+}
+
+// searchURL returns url of search page for the same query and filters,
+// but with page number shifted by delta. Used in templates for pagination
+func searchURL(q entity.SearchQuery, delta int) string {
+	params := url.Values{}
+	params.Set("q", q.Query)
+
+	if q.TagID != 0 {
+		params.Set("tag", strconv.Itoa(q.TagID))
+	}
+	if q.Author != "" {
+		params.Set("author", q.Author)
+	}
+	if q.From != "" {
+		params.Set("from", q.From)
+	}
+	if q.To != "" {
+		params.Set("to", q.To)
+	}
+	if page := q.Page + delta; page > 1 {
+		params.Set("page", strconv.Itoa(page))
+	}
+
+	return "/search?" + params.Encode()
+}
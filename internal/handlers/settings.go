@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"forum/internal/entity"
+	"forum/pkg/sesm"
+	"net/http"
+)
+
+func (r *Routes) userSettings(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		r.methodNotAllowed(w)
+		return
+	}
+
+	r.renderSettings(w, req, http.StatusOK, "")
+}
+
+// userSettingsDisconnect disconnects external login provider from the user
+func (r *Routes) userSettingsDisconnect(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		r.methodNotAllowed(w)
+		return
+	}
+	if err := req.ParseForm(); err != nil {
+		r.logger.Print("userSettingsDisconnect: invalid form fill (parse error)")
+		r.badRequest(w)
+		return
+	}
+
+	userID := r.sesm.GetUserID(req.Context())
+	provider := req.PostForm.Get("provider")
+
+	err := r.services.Identity.Unlink(userID, provider)
+	switch {
+	case err == nil:
+		r.flash(req, flashSuccess, r.providerName(provider)+" is disconnected.")
+		http.Redirect(w, req, "/user/settings", http.StatusSeeOther)
+	case errors.Is(err, entity.ErrIdentityNotLinked):
+		http.Redirect(w, req, "/user/settings", http.StatusSeeOther)
+	case errors.Is(err, entity.ErrLastLoginMethod):
+		r.logger.Print("userSettingsDisconnect: last login method")
+		r.renderSettings(w, req, http.StatusConflict, fmt.Sprintf(
+			"%s is the only way to log in to your account. Set a password through password reset before disconnecting it.",
+			r.providerName(provider)))
+	default:
+		r.serverError(w, req, err)
+	}
+}
+
+// userSettingsEmail changes email address of the user. New address has to
+// be confirmed before the user can post again
+func (r *Routes) userSettingsEmail(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		r.methodNotAllowed(w)
+		return
+	}
+	if err := req.ParseForm(); err != nil {
+		r.logger.Print("userSettingsEmail: invalid form fill (parse error)")
+		r.badRequest(w)
+		return
+	}
+
+	form := entity.ChangeEmailForm{Email: req.PostForm.Get("email")}
+
+	err := r.services.Account.ChangeEmail(r.sesm.GetUserID(req.Context()), &form)
+	switch {
+	case err == nil:
+		http.Redirect(w, req, "/user/verify/resend", http.StatusSeeOther)
+	case errors.Is(err, entity.ErrInvalidFormData), errors.Is(err, entity.ErrDuplicateEmail):
+		r.logger.Print("userSettingsEmail: invalid form fill")
+		r.renderSettings(w, req, http.StatusBadRequest, form.FieldErrors["email"])
+	case errors.Is(err, entity.ErrTokenRecentlySent):
+		r.logger.Print("userSettingsEmail: link was sent recently")
+		r.renderSettings(w, req, http.StatusTooManyRequests, "A link was sent less than a minute ago, try again later.")
+	default:
+		r.serverError(w, req, err)
+	}
+}
+
+// oauthConnect links identity returned by provider to the logged in user
+func (r *Routes) oauthConnect(w http.ResponseWriter, req *http.Request, identity entity.ExternalIdentity) {
+	userID := r.sesm.GetUserID(req.Context())
+
+	err := r.services.Identity.Link(userID, identity)
+	switch {
+	case err == nil:
+		r.flash(req, flashSuccess, r.providerName(identity.Provider)+" is connected.")
+		http.Redirect(w, req, "/user/settings", http.StatusSeeOther)
+	case errors.Is(err, entity.ErrIdentityLinked):
+		r.logger.Print("oauthConnect: identity is linked to another user")
+		r.renderSettings(w, req, http.StatusConflict, fmt.Sprintf(
+			"This %s account is already connected to another user.", r.providerName(identity.Provider)))
+	case errors.Is(err, entity.ErrProviderLinked):
+		r.logger.Print("oauthConnect: provider is already connected")
+		r.renderSettings(w, req, http.StatusConflict, fmt.Sprintf(
+			"Another %s account is already connected. Disconnect it first.", r.providerName(identity.Provider)))
+	default:
+		r.serverError(w, req, err)
+	}
+}
+
+func (r *Routes) renderSettings(w http.ResponseWriter, req *http.Request, status int, notice string) {
+	userID := r.sesm.GetUserID(req.Context())
+
+	identities, err := r.services.Identity.GetAll(userID)
+	if err != nil {
+		r.serverError(w, req, err)
+		return
+	}
+
+	twoFactor, err := r.services.TwoFactor.Status(userID, r.sesm.GetUserRole(req.Context()))
+	if err != nil {
+		r.serverError(w, req, err)
+		return
+	}
+
+	data, err := r.newTemplateData(req)
+	if err != nil {
+		r.serverError(w, req, err)
+		return
+	}
+
+	user, err := r.services.User.GetUserByUsername(data.Username)
+	if err != nil {
+		r.serverError(w, req, err)
+		return
+	}
+
+	data.Models.Email = user.Email
+	data.Models.Identities = identities
+	data.Models.TwoFactor = twoFactor
+	data.Notice = notice
+
+	r.render(w, req, status, "settings.html", data)
+}
+
+// userSessions lists active sessions of the user on all devices
+func (r *Routes) userSessions(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		r.methodNotAllowed(w)
+		return
+	}
+
+	sessions, err := r.sesm.ListSessions(req.Context(), r.sesm.GetUserID(req.Context()))
+	if err != nil {
+		r.serverError(w, req, err)
+		return
+	}
+
+	data, err := r.newTemplateData(req)
+	if err != nil {
+		r.serverError(w, req, err)
+		return
+	}
+	data.Models.Sessions = sessions
+
+	r.render(w, req, http.StatusOK, "sessions.html", data)
+}
+
+// userSessionsRevoke logs out one session of the user. Logging out the
+// current session is the same as logout
+func (r *Routes) userSessionsRevoke(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		r.methodNotAllowed(w)
+		return
+	}
+	if err := req.ParseForm(); err != nil {
+		r.logger.Print("userSessionsRevoke: invalid form fill (parse error)")
+		r.badRequest(w)
+		return
+	}
+
+	userID := r.sesm.GetUserID(req.Context())
+
+	err := r.sesm.RevokeSession(req.Context(), userID, req.PostForm.Get("session"))
+	switch {
+	case err == nil:
+		r.flash(req, flashSuccess, "Device is logged out.")
+	case errors.Is(err, sesm.ErrNotFound):
+		// Already expired or logged out session
+	default:
+		r.serverError(w, req, err)
+		return
+	}
+
+	if r.sesm.Status(req.Context()) == sesm.Destroyed {
+		http.Redirect(w, req, "/", http.StatusSeeOther)
+		return
+	}
+	http.Redirect(w, req, "/user/sessions", http.StatusSeeOther)
+}
+
+// userSessionsRevokeOthers logs out all sessions of the user except the
+// current one
+func (r *Routes) userSessionsRevokeOthers(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		r.methodNotAllowed(w)
+		return
+	}
+
+	err := r.sesm.RevokeOtherSessions(req.Context(), r.sesm.GetUserID(req.Context()))
+	if err != nil {
+		r.serverError(w, req, err)
+		return
+	}
+
+	r.flash(req, flashSuccess, "All other devices are logged out.")
+	http.Redirect(w, req, "/user/sessions", http.StatusSeeOther)
+}
@@ -2,6 +2,8 @@ package handlers
 
 import (
 	"forum/internal/entity"
+	"forum/pkg/oauth"
+	"forum/pkg/sesm"
 	"forum/web"
 	"html/template"
 	"io/fs"
@@ -10,13 +12,32 @@ import (
 )
 
 type Models struct {
-	Posts         []entity.PostView
-	Post          entity.PostView
-	Tags          []entity.TagEntity
-	Notifications []entity.Notification
-	Requests      []entity.Request
-	Reports       []entity.Report
-	Users         []entity.UserEntity
+	Posts          []entity.PostView
+	Page           entity.Page
+	Post           entity.PostView
+	Thread         int // id of comment which thread is shown
+	Tags           []entity.TagEntity
+	Notifications  []entity.Notification
+	Requests       []entity.Request
+	Reports        []entity.Report
+	Users          []entity.UserEntity
+	Search         entity.SearchQuery
+	SearchResults  []entity.SearchResultView
+	History        entity.PostHistory
+	CommentHistory entity.CommentHistory
+	Email          string            // email address of the user on settings page
+	Identities     []entity.Identity // external login providers linked to the user
+	TwoFactor      entity.TwoFactorStatus
+	TwoFactorSetup entity.TwoFactorSetup
+	QRCode         template.HTML // provisioning URI of TwoFactorSetup as SVG image
+	RecoveryCodes  []string      // shown only once, right after they are generated
+	StaffTwoFactor bool          // 2FA is mandatory for moderators and admins
+	Sessions       []sesm.Session
+	Lockouts       []entity.Lockout
+	Sanctions      map[int][]entity.Sanction // current sanctions by user id
+	Moderation     []entity.ModerationEntry
+	ModerationView entity.ModerationFilter
+	ModerationNext int // Before of the next page of the log, 0 if it's the last one
 }
 
 type templateData struct {
@@ -24,7 +45,20 @@ type templateData struct {
 	Username           string
 	UserRole           string
 	IsAuthenticated    bool
+	IsVerified         bool // email address of authenticated user is confirmed
 	NotificationsCount int
+	Form               any    // submitted form with validation errors
+	Notice             string // result of submitted form shown on the page
+	Providers          []*oauth.Provider
+	CSRFToken          string // sent back by every form, see verifyCSRF
+	Flashes            []flashMessage
+}
+
+// commentNode is passed to recursive comment template, so nested comments
+// have access to the data of the whole page
+type commentNode struct {
+	Comment entity.CommentView
+	Root    templateData
 }
 
 type errData struct {
@@ -35,6 +69,17 @@ type errData struct {
 var fm = template.FuncMap{
 	"low": strings.ToLower,
 	"cap": strings.Title,
+
+	"searchURL": searchURL,
+	"pageURL":   pageURL,
+	"node": func(c entity.CommentView, root templateData) commentNode {
+		return commentNode{Comment: c, Root: root}
+	},
+	"identityOf":        identityOf,
+	"moderationActions": func() []string { return entity.ModerationActions },
+	"moderationTargets": func() []string { return entity.ModerationTargets },
+	"moderationURL":     moderationURL,
+	"device":            device,
 }
 
 // newTemplateCache initializes all templates and stores them in map
@@ -55,6 +100,8 @@ func newTemplateCache() (map[string]*template.Template, error) {
 			"html/partials/nav.html",
 			"html/partials/topics.html",
 			"html/partials/userbar.html",
+			"html/partials/pagination.html",
+			"html/partials/diff.html",
 			page,
 		}
 
@@ -2,17 +2,24 @@ package handlers
 
 import (
 	"bytes"
+	"forum/config"
 	"forum/internal/entity/mocks"
 	"forum/internal/entity/mocks/sqlite3store"
+	"forum/pkg/oauth"
+	"forum/pkg/rate"
+	"forum/pkg/realip"
 	"forum/pkg/sesm"
 	"io"
 	"log"
+	"mime/multipart"
 	"net/http"
 	"net/http/cookiejar"
 	"net/http/httptest"
 	"net/url"
+	"regexp"
 	"strings"
 	"testing"
+	"time"
 )
 
 const (
@@ -20,6 +27,12 @@ const (
 	sessionCookieValue  = "anythingHereWouldWork"
 )
 
+// testLimit is high enough for requests of any test, the limiter itself
+// is tested in pkg/rate
+var testLimit = rate.Limit{Rate: 1000, Period: time.Second}
+
+var csrfTokenRX = regexp.MustCompile(`<meta name="csrf-token" content="([^"]+)">`)
+
 func newTestRoutes(t *testing.T) *Routes {
 	repos := mocks.NewReposMock()
 	services := mocks.NewServicesMock(repos)
@@ -32,11 +45,26 @@ func newTestRoutes(t *testing.T) *Routes {
 	sesm := sesm.New()
 	sesm.Store = sqlite3store.New()
 
+	cfg := &config.Config{
+		Http: config.Http{
+			RateInterval: 1,
+			RateLimit:    1000,
+		},
+	}
+
 	return &Routes{
 		services:  services,
 		tempCache: tempCache,
 		sesm:      sesm,
 		logger:    log.New(io.Discard, "", 0),
+		cfg:       cfg,
+		providers: oauth.NewRegistry(nil, nil),
+		limiters: limiters{
+			read:  rate.NewLimiter(testLimit),
+			auth:  rate.NewLimiter(testLimit),
+			write: rate.NewLimiter(testLimit),
+		},
+		realIP: realip.New(nil),
 	}
 }
 
@@ -77,6 +105,38 @@ func (ts *testServer) get(t *testing.T, url string) (int, http.Header, string) {
 	return rs.StatusCode, rs.Header, string(body)
 }
 
+// csrfToken returns CSRF token of the test session taken from home page, the
+// same way scripts of the site take it
+func (ts *testServer) csrfToken(t *testing.T) string {
+	req, err := http.NewRequest("GET", ts.URL+"/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.AddCookie(&http.Cookie{
+		Name:  sessionNameInCookie,
+		Value: sessionCookieValue,
+	})
+
+	res, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := csrfTokenRX.FindSubmatch(body)
+	if m == nil {
+		t.Fatal("no csrf token on home page")
+	}
+
+	return string(m[1])
+}
+
 func (ts *testServer) postForm(t *testing.T, url string, form url.Values) (int, http.Header, string) {
 	req, err := http.NewRequest("POST", ts.URL+url, strings.NewReader(form.Encode()))
 	if err != nil {
@@ -84,6 +144,48 @@ func (ts *testServer) postForm(t *testing.T, url string, form url.Values) (int,
 	}
 
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set(csrfHeader, ts.csrfToken(t))
+
+	req.AddCookie(&http.Cookie{
+		Name:  sessionNameInCookie,
+		Value: sessionCookieValue,
+	})
+
+	res, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body = bytes.TrimSpace(body)
+
+	return res.StatusCode, res.Header, string(body)
+}
+
+func (ts *testServer) postMultipart(t *testing.T, url string, form url.Values) (int, http.Header, string) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	for key, values := range form {
+		for _, value := range values {
+			if err := mw.WriteField(key, value); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest("POST", ts.URL+url, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.Header.Set(csrfHeader, ts.csrfToken(t))
 
 	req.AddCookie(&http.Cookie{
 		Name:  sessionNameInCookie,
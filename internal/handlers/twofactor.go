@@ -0,0 +1,297 @@
+package handlers
+
+import (
+	"errors"
+	"forum/internal/entity"
+	"forum/pkg/qr"
+	"forum/pkg/realip"
+	"html/template"
+	"net/http"
+	"strings"
+)
+
+// maxPasscodeAttempts is number of wrong codes after which user has to
+// enter password again
+const maxPasscodeAttempts = 5
+
+// userLoginTwoFactor is the second login step of users with two-factor
+// authentication. User is logged in only after the right code is entered
+func (r *Routes) userLoginTwoFactor(w http.ResponseWriter, req *http.Request) {
+	userID := r.sesm.GetInt(req.Context(), pendingUserKey)
+	if userID == 0 || r.isAuthenticated(req) {
+		http.Redirect(w, req, "/", http.StatusSeeOther)
+		return
+	}
+
+	switch req.Method {
+	case http.MethodGet:
+		r.renderLoginTwoFactor(w, req, http.StatusOK, &entity.PasscodeForm{}, "")
+		return
+	case http.MethodPost:
+	default:
+		r.methodNotAllowed(w)
+		return
+	}
+
+	form, ok := r.parsePasscodeForm(w, req)
+	if !ok {
+		return
+	}
+
+	err := r.services.TwoFactor.Verify(userID, form.Passcode)
+	switch {
+	case err == nil:
+		r.startSession(w, req, userID)
+	case errors.Is(err, entity.ErrInvalidPasscode):
+		r.logger.Print("userLoginTwoFactor: invalid code")
+
+		// Wrong codes count towards lockout like wrong passwords
+		identifier := r.sesm.GetString(req.Context(), pendingIdentifierKey)
+		if err := r.services.Lockout.RecordFailure(identifier, realip.FromRequest(req)); err != nil {
+			r.serverError(w, req, err)
+			return
+		}
+
+		attempts := r.sesm.GetInt(req.Context(), pendingAttemptsKey) + 1
+		r.sesm.Put(req.Context(), pendingAttemptsKey, attempts)
+
+		if attempts >= maxPasscodeAttempts {
+			r.sesm.Remove(req.Context(), pendingUserKey)
+			r.sesm.Remove(req.Context(), pendingIdentifierKey)
+			r.sesm.Remove(req.Context(), pendingAttemptsKey)
+			r.renderLoginTwoFactor(w, req, http.StatusTooManyRequests, form, "Too many wrong codes. Log in again.")
+			return
+		}
+
+		form.AddFieldError("passcode", "Invalid or already used code")
+		r.renderLoginTwoFactor(w, req, http.StatusBadRequest, form, "")
+	case errors.Is(err, entity.ErrTwoFactorDisabled):
+		// Turned off by admin while user was entering the code
+		r.startSession(w, req, userID)
+	default:
+		r.serverError(w, req, err)
+	}
+}
+
+func (r *Routes) renderLoginTwoFactor(w http.ResponseWriter, req *http.Request, status int, form *entity.PasscodeForm, notice string) {
+	data, err := r.newTemplateData(req)
+	if err != nil {
+		r.serverError(w, req, err)
+		return
+	}
+	data.Form = form
+	data.Notice = notice
+
+	r.render(w, req, status, "login_2fa.html", data)
+}
+
+// userTwoFactor shows QR code of new secret and enables two-factor
+// authentication when user confirms it with code from authenticator app
+func (r *Routes) userTwoFactor(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+		r.renderTwoFactorSetup(w, req, http.StatusOK, &entity.PasscodeForm{})
+		return
+	case http.MethodPost:
+	default:
+		r.methodNotAllowed(w)
+		return
+	}
+
+	form, ok := r.parsePasscodeForm(w, req)
+	if !ok {
+		return
+	}
+
+	userID := r.sesm.GetUserID(req.Context())
+
+	codes, err := r.services.TwoFactor.Confirm(userID, form.Passcode)
+	switch {
+	case err == nil:
+		r.renderRecoveryCodes(w, req, codes)
+	case errors.Is(err, entity.ErrInvalidPasscode):
+		r.logger.Print("userTwoFactor: invalid code")
+		form.AddFieldError("passcode", "Invalid code, check time on your phone and try again")
+		r.renderTwoFactorSetup(w, req, http.StatusBadRequest, form)
+	case errors.Is(err, entity.ErrTwoFactorEnabled):
+		http.Redirect(w, req, "/user/settings", http.StatusSeeOther)
+	case errors.Is(err, entity.ErrTwoFactorDisabled):
+		// Enrolment wasn't started, so there is no secret to confirm
+		http.Redirect(w, req, "/user/settings/2fa", http.StatusSeeOther)
+	default:
+		r.serverError(w, req, err)
+	}
+}
+
+func (r *Routes) renderTwoFactorSetup(w http.ResponseWriter, req *http.Request, status int, form *entity.PasscodeForm) {
+	userID := r.sesm.GetUserID(req.Context())
+
+	username, err := r.getUsername(req.Context())
+	if err != nil {
+		r.serverError(w, req, err)
+		return
+	}
+
+	setup, err := r.services.TwoFactor.Begin(userID, username)
+	if err != nil {
+		if errors.Is(err, entity.ErrTwoFactorEnabled) {
+			http.Redirect(w, req, "/user/settings", http.StatusSeeOther)
+			return
+		}
+		r.serverError(w, req, err)
+		return
+	}
+
+	code, err := qr.Encode(setup.URI)
+	if err != nil {
+		r.serverError(w, req, err)
+		return
+	}
+
+	required, err := r.services.TwoFactor.IsRequired(r.sesm.GetUserRole(req.Context()))
+	if err != nil {
+		r.serverError(w, req, err)
+		return
+	}
+
+	data, err := r.newTemplateData(req)
+	if err != nil {
+		r.serverError(w, req, err)
+		return
+	}
+	data.Models.TwoFactorSetup = setup
+	data.Models.QRCode = template.HTML(code.SVG(4))
+	data.Form = form
+	if required {
+		data.Notice = "Two-factor authentication is required for your role. Set it up to continue."
+	}
+
+	r.render(w, req, status, "twofactor.html", data)
+}
+
+func (r *Routes) renderRecoveryCodes(w http.ResponseWriter, req *http.Request, codes []string) {
+	data, err := r.newTemplateData(req)
+	if err != nil {
+		r.serverError(w, req, err)
+		return
+	}
+	data.Models.RecoveryCodes = codes
+
+	r.render(w, req, http.StatusOK, "twofactor.html", data)
+}
+
+// userTwoFactorDisable turns off two-factor authentication after user
+// enters code
+func (r *Routes) userTwoFactorDisable(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		r.methodNotAllowed(w)
+		return
+	}
+
+	form, ok := r.parsePasscodeForm(w, req)
+	if !ok {
+		return
+	}
+
+	userID := r.sesm.GetUserID(req.Context())
+	role := r.sesm.GetUserRole(req.Context())
+
+	err := r.services.TwoFactor.Disable(userID, role, form.Passcode)
+	switch {
+	case err == nil:
+		r.flash(req, flashSuccess, "Two-factor authentication is turned off.")
+		http.Redirect(w, req, "/user/settings", http.StatusSeeOther)
+	case errors.Is(err, entity.ErrTwoFactorDisabled):
+		http.Redirect(w, req, "/user/settings", http.StatusSeeOther)
+	case errors.Is(err, entity.ErrTwoFactorRequired):
+		r.logger.Print("userTwoFactorDisable: 2FA is required for the role")
+		r.renderSettings(w, req, http.StatusConflict, "Two-factor authentication is required for your role and can't be turned off.")
+	case errors.Is(err, entity.ErrInvalidPasscode):
+		r.logger.Print("userTwoFactorDisable: invalid code")
+		r.renderSettings(w, req, http.StatusBadRequest, "Invalid or already used code.")
+	default:
+		r.serverError(w, req, err)
+	}
+}
+
+// userTwoFactorRecovery replaces recovery codes with new ones after user
+// enters code
+func (r *Routes) userTwoFactorRecovery(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		r.methodNotAllowed(w)
+		return
+	}
+
+	form, ok := r.parsePasscodeForm(w, req)
+	if !ok {
+		return
+	}
+
+	userID := r.sesm.GetUserID(req.Context())
+
+	codes, err := r.services.TwoFactor.RegenerateRecoveryCodes(userID, form.Passcode)
+	switch {
+	case err == nil:
+		r.renderRecoveryCodes(w, req, codes)
+	case errors.Is(err, entity.ErrTwoFactorDisabled):
+		http.Redirect(w, req, "/user/settings", http.StatusSeeOther)
+	case errors.Is(err, entity.ErrInvalidPasscode):
+		r.logger.Print("userTwoFactorRecovery: invalid code")
+		r.renderSettings(w, req, http.StatusBadRequest, "Invalid or already used code.")
+	default:
+		r.serverError(w, req, err)
+	}
+}
+
+// parsePasscodeForm reads code from submitted form. Bad request is sent if
+// form can't be parsed
+func (r *Routes) parsePasscodeForm(w http.ResponseWriter, req *http.Request) (*entity.PasscodeForm, bool) {
+	if err := req.ParseForm(); err != nil {
+		r.logger.Print("parsePasscodeForm: invalid form fill (parse error)")
+		r.badRequest(w)
+		return nil, false
+	}
+
+	return &entity.PasscodeForm{Passcode: strings.TrimSpace(req.PostForm.Get("passcode"))}, true
+}
+
+// adminSettings shows and changes site-wide settings
+func (r *Routes) adminSettings(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		if err := req.ParseForm(); err != nil {
+			r.logger.Print("adminSettings: invalid form fill (parse error)")
+			r.badRequest(w)
+			return
+		}
+
+		required := req.PostForm.Get("staffTwoFactor") == "on"
+		if err := r.services.TwoFactor.SetRequired(r.sesm.GetUserID(req.Context()), required); err != nil {
+			r.serverError(w, req, err)
+			return
+		}
+
+		r.flash(req, flashSuccess, "Settings are saved.")
+		http.Redirect(w, req, "/admin/settings", http.StatusSeeOther)
+		return
+	default:
+		r.methodNotAllowed(w)
+		return
+	}
+
+	required, err := r.services.TwoFactor.IsRequired(entity.ADMIN)
+	if err != nil {
+		r.serverError(w, req, err)
+		return
+	}
+
+	data, err := r.newTemplateData(req)
+	if err != nil {
+		r.serverError(w, req, err)
+		return
+	}
+	data.Models.StaffTwoFactor = required
+
+	r.render(w, req, http.StatusOK, "admin_settings.html", data)
+}
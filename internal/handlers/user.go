@@ -4,7 +4,10 @@ import (
 	"errors"
 	"fmt"
 	"forum/internal/entity"
+	"forum/pkg/realip"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
 )
 
@@ -26,7 +29,7 @@ func (r *Routes) userSignupPost(w http.ResponseWriter, req *http.Request) {
 
 	u := entity.UserSignupForm{Username: username, Email: email, Password: password}
 
-	_, err := r.services.User.SaveUser(&u) // Put user id in context
+	id, err := r.services.User.SaveUser(&u) // Put user id in context
 	if err != nil {
 		switch {
 		case errors.Is(err, entity.ErrInvalidFormData), errors.Is(err, entity.ErrDuplicateUsername), errors.Is(err, entity.ErrDuplicateEmail):
@@ -41,6 +44,12 @@ func (r *Routes) userSignupPost(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	// Account is already created, so user can request the link again if
+	// it wasn't sent
+	if err := r.services.Account.SendVerification(id); err != nil {
+		r.logger.Printf("userSignupPost: send verification: %v", err)
+	}
+
 	http.Redirect(w, req, "/", http.StatusSeeOther)
 }
 
@@ -59,6 +68,23 @@ func (r *Routes) userLoginPost(w http.ResponseWriter, req *http.Request) {
 	form := req.PostForm
 	identifier := strings.ToLower(form.Get("identifier"))
 	password := form.Get("password")
+	remember := form.Get("remember") == "on"
+	ip := realip.FromRequest(req)
+
+	// Password isn't checked at all while account or address is locked
+	wait, err := r.services.Lockout.Check(identifier, ip)
+	if err != nil {
+		if errors.Is(err, entity.ErrLoginLocked) {
+			r.logger.Printf("userLoginPost: login is locked for %s", ip)
+
+			w.Header().Set("Retry-After", strconv.Itoa(seconds(wait)))
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprintf(w, "Too many failed attempts to log in, try again in %d min", int(math.Ceil(wait.Minutes())))
+			return
+		}
+		r.serverError(w, req, err)
+		return
+	}
 
 	u := entity.UserLoginForm{Identifier: identifier, Password: password}
 	id, err := r.services.User.Authenticate(&u)
@@ -67,6 +93,13 @@ func (r *Routes) userLoginPost(w http.ResponseWriter, req *http.Request) {
 		case errors.Is(err, entity.ErrInvalidFormData), errors.Is(err, entity.ErrInvalidCredentials):
 			r.logger.Print("userSignupPost: invalid form fill")
 
+			if errors.Is(err, entity.ErrInvalidCredentials) {
+				if err := r.services.Lockout.RecordFailure(identifier, ip); err != nil {
+					r.serverError(w, req, err)
+					return
+				}
+			}
+
 			w.WriteHeader(http.StatusBadRequest)
 			msg := getErrorMessage(&u.Validator)
 			fmt.Fprint(w, strings.TrimSpace(msg))
@@ -76,23 +109,302 @@ func (r *Routes) userLoginPost(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	role, err := r.services.User.GetUserRole(id)
+	r.logIn(w, req, id, identifier, remember)
+}
+
+// logIn starts new session of the user and redirects to home page. User
+// with two-factor authentication is asked for code first, failed codes are
+// counted for the identifier the password was entered for (it's empty after
+// login through provider). Remembered session lives longer and stays after
+// browser is closed
+func (r *Routes) logIn(w http.ResponseWriter, req *http.Request, userID int, identifier string, remember bool) {
+	restrictions, err := r.services.Sanction.GetRestrictions(userID)
+	if err != nil {
+		r.serverError(w, req, err)
+		return
+	}
+	if restrictions.Banned() {
+		r.logger.Print("logIn: user is banned")
+		r.sanctioned(w, req, restrictions.Ban)
+		return
+	}
+
+	enabled, err := r.services.TwoFactor.IsEnabled(userID)
+	if err != nil {
+		r.serverError(w, req, err)
+		return
+	}
+
+	// Choice is kept in session until all login steps are passed
+	r.sesm.PutRemember(req.Context(), remember)
+
+	if enabled {
+		r.sesm.Put(req.Context(), pendingUserKey, userID)
+		r.sesm.Put(req.Context(), pendingIdentifierKey, identifier)
+		r.sesm.Remove(req.Context(), pendingAttemptsKey)
+		http.Redirect(w, req, "/user/login/2fa", http.StatusSeeOther)
+		return
+	}
+
+	r.startSession(w, req, userID)
+}
+
+// startSession logs in user that passed all login steps and forgets failed
+// logins of the account
+func (r *Routes) startSession(w http.ResponseWriter, req *http.Request, userID int) {
+	role, err := r.services.User.GetUserRole(userID)
 	if err != nil {
 		r.serverError(w, req, err)
 		return
 	}
 
-	err = r.sesm.RenewToken(req.Context(), id)
+	if err := r.services.Lockout.RecordSuccess(userID); err != nil {
+		r.serverError(w, req, err)
+		return
+	}
+
+	err = r.sesm.RenewToken(req.Context())
 	if err != nil {
 		r.serverError(w, req, err)
 		return
 	}
-	r.sesm.PutUserID(req.Context(), id)
+	r.sesm.Remove(req.Context(), pendingUserKey)
+	r.sesm.Remove(req.Context(), pendingIdentifierKey)
+	r.sesm.Remove(req.Context(), pendingAttemptsKey)
+	r.sesm.PutUserID(req.Context(), userID)
 	r.sesm.PutUserRole(req.Context(), role)
 
 	http.Redirect(w, req, "/", http.StatusSeeOther)
 }
 
+func (r *Routes) userForgot(w http.ResponseWriter, req *http.Request) {
+	switch {
+	case req.Method == http.MethodPost:
+		r.userForgotPost(w, req)
+		return
+	case req.Method != http.MethodGet:
+		r.methodNotAllowed(w)
+		return
+	}
+
+	data, err := r.newTemplateData(req)
+	if err != nil {
+		r.serverError(w, req, err)
+		return
+	}
+	data.Form = entity.ForgotPasswordForm{}
+
+	r.render(w, req, http.StatusOK, "forgot.html", data)
+}
+
+func (r *Routes) userForgotPost(w http.ResponseWriter, req *http.Request) {
+	if err := req.ParseForm(); err != nil {
+		r.logger.Print("userForgotPost: invalid form fill (parse error)")
+		r.badRequest(w)
+		return
+	}
+
+	f := entity.ForgotPasswordForm{Email: strings.ToLower(strings.TrimSpace(req.PostForm.Get("email")))}
+
+	data, err := r.newTemplateData(req)
+	if err != nil {
+		r.serverError(w, req, err)
+		return
+	}
+
+	err = r.services.Account.RequestPasswordReset(&f)
+	if err != nil {
+		if errors.Is(err, entity.ErrInvalidFormData) {
+			r.logger.Print("userForgotPost: invalid form fill")
+			data.Form = f
+			r.render(w, req, http.StatusBadRequest, "forgot.html", data)
+			return
+		}
+		r.serverError(w, req, err)
+		return
+	}
+
+	// The same answer is given for unknown emails
+	data.Notice = "If an account with that email exists, a link to reset the password has been sent to it"
+
+	r.render(w, req, http.StatusOK, "forgot.html", data)
+}
+
+func (r *Routes) userReset(w http.ResponseWriter, req *http.Request) {
+	switch {
+	case req.Method == http.MethodPost:
+		r.userResetPost(w, req)
+		return
+	case req.Method != http.MethodGet:
+		r.methodNotAllowed(w)
+		return
+	}
+
+	data, err := r.newTemplateData(req)
+	if err != nil {
+		r.serverError(w, req, err)
+		return
+	}
+
+	f := entity.ResetPasswordForm{Token: req.URL.Query().Get("token")}
+
+	err = r.services.Account.CheckResetToken(f.Token)
+	if err != nil {
+		if errors.Is(err, entity.ErrInvalidToken) {
+			r.logger.Print("userReset: invalid token")
+			data.Form = entity.ResetPasswordForm{}
+			r.render(w, req, http.StatusBadRequest, "reset.html", data)
+			return
+		}
+		r.serverError(w, req, err)
+		return
+	}
+	data.Form = f
+
+	r.render(w, req, http.StatusOK, "reset.html", data)
+}
+
+func (r *Routes) userResetPost(w http.ResponseWriter, req *http.Request) {
+	if err := req.ParseForm(); err != nil {
+		r.logger.Print("userResetPost: invalid form fill (parse error)")
+		r.badRequest(w)
+		return
+	}
+
+	form := req.PostForm
+	f := entity.ResetPasswordForm{
+		Token:           form.Get("token"),
+		Password:        form.Get("password"),
+		ConfirmPassword: form.Get("confirmPassword"),
+	}
+
+	userID, err := r.services.Account.ResetPassword(&f)
+	if err != nil {
+		switch {
+		case errors.Is(err, entity.ErrInvalidFormData):
+			r.logger.Print("userResetPost: invalid form fill")
+		case errors.Is(err, entity.ErrInvalidToken):
+			r.logger.Print("userResetPost: invalid token")
+			f.Token = ""
+		default:
+			r.serverError(w, req, err)
+			return
+		}
+
+		data, err := r.newTemplateData(req)
+		if err != nil {
+			r.serverError(w, req, err)
+			return
+		}
+		f.Password, f.ConfirmPassword = "", ""
+		data.Form = f
+
+		r.render(w, req, http.StatusBadRequest, "reset.html", data)
+		return
+	}
+
+	// Whoever knew the old password is logged out everywhere
+	err = r.sesm.DeleteAllTokens(req.Context(), userID)
+	if err != nil {
+		r.serverError(w, req, err)
+		return
+	}
+
+	data, err := r.newTemplateData(req)
+	if err != nil {
+		r.serverError(w, req, err)
+		return
+	}
+	data.Form = entity.ResetPasswordForm{}
+	data.Notice = "Your password has been changed. Sign in with the new password"
+
+	r.render(w, req, http.StatusOK, "reset.html", data)
+}
+
+func (r *Routes) userVerify(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		r.methodNotAllowed(w)
+		return
+	}
+
+	userID, err := r.services.Account.VerifyEmail(req.URL.Query().Get("token"))
+	if err != nil {
+		if errors.Is(err, entity.ErrInvalidToken) {
+			r.logger.Print("userVerify: invalid token")
+
+			data, err := r.newTemplateData(req)
+			if err != nil {
+				r.serverError(w, req, err)
+				return
+			}
+			data.Notice = "This link is invalid or has expired"
+
+			r.render(w, req, http.StatusBadRequest, "verify.html", data)
+			return
+		}
+		r.serverError(w, req, err)
+		return
+	}
+
+	data, err := r.newTemplateData(req)
+	if err != nil {
+		r.serverError(w, req, err)
+		return
+	}
+	data.Notice = "Your email address is confirmed"
+	if r.sesm.GetUserID(req.Context()) != userID {
+		data.Notice += ". Sign in to create posts and comments"
+	}
+
+	r.render(w, req, http.StatusOK, "verify.html", data)
+}
+
+func (r *Routes) userVerifyResend(w http.ResponseWriter, req *http.Request) {
+	switch {
+	case req.Method == http.MethodPost:
+		r.userVerifyResendPost(w, req)
+		return
+	case req.Method != http.MethodGet:
+		r.methodNotAllowed(w)
+		return
+	}
+
+	data, err := r.newTemplateData(req)
+	if err != nil {
+		r.serverError(w, req, err)
+		return
+	}
+
+	r.render(w, req, http.StatusOK, "verify.html", data)
+}
+
+func (r *Routes) userVerifyResendPost(w http.ResponseWriter, req *http.Request) {
+	userID, data, err := r.getBaseInfo(req)
+	if err != nil {
+		r.serverError(w, req, err)
+		return
+	}
+
+	err = r.services.Account.SendVerification(userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, entity.ErrAlreadyVerified):
+			http.Redirect(w, req, "/", http.StatusSeeOther)
+		case errors.Is(err, entity.ErrTokenRecentlySent):
+			r.logger.Print("userVerifyResend: link was sent recently")
+			data.Notice = "The link was sent less than a minute ago, check your inbox or try again later"
+			r.render(w, req, http.StatusTooManyRequests, "verify.html", data)
+		default:
+			r.serverError(w, req, err)
+		}
+		return
+	}
+
+	data.Notice = "A new link has been sent to your email address"
+
+	r.render(w, req, http.StatusOK, "verify.html", data)
+}
+
 func (r *Routes) userLogout(w http.ResponseWriter, req *http.Request) {
 	if req.Method != http.MethodPost {
 		r.methodNotAllowed(w)
@@ -162,7 +474,7 @@ func (r *Routes) deleteNotification(w http.ResponseWriter, req *http.Request) {
 }
 
 func (r *Routes) userPromote(w http.ResponseWriter, req *http.Request) {
-	if req.Method != http.MethodGet {
+	if req.Method != http.MethodPost {
 		r.methodNotAllowed(w)
 		return
 	}
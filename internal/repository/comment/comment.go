@@ -4,19 +4,21 @@ import (
 	"database/sql"
 	"errors"
 	"forum/internal/entity"
+	"forum/internal/repository/moderation"
 )
 
 type ICommentRepository interface {
-	Insert(entity.CommentCreateForm, int, int) error
+	Insert(entity.CommentCreateForm, int, int) (int, error)
 	GetAllForPost(int) (*[]entity.CommentEntity, error)
 	GetAllUserCommentsForPost(userID, postID int) (*[]entity.CommentEntity, error)
 	Exists(int) (bool, error)
 	Delete(commentID, userID int) error
-	DeleteByPrivileged(commentID int) error
+	DeleteByPrivileged(commentID int, e entity.ModerationEntry) error
 	GetAuthorID(commentID int) (int, error)
 	GetByID(commentID int) (entity.CommentEntity, error)
 	Update(commentID int, content string) error
 	GetPostID(commentID int) (int, error)
+	GetRevisions(commentID int) (*[]entity.CommentRevision, error)
 }
 
 type commentRepository struct {
@@ -31,20 +33,45 @@ func NewCommentRepo(db *sql.DB) *commentRepository {
 	}
 }
 
-func (r *commentRepository) Insert(c entity.CommentCreateForm, postID int, userID int) error {
+func (r *commentRepository) Insert(c entity.CommentCreateForm, postID int, userID int) (int, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
 	query := `
-		INSERT INTO comments (content, post_id, user_id, created_at) 
-		VALUES ($1, $2, $3, datetime('now', 'localtime'))
+		INSERT INTO comments (content, post_id, user_id, parent_id, created_at) 
+		VALUES ($1, $2, $3, $4, datetime('now', 'localtime'))
+		RETURNING id
 		`
 
-	_, err := r.DB.Exec(query, c.Content, postID, userID)
+	// Top level comments have NULL parent
+	var parentID sql.NullInt64
+	if c.ParentID != 0 {
+		parentID = sql.NullInt64{Int64: int64(c.ParentID), Valid: true}
+	}
 
-	return err
+	var commentID int
+	err = tx.QueryRow(query, c.Content, postID, userID, parentID).Scan(&commentID)
+	if err != nil {
+		return 0, err
+	}
+
+	if err = insertRevision(tx, commentID); err != nil {
+		return 0, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return commentID, nil
 }
 
 func (r *commentRepository) GetAllForPost(postID int) (*[]entity.CommentEntity, error) {
 	query := `
-		SELECT c.id, c.content, c.created_at, c.post_id, u.username, 
+		SELECT c.id, c.content, c.created_at, c.updated_at, c.post_id, c.parent_id, u.username, 
 			SUM(CASE WHEN cr.is_like = true THEN 1 ELSE 0 END) as likes_count,
 			SUM(CASE WHEN cr.is_like = false THEN 1 ELSE 0 END) as dislikes_count
 		FROM comments c
@@ -52,22 +79,28 @@ func (r *commentRepository) GetAllForPost(postID int) (*[]entity.CommentEntity,
 		LEFT JOIN comment_reactions cr ON c.id = cr.comment_id
 		WHERE c.post_id = $1
 		GROUP BY c.id
+		ORDER BY c.id
 	`
 
 	rows, err := r.DB.Query(query, postID)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
 	var comments []entity.CommentEntity
 
 	for rows.Next() {
 		var comment entity.CommentEntity
-		if err := rows.Scan(&comment.ID, &comment.Content, &comment.CreatedAt,
-			&comment.PostID, &comment.Username, &comment.Likes, &comment.Dislikes); err != nil {
+		var updatedAt sql.NullTime
+		var parentID sql.NullInt64
+		if err := rows.Scan(&comment.ID, &comment.Content, &comment.CreatedAt, &updatedAt,
+			&comment.PostID, &parentID, &comment.Username, &comment.Likes, &comment.Dislikes); err != nil {
 
 			return nil, err
 		}
+		comment.UpdatedAt = updatedAt.Time
+		comment.ParentID = int(parentID.Int64)
 		comments = append(comments, comment)
 	}
 
@@ -80,7 +113,7 @@ func (r *commentRepository) GetAllForPost(postID int) (*[]entity.CommentEntity,
 
 func (r *commentRepository) GetAllUserCommentsForPost(userID, postID int) (*[]entity.CommentEntity, error) {
 	query := `
-		SELECT c.id, c.content, c.created_at, c.post_id, u.username,
+		SELECT c.id, c.content, c.created_at, c.updated_at, c.post_id, u.username,
 			SUM(CASE WHEN cr.is_like = true THEN 1 ELSE 0 END) as likes_count,
 			SUM(CASE WHEN cr.is_like = false THEN 1 ELSE 0 END) as dislikes_count
 		FROM comments c
@@ -99,11 +132,13 @@ func (r *commentRepository) GetAllUserCommentsForPost(userID, postID int) (*[]en
 
 	for rows.Next() {
 		var comment entity.CommentEntity
-		if err := rows.Scan(&comment.ID, &comment.Content, &comment.CreatedAt,
+		var updatedAt sql.NullTime
+		if err := rows.Scan(&comment.ID, &comment.Content, &comment.CreatedAt, &updatedAt,
 			&comment.PostID, &comment.Username, &comment.Likes, &comment.Dislikes); err != nil {
 
 			return nil, err
 		}
+		comment.UpdatedAt = updatedAt.Time
 		comments = append(comments, comment)
 	}
 
@@ -146,13 +181,20 @@ func (r *commentRepository) Delete(commentID, userID int) error {
 	return nil
 }
 
-func (r *commentRepository) DeleteByPrivileged(commentID int) error {
+// DeleteByPrivileged deletes the comment and records it in moderation log
+func (r *commentRepository) DeleteByPrivileged(commentID int, e entity.ModerationEntry) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
 	query := `
 		DELETE FROM comments
 		WHERE id = $1
 	`
 
-	_, err := r.DB.Exec(query, commentID)
+	_, err = tx.Exec(query, commentID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return entity.ErrCommentNotFound
@@ -160,7 +202,11 @@ func (r *commentRepository) DeleteByPrivileged(commentID int) error {
 		return err
 	}
 
-	return nil
+	if err := moderation.Append(tx, e); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
 func (r *commentRepository) GetAuthorID(commentID int) (int, error) {
@@ -185,37 +231,53 @@ func (r *commentRepository) GetAuthorID(commentID int) (int, error) {
 
 func (r *commentRepository) GetByID(commentID int) (entity.CommentEntity, error) {
 	query := `
-		SELECT content
-		FROM comments
-		WHERE id = $1
+		SELECT c.id, c.content, c.created_at, c.updated_at, c.post_id, c.parent_id, u.username
+		FROM comments c
+		INNER JOIN users u ON c.user_id = u.id
+		WHERE c.id = $1
 	`
 
 	var comment entity.CommentEntity
+	var updatedAt sql.NullTime
+	var parentID sql.NullInt64
 
-	err := r.DB.QueryRow(query, commentID).Scan(&comment.Content)
+	err := r.DB.QueryRow(query, commentID).Scan(&comment.ID, &comment.Content, &comment.CreatedAt,
+		&updatedAt, &comment.PostID, &parentID, &comment.Username)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return entity.CommentEntity{}, entity.ErrCommentNotFound
 		}
 		return entity.CommentEntity{}, err
 	}
+	comment.UpdatedAt = updatedAt.Time
+	comment.ParentID = int(parentID.Int64)
 
 	return comment, nil
 }
 
 func (r *commentRepository) Update(commentID int, content string) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
 	query := `
 		UPDATE comments
-		SET content = $1
+		SET content = $1, updated_at = datetime('now', 'localtime')
 		WHERE id = $2
 	`
 
-	_, err := r.DB.Exec(query, content, commentID)
+	_, err = tx.Exec(query, content, commentID)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	if err = insertRevision(tx, commentID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
 func (r *commentRepository) GetPostID(commentID int) (int, error) {
@@ -237,3 +299,47 @@ func (r *commentRepository) GetPostID(commentID int) (int, error) {
 
 	return postID, nil
 }
+
+func (r *commentRepository) GetRevisions(commentID int) (*[]entity.CommentRevision, error) {
+	query := `
+		SELECT id, comment_id, content, created_at
+		FROM comment_revisions
+		WHERE comment_id = $1
+		ORDER BY id
+	`
+
+	rows, err := r.DB.Query(query, commentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revisions []entity.CommentRevision
+
+	for rows.Next() {
+		var rev entity.CommentRevision
+		if err := rows.Scan(&rev.ID, &rev.CommentID, &rev.Content, &rev.CreatedAt); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, rev)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &revisions, nil
+}
+
+// insertRevision saves current text of comment as its new revision
+func insertRevision(tx *sql.Tx, commentID int) error {
+	query := `
+		INSERT INTO comment_revisions (comment_id, content, created_at)
+		SELECT id, content, datetime('now', 'localtime')
+		FROM comments
+		WHERE id = $1
+	`
+
+	_, err := tx.Exec(query, commentID)
+	return err
+}
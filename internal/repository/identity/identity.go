@@ -0,0 +1,194 @@
+package identity
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"forum/internal/entity"
+	"strings"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+type IIdentityRepository interface {
+	GetUserID(provider, subject string) (int, error)
+	GetAll(userID int) ([]entity.Identity, error)
+	Insert(userID int, identity entity.ExternalIdentity) error
+	InsertWithUser(username string, identity entity.ExternalIdentity) (int, error)
+	Delete(userID int, provider string) error
+}
+
+type identityRepo struct {
+	DB *sql.DB
+}
+
+var _ IIdentityRepository = (*identityRepo)(nil)
+
+func NewIdentityRepo(db *sql.DB) *identityRepo {
+	return &identityRepo{
+		DB: db,
+	}
+}
+
+// GetUserID returns id of user that identity is linked to
+func (r *identityRepo) GetUserID(provider, subject string) (int, error) {
+	query := `
+		SELECT user_id
+		FROM user_identities
+		WHERE provider = $1 AND provider_user_id = $2
+	`
+
+	var userID int
+
+	err := r.DB.QueryRow(query, provider, subject).Scan(&userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, entity.ErrIdentityNotLinked
+		}
+		return 0, err
+	}
+
+	return userID, nil
+}
+
+func (r *identityRepo) GetAll(userID int) ([]entity.Identity, error) {
+	query := `
+		SELECT id, user_id, provider, provider_user_id, email, created_at
+		FROM user_identities
+		WHERE user_id = $1
+		ORDER BY created_at
+	`
+
+	rows, err := r.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var identities []entity.Identity
+
+	for rows.Next() {
+		var i entity.Identity
+		if err := rows.Scan(&i.ID, &i.UserID, &i.Provider, &i.Subject, &i.Email, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		identities = append(identities, i)
+	}
+
+	return identities, rows.Err()
+}
+
+func (r *identityRepo) Insert(userID int, identity entity.ExternalIdentity) error {
+	return insert(r.DB, userID, identity)
+}
+
+// InsertWithUser creates user without password and links identity to it.
+// Email confirmed by provider is marked as verified, made up placeholder
+// address stays unverified until user changes it to a real one
+func (r *identityRepo) InsertWithUser(username string, identity entity.ExternalIdentity) (int, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	query1 := `
+		INSERT INTO users (username, email, hashed_password, created_at, verified_at)
+		VALUES ($1, $2, '', datetime('now', 'localtime'), CASE WHEN $3 THEN datetime('now', 'localtime') END)
+	`
+
+	verified := identity.EmailVerified && !identity.Placeholder
+
+	result, err := tx.Exec(query1, username, identity.Email, verified)
+	if err != nil {
+		return 0, duplicateError(err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	query2 := `
+		INSERT INTO roles (role, user_id)
+		VALUES ($1, $2)
+	`
+
+	_, err = tx.Exec(query2, entity.USER, int(id))
+	if err != nil {
+		return 0, err
+	}
+
+	if err := insert(tx, int(id), identity); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+func (r *identityRepo) Delete(userID int, provider string) error {
+	query := `
+		DELETE FROM user_identities
+		WHERE user_id = $1 AND provider = $2
+	`
+
+	result, err := r.DB.Exec(query, userID, provider)
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return entity.ErrIdentityNotLinked
+	}
+
+	return nil
+}
+
+// execer is implemented by both sql.DB and sql.Tx
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+func insert(db execer, userID int, identity entity.ExternalIdentity) error {
+	query := `
+		INSERT INTO user_identities (user_id, provider, provider_user_id, email, created_at)
+		VALUES ($1, $2, $3, $4, datetime('now', 'localtime'))
+	`
+
+	_, err := db.Exec(query, userID, identity.Provider, identity.Subject, identity.Email)
+	if err != nil {
+		return duplicateError(err)
+	}
+
+	return nil
+}
+
+// duplicateError maps violated unique constraints to entity errors
+func duplicateError(err error) error {
+	var sqliteError sqlite3.Error
+	if !errors.As(err, &sqliteError) || sqliteError.Code != sqlite3.ErrConstraint ||
+		!strings.Contains(sqliteError.Error(), "UNIQUE constraint failed:") {
+		return err
+	}
+
+	switch msg := sqliteError.Error(); {
+	case strings.Contains(msg, "users.email"):
+		return entity.ErrDuplicateEmail
+	case strings.Contains(msg, "users.username"):
+		return entity.ErrDuplicateUsername
+	case strings.Contains(msg, "user_identities.provider_user_id"):
+		return entity.ErrIdentityLinked
+	case strings.Contains(msg, "user_identities.user_id"):
+		return entity.ErrProviderLinked
+	default:
+		return fmt.Errorf("(repo) identity: unknown field - %v", sqliteError)
+	}
+}
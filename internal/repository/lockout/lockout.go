@@ -0,0 +1,241 @@
+package lockout
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"forum/internal/entity"
+	"forum/internal/repository/moderation"
+	"time"
+)
+
+type ILockoutRepository interface {
+	LockedFor(userID int, ip string) (time.Duration, error)
+	AddUserFailure(userID int, window time.Duration) (entity.Lockout, error)
+	AddIPFailure(ip string, window time.Duration) (entity.Lockout, error)
+	Lock(lockoutID int, d time.Duration) error
+	SetNotified(lockoutID int) error
+	DeleteUser(userID int) error
+	Get(lockoutID int) (entity.Lockout, error)
+	Delete(lockoutID int, e entity.ModerationEntry) error
+	DeleteStale(window time.Duration) error
+	GetAll(window time.Duration) ([]entity.Lockout, error)
+}
+
+type lockoutRepo struct {
+	DB *sql.DB
+}
+
+var _ ILockoutRepository = (*lockoutRepo)(nil)
+
+func NewLockoutRepo(db *sql.DB) *lockoutRepo {
+	return &lockoutRepo{
+		DB: db,
+	}
+}
+
+// LockedFor returns time until the longest lock of the account or of the
+// address ends, it's 0 if neither of them is locked
+func (r *lockoutRepo) LockedFor(userID int, ip string) (time.Duration, error) {
+	query := `
+		SELECT COALESCE(MAX(strftime('%s', locked_until) - strftime('%s', 'now', 'localtime')), 0)
+		FROM login_lockouts
+		WHERE (user_id = $1 OR ip = $2) AND locked_until > datetime('now', 'localtime')
+	`
+
+	var seconds int
+
+	err := r.DB.QueryRow(query, userID, ip).Scan(&seconds)
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(seconds) * time.Second, nil
+}
+
+// AddUserFailure counts failed login of the account. Failures are counted
+// from the start if the previous one was more than window ago
+func (r *lockoutRepo) AddUserFailure(userID int, window time.Duration) (entity.Lockout, error) {
+	query := `
+		INSERT INTO login_lockouts (user_id, failures, last_failure)
+		VALUES ($1, 1, datetime('now', 'localtime'))
+		ON CONFLICT (user_id) DO UPDATE
+		SET failures = CASE WHEN last_failure > datetime('now', 'localtime', $2) THEN failures + 1 ELSE 1 END,
+			notified = CASE WHEN last_failure > datetime('now', 'localtime', $2) THEN notified ELSE 0 END,
+			last_failure = excluded.last_failure
+		RETURNING id, failures, notified
+	`
+
+	l := entity.Lockout{UserID: userID}
+
+	err := r.DB.QueryRow(query, userID, modifier(-window)).Scan(&l.ID, &l.Failures, &l.Notified)
+	return l, err
+}
+
+// AddIPFailure counts failed login from the address the same way as
+// AddUserFailure
+func (r *lockoutRepo) AddIPFailure(ip string, window time.Duration) (entity.Lockout, error) {
+	query := `
+		INSERT INTO login_lockouts (ip, failures, last_failure)
+		VALUES ($1, 1, datetime('now', 'localtime'))
+		ON CONFLICT (ip) DO UPDATE
+		SET failures = CASE WHEN last_failure > datetime('now', 'localtime', $2) THEN failures + 1 ELSE 1 END,
+			notified = CASE WHEN last_failure > datetime('now', 'localtime', $2) THEN notified ELSE 0 END,
+			last_failure = excluded.last_failure
+		RETURNING id, failures, notified
+	`
+
+	l := entity.Lockout{IP: ip}
+
+	err := r.DB.QueryRow(query, ip, modifier(-window)).Scan(&l.ID, &l.Failures, &l.Notified)
+	return l, err
+}
+
+// Lock refuses logins for d from now
+func (r *lockoutRepo) Lock(lockoutID int, d time.Duration) error {
+	query := `
+		UPDATE login_lockouts
+		SET locked_until = datetime('now', 'localtime', $1)
+		WHERE id = $2
+	`
+
+	_, err := r.DB.Exec(query, modifier(d), lockoutID)
+	return err
+}
+
+func (r *lockoutRepo) SetNotified(lockoutID int) error {
+	query := `
+		UPDATE login_lockouts
+		SET notified = true
+		WHERE id = $1
+	`
+
+	_, err := r.DB.Exec(query, lockoutID)
+	return err
+}
+
+// DeleteUser forgets failed logins of the account
+func (r *lockoutRepo) DeleteUser(userID int) error {
+	query := `
+		DELETE FROM login_lockouts
+		WHERE user_id = $1
+	`
+
+	_, err := r.DB.Exec(query, userID)
+	return err
+}
+
+func (r *lockoutRepo) Get(lockoutID int) (entity.Lockout, error) {
+	query := `
+		SELECT l.id, COALESCE(l.user_id, 0), COALESCE(u.username, ''), COALESCE(l.ip, ''),
+			l.failures, l.last_failure, l.locked_until,
+			COALESCE(l.locked_until > datetime('now', 'localtime'), false) AS locked, l.notified
+		FROM login_lockouts l
+		LEFT JOIN users u ON u.id = l.user_id
+		WHERE l.id = $1
+	`
+
+	var l entity.Lockout
+	var lockedUntil sql.NullTime
+
+	err := r.DB.QueryRow(query, lockoutID).Scan(&l.ID, &l.UserID, &l.Username, &l.IP, &l.Failures, &l.LastFailure, &lockedUntil, &l.Locked, &l.Notified)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return entity.Lockout{}, entity.ErrLockoutNotFound
+		}
+		return entity.Lockout{}, err
+	}
+	l.LockedUntil = lockedUntil.Time
+
+	return l, nil
+}
+
+// Delete deletes the lockout and records it in moderation log
+func (r *lockoutRepo) Delete(lockoutID int, e entity.ModerationEntry) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		DELETE FROM login_lockouts
+		WHERE id = $1
+	`
+
+	res, err := tx.Exec(query, lockoutID)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return entity.ErrLockoutNotFound
+	}
+
+	if err := moderation.Append(tx, e); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeleteStale deletes lockouts that ended and have no failures during the
+// last window, they would be counted from the start anyway
+func (r *lockoutRepo) DeleteStale(window time.Duration) error {
+	query := `
+		DELETE FROM login_lockouts
+		WHERE last_failure <= datetime('now', 'localtime', $1)
+			AND (locked_until IS NULL OR locked_until <= datetime('now', 'localtime'))
+	`
+
+	_, err := r.DB.Exec(query, modifier(-window))
+	return err
+}
+
+// GetAll returns lockouts with failures during the last window or that
+// didn't end yet, locked ones first
+func (r *lockoutRepo) GetAll(window time.Duration) ([]entity.Lockout, error) {
+	query := `
+		SELECT l.id, COALESCE(l.user_id, 0), COALESCE(u.username, ''), COALESCE(l.ip, ''),
+			l.failures, l.last_failure, l.locked_until,
+			COALESCE(l.locked_until > datetime('now', 'localtime'), false) AS locked, l.notified
+		FROM login_lockouts l
+		LEFT JOIN users u ON u.id = l.user_id
+		WHERE l.last_failure > datetime('now', 'localtime', $1)
+			OR l.locked_until > datetime('now', 'localtime')
+		ORDER BY locked DESC, l.last_failure DESC
+	`
+
+	rows, err := r.DB.Query(query, modifier(-window))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lockouts []entity.Lockout
+
+	for rows.Next() {
+		var l entity.Lockout
+		var lockedUntil sql.NullTime
+
+		err := rows.Scan(&l.ID, &l.UserID, &l.Username, &l.IP, &l.Failures, &l.LastFailure, &lockedUntil, &l.Locked, &l.Notified)
+		if err != nil {
+			return nil, err
+		}
+		l.LockedUntil = lockedUntil.Time
+
+		lockouts = append(lockouts, l)
+	}
+
+	return lockouts, rows.Err()
+}
+
+// modifier formats duration as sqlite datetime modifier
+func modifier(d time.Duration) string {
+	return fmt.Sprintf("%+d seconds", int(d.Seconds()))
+}
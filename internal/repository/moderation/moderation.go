@@ -0,0 +1,73 @@
+package moderation
+
+import (
+	"database/sql"
+	"forum/internal/entity"
+)
+
+type IModerationRepository interface {
+	GetAll(f entity.ModerationFilter) ([]entity.ModerationEntry, error)
+}
+
+type moderationRepo struct {
+	DB *sql.DB
+}
+
+var _ IModerationRepository = (*moderationRepo)(nil)
+
+func NewModerationRepo(db *sql.DB) *moderationRepo {
+	return &moderationRepo{
+		DB: db,
+	}
+}
+
+// Append adds entry to moderation log within tx of the action it records, so
+// both are saved or rolled back together. Name of the actor is saved too, so
+// the log stays readable after the actor is deleted
+func Append(tx *sql.Tx, e entity.ModerationEntry) error {
+	query := `
+		INSERT INTO moderation_log (actor_id, actor_name, action, target_type, target_id, reason, before_snapshot, created_at)
+		VALUES ($1, COALESCE((SELECT username FROM users WHERE id = $1), 'system'), $2, $3, $4, $5, $6, datetime('now', 'localtime'))
+	`
+
+	before := sql.NullString{String: e.Before, Valid: e.Before != ""}
+
+	_, err := tx.Exec(query, e.ActorID, e.Action, e.TargetType, e.TargetID, e.Reason, before)
+	return err
+}
+
+// GetAll returns entries of moderation log matching the filter, newest first
+func (r *moderationRepo) GetAll(f entity.ModerationFilter) ([]entity.ModerationEntry, error) {
+	query := `
+		SELECT id, actor_id, actor_name, action, target_type, target_id, reason, COALESCE(before_snapshot, ''), created_at
+		FROM moderation_log
+		WHERE ($1 = '' OR actor_name = $1 COLLATE NOCASE)
+			AND ($2 = '' OR action = $2)
+			AND ($3 = '' OR target_type = $3)
+			AND ($4 = 0 OR target_id = $4)
+			AND ($5 = 0 OR id < $5)
+		ORDER BY id DESC
+		LIMIT $6
+	`
+
+	rows, err := r.DB.Query(query, f.Actor, f.Action, f.TargetType, f.TargetID, f.Before, f.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []entity.ModerationEntry
+
+	for rows.Next() {
+		var e entity.ModerationEntry
+
+		err := rows.Scan(&e.ID, &e.ActorID, &e.ActorName, &e.Action, &e.TargetType, &e.TargetID, &e.Reason, &e.Before, &e.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, e)
+	}
+
+	return entries, rows.Err()
+}
@@ -2,14 +2,155 @@ package post
 
 import (
 	"database/sql"
+	"fmt"
 	"forum/internal/entity"
+	"time"
 )
 
+// cursorLayout matches format in which 'created_at' is stored, so cursor can
+// be compared with it as text
+const cursorLayout = "2006-01-02 15:04:05"
+
+// postsSelect is a common part of all post listings. Reactions and comments
+// are counted in subqueries instead of joins, so posts can be taken in
+// listing order page by page without grouping the whole table
+const postsSelect = `
+	SELECT p.id, p.title, p.content, p.created_at, u.username,
+		(
+			SELECT COUNT(*)
+			FROM post_reactions pr
+			WHERE pr.post_id = p.id AND pr.is_like = true
+		) AS likes_count,
+		(
+			SELECT COUNT(*)
+			FROM post_reactions pr
+			WHERE pr.post_id = p.id AND pr.is_like = false
+		) AS dislikes_count,
+		(
+			SELECT COUNT(*)
+			FROM comments c
+			WHERE c.post_id = p.id
+		) AS comments_count,
+		(
+			SELECT GROUP_CONCAT(t.name, ', ')
+			FROM tags t
+			LEFT JOIN posts_tags pt ON pt.tag_id = t.id
+			WHERE pt.post_id = p.id
+		) AS tags,
+		(
+			SELECT name
+			FROM images
+			WHERE post_id = p.id
+		) AS image_name
+	FROM posts p
+	INNER JOIN users u ON p.user_id = u.id
+`
+
+// scores are expressions by which posts are sorted in addition to creation
+// time. They are calculated over columns of postsSelect
+var scores = map[string]string{
+	entity.SORT_NEW:       "0",
+	entity.SORT_TOP:       "likes_count - dislikes_count",
+	entity.SORT_COMMENTED: "comments_count",
+
+	// Many reactions that are split evenly between likes and dislikes
+	entity.SORT_CONTROVERSIAL: `
+		(likes_count + dislikes_count) * MIN(likes_count, dislikes_count) * 1.0
+		/ MAX(likes_count, dislikes_count, 1)
+	`,
+
+	// Rating that decays with squared age in hours, so fresh posts with
+	// few likes outrun old popular ones. Age is counted to the reference
+	// time ($%[1]d) which stays the same while paging
+	entity.SORT_HOT: `
+		(likes_count - dislikes_count + 1) * 1.0
+		/ ((julianday($%[1]d) - julianday(created_at)) * 24 + 2)
+		/ ((julianday($%[1]d) - julianday(created_at)) * 24 + 2)
+	`,
+}
+
+// periods are modifiers of sqlite date function, that limit top posts
+// to given time window before the reference time
+var periods = map[string]string{
+	entity.PERIOD_DAY:   "-1 day",
+	entity.PERIOD_WEEK:  "-7 days",
+	entity.PERIOD_MONTH: "-1 month",
+	entity.PERIOD_YEAR:  "-1 year",
+}
+
+// getPostsPage returns one page of posts that satisfy given filter condition.
+// Filter placeholders are numbered from $1 and correspond to given args.
+//
+// Posts are always returned in listing order - best (or newest) first
+func getPostsPage(db *sql.DB, filter string, page entity.PageRequest, args ...interface{}) (*[]entity.PostEntity, error) {
+	score, ok := scores[page.Sort]
+	if !ok {
+		return nil, entity.ErrInvalidSort
+	}
+
+	inner := postsSelect + "WHERE true"
+
+	if filter != "" {
+		inner += " AND " + filter
+	}
+
+	if modifier, ok := periods[page.Period]; ok && page.Sort == entity.SORT_TOP {
+		args = append(args, page.Ref.In(time.Local).Format(cursorLayout), modifier)
+		inner += fmt.Sprintf(" AND p.created_at >= datetime($%d, $%d)", len(args)-1, len(args))
+	}
+
+	if page.Sort == entity.SORT_HOT {
+		args = append(args, page.Ref.In(time.Local).Format(cursorLayout))
+		score = fmt.Sprintf(score, len(args))
+	}
+
+	query := fmt.Sprintf("SELECT *, %s AS score FROM (%s) WHERE true", score, inner)
+
+	order, cmp := "DESC", "<"
+	if page.Backward {
+		// Previous page is read from cursor towards better posts
+		order, cmp = "ASC", ">"
+	}
+	orderBy := fmt.Sprintf("created_at %[1]s, id %[1]s", order)
+
+	// Newest posts don't need score, so the index on (created_at, id)
+	// can be used for ordering
+	if page.Sort == entity.SORT_NEW {
+		if page.Cursor != nil {
+			args = append(args, page.Cursor.CreatedAt.Format(cursorLayout), page.Cursor.ID)
+			query += fmt.Sprintf(" AND (created_at, id) %s ($%d, $%d)", cmp, len(args)-1, len(args))
+		}
+	} else {
+		if page.Cursor != nil {
+			args = append(args, page.Cursor.Score, page.Cursor.CreatedAt.Format(cursorLayout), page.Cursor.ID)
+			query += fmt.Sprintf(" AND (score, created_at, id) %s ($%d, $%d, $%d)", cmp, len(args)-2, len(args)-1, len(args))
+		}
+		orderBy = "score " + order + ", " + orderBy
+	}
+
+	args = append(args, page.Limit)
+	query += fmt.Sprintf(" ORDER BY %s LIMIT $%d", orderBy, len(args))
+
+	posts, err := getAllPostsByQuery(db, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	if page.Backward {
+		for i, j := 0, len(*posts)-1; i < j; i, j = i+1, j-1 {
+			(*posts)[i], (*posts)[j] = (*posts)[j], (*posts)[i]
+		}
+	}
+
+	return posts, nil
+}
+
 func getAllPostsByQuery(db *sql.DB, query string, args ...interface{}) (*[]entity.PostEntity, error) {
 	rows, err := db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
 	var posts []entity.PostEntity
 
@@ -18,7 +159,7 @@ func getAllPostsByQuery(db *sql.DB, query string, args ...interface{}) (*[]entit
 		var tags sql.NullString
 		var imageName sql.NullString
 		if err := rows.Scan(&post.ID, &post.Title, &post.Content, &post.CreatedAt,
-			&post.Username, &post.Likes, &post.Dislikes, &post.CommentsLen, &tags, &imageName); err != nil {
+			&post.Username, &post.Likes, &post.Dislikes, &post.CommentsLen, &tags, &imageName, &post.Score); err != nil {
 
 			return nil, err
 		}
@@ -37,3 +178,29 @@ func getAllPostsByQuery(db *sql.DB, query string, args ...interface{}) (*[]entit
 
 	return &posts, nil
 }
+
+// insertRevision saves current state of post as its new revision made by
+// given user
+func insertRevision(tx *sql.Tx, postID, userID int) error {
+	query := `
+		INSERT INTO post_revisions (post_id, user_id, title, content, tags, image_name, created_at)
+		SELECT p.id, NULLIF($1, 0), p.title, p.content,
+			COALESCE((
+				SELECT GROUP_CONCAT(t.name, ', ')
+				FROM tags t
+				INNER JOIN posts_tags pt ON pt.tag_id = t.id
+				WHERE pt.post_id = p.id
+			), ''),
+			(
+				SELECT name
+				FROM images
+				WHERE post_id = p.id
+			),
+			datetime('now', 'localtime')
+		FROM posts p
+		WHERE p.id = $2
+	`
+
+	_, err := tx.Exec(query, userID, postID)
+	return err
+}
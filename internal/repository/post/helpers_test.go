@@ -0,0 +1,248 @@
+package post
+
+import (
+	"database/sql"
+	"forum/internal/assert"
+	"forum/internal/entity"
+	"forum/migrations"
+	"forum/pkg/migrate"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// newTestDB returns in-memory database with five posts. Posts 2, 3 and 4 are
+// created at the same second, post 2 is written by another user. Posts 1, 3
+// and 5 are liked once, so by rating they tie with each other and posts 2 and
+// 4 tie on (score, created_at)
+func newTestDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every new connection to ':memory:' is a new database
+	db.SetMaxOpenConns(1)
+
+	t.Cleanup(func() { db.Close() })
+
+	m, err := migrate.New(db, migrations.Files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(); err != nil {
+		t.Fatal(err)
+	}
+
+	fixtures := `
+		INSERT INTO users (id, username, email, hashed_password, created_at) VALUES
+			(1, 'yuta', 'yuta@gmail.com', '', '2024-01-01 00:00:00'),
+			(2, 'satoru', 'satoru@gmail.com', '', '2024-01-01 00:00:00');
+		INSERT INTO posts (id, title, content, user_id, created_at) VALUES
+			(1, 'first', 'content', 1, '2024-01-01 10:00:00'),
+			(2, 'second', 'content', 2, '2024-01-02 10:00:00'),
+			(3, 'third', 'content', 1, '2024-01-02 10:00:00'),
+			(4, 'fourth', 'content', 1, '2024-01-02 10:00:00'),
+			(5, 'fifth', 'content', 1, '2024-01-03 10:00:00');
+		INSERT INTO post_reactions (is_like, created_at, post_id, user_id) VALUES
+			(true, '2024-01-04 00:00:00', 1, 2),
+			(true, '2024-01-04 00:00:00', 3, 2),
+			(true, '2024-01-04 00:00:00', 5, 2);
+	`
+	if _, err := db.Exec(fixtures); err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+func TestGetPostsPage(t *testing.T) {
+	db := newTestDB(t)
+
+	tie := time.Date(2024, time.January, 2, 10, 0, 0, 0, time.UTC)
+	last := time.Date(2024, time.January, 1, 10, 0, 0, 0, time.UTC)
+	ref := time.Date(2024, time.January, 3, 12, 0, 0, 0, time.Local)
+
+	newest := func(cursor *entity.PostCursor, backward bool, limit int) entity.PageRequest {
+		return entity.PageRequest{Cursor: cursor, Backward: backward, Limit: limit, Sort: entity.SORT_NEW, Ref: ref}
+	}
+	top := func(period string, ref time.Time, cursor *entity.PostCursor, backward bool) entity.PageRequest {
+		return entity.PageRequest{Cursor: cursor, Backward: backward, Limit: 10, Sort: entity.SORT_TOP, Period: period, Ref: ref}
+	}
+
+	tests := []struct {
+		name    string
+		filter  string
+		args    []interface{}
+		page    entity.PageRequest
+		wantIDs []int
+	}{
+		{
+			name:    "First page",
+			page:    newest(nil, false, 2),
+			wantIDs: []int{5, 4},
+		},
+		{
+			name:    "After cursor inside of tie",
+			page:    newest(&entity.PostCursor{CreatedAt: tie, ID: 4}, false, 2),
+			wantIDs: []int{3, 2},
+		},
+		{
+			name:    "After cursor at the end of tie",
+			page:    newest(&entity.PostCursor{CreatedAt: tie, ID: 2}, false, 10),
+			wantIDs: []int{1},
+		},
+		{
+			name:    "Before cursor inside of tie",
+			page:    newest(&entity.PostCursor{CreatedAt: tie, ID: 2}, true, 2),
+			wantIDs: []int{4, 3},
+		},
+		{
+			name:    "Before cursor at the start of tie",
+			page:    newest(&entity.PostCursor{CreatedAt: tie, ID: 4}, true, 10),
+			wantIDs: []int{5},
+		},
+		{
+			name:    "After the last post",
+			page:    newest(&entity.PostCursor{CreatedAt: last, ID: 1}, false, 10),
+			wantIDs: nil,
+		},
+		{
+			name:    "Filter placeholders go before cursor ones",
+			filter:  "p.user_id = $1",
+			args:    []interface{}{1},
+			page:    newest(&entity.PostCursor{CreatedAt: tie, ID: 4}, false, 10),
+			wantIDs: []int{3, 1},
+		},
+		{
+			name:    "Top posts of all time",
+			page:    top(entity.PERIOD_ALL, ref, nil, false),
+			wantIDs: []int{5, 3, 1, 4, 2},
+		},
+		{
+			name:    "Top posts after cursor inside of score tie",
+			page:    top(entity.PERIOD_ALL, ref, &entity.PostCursor{Score: 1, CreatedAt: tie, ID: 3}, false),
+			wantIDs: []int{1, 4, 2},
+		},
+		{
+			name:    "Top posts after cursor inside of (score, created_at) tie",
+			page:    top(entity.PERIOD_ALL, ref, &entity.PostCursor{Score: 0, CreatedAt: tie, ID: 4}, false),
+			wantIDs: []int{2},
+		},
+		{
+			name:    "Top posts before cursor inside of (score, created_at) tie",
+			page:    top(entity.PERIOD_ALL, ref, &entity.PostCursor{Score: 0, CreatedAt: tie, ID: 2}, true),
+			wantIDs: []int{5, 3, 1, 4},
+		},
+		{
+			name:    "Top posts of the day before reference time",
+			page:    top(entity.PERIOD_DAY, ref, nil, false),
+			wantIDs: []int{5},
+		},
+		{
+			name:    "Top posts of the day move with reference time",
+			page:    top(entity.PERIOD_DAY, ref.Add(-3*time.Hour), nil, false),
+			wantIDs: []int{5, 3, 4, 2},
+		},
+		{
+			name:    "Top posts of the week",
+			page:    top(entity.PERIOD_WEEK, ref, nil, false),
+			wantIDs: []int{5, 3, 1, 4, 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			posts, err := getPostsPage(db, tt.filter, tt.page, tt.args...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, len(*posts), len(tt.wantIDs))
+			for i := range tt.wantIDs {
+				if i < len(*posts) {
+					assert.Equal(t, (*posts)[i].ID, tt.wantIDs[i])
+				}
+			}
+		})
+	}
+}
+
+// TestGetPostsPageWalk pages through all posts forward and then back from the
+// last one, so none of them is skipped or repeated
+func TestGetPostsPageWalk(t *testing.T) {
+	r := NewPostRepo(newTestDB(t))
+
+	tests := []struct {
+		sort    string
+		wantIDs []int
+	}{
+		{sort: entity.SORT_NEW, wantIDs: []int{5, 4, 3, 2, 1}},
+		{sort: entity.SORT_TOP, wantIDs: []int{5, 3, 1, 4, 2}},
+		// Post 1 is liked too, but it is the oldest one
+		{sort: entity.SORT_HOT, wantIDs: []int{5, 3, 4, 2, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sort, func(t *testing.T) {
+			cursorAt := func(p entity.PostEntity) *entity.PostCursor {
+				return &entity.PostCursor{Score: p.Score, CreatedAt: p.CreatedAt, ID: p.ID}
+			}
+
+			var forward []int
+			page := entity.PageRequest{
+				Limit:  2,
+				Sort:   tt.sort,
+				Period: entity.PERIOD_ALL,
+				Ref:    time.Date(2024, time.January, 4, 0, 0, 0, 0, time.Local),
+			}
+			for {
+				posts, err := r.GetAll(page)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(*posts) == 0 {
+					break
+				}
+				for _, p := range *posts {
+					forward = append(forward, p.ID)
+				}
+				page.Cursor = cursorAt((*posts)[len(*posts)-1])
+			}
+
+			assert.Equal(t, len(forward), len(tt.wantIDs))
+			for i := range tt.wantIDs {
+				if i < len(forward) {
+					assert.Equal(t, forward[i], tt.wantIDs[i])
+				}
+			}
+
+			var backward []int
+			page.Backward = true
+			for {
+				posts, err := r.GetAll(page)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(*posts) == 0 {
+					break
+				}
+				// Pages are in listing order, so they are prepended
+				ids := make([]int, 0, len(*posts))
+				for _, p := range *posts {
+					ids = append(ids, p.ID)
+				}
+				backward = append(ids, backward...)
+				page.Cursor = cursorAt((*posts)[0])
+			}
+
+			want := tt.wantIDs[:len(tt.wantIDs)-1]
+			assert.Equal(t, len(backward), len(want))
+			for i := range want {
+				if i < len(backward) {
+					assert.Equal(t, backward[i], want[i])
+				}
+			}
+		})
+	}
+}
@@ -4,21 +4,23 @@ import (
 	"database/sql"
 	"errors"
 	"forum/internal/entity"
+	"forum/internal/repository/moderation"
 )
 
 type IPostRepository interface {
 	Insert(entity.PostCreateForm, []int) (int, error)
 	Get(int) (entity.PostEntity, error)
-	GetAll() (*[]entity.PostEntity, error)
-	GetAllByTagId(int) (*[]entity.PostEntity, error)
-	GetAllByUserID(int) (*[]entity.PostEntity, error)
-	GetAllByUserReaction(int) (*[]entity.PostEntity, error)
-	GetAllCommentedPosts(userID int) (*[]entity.PostEntity, error)
+	GetAll(entity.PageRequest) (*[]entity.PostEntity, error)
+	GetAllByTagId(int, entity.PageRequest) (*[]entity.PostEntity, error)
+	GetAllByUserID(int, entity.PageRequest) (*[]entity.PostEntity, error)
+	GetAllByUserReaction(int, entity.PageRequest) (*[]entity.PostEntity, error)
+	GetAllCommentedPosts(userID int, page entity.PageRequest) (*[]entity.PostEntity, error)
 	Exists(int) (bool, error)
 	Delete(postID int, userID int) error
-	DeleteByPrivileged(postID int) error
+	DeleteByPrivileged(postID int, e entity.ModerationEntry) error
 	GetAuthorID(postID int) (int, error)
 	Update(p entity.PostCreateForm, tagIDs []int, deleteImage bool) error
+	GetRevisions(postID int) (*[]entity.PostRevision, error)
 }
 
 type postRepository struct {
@@ -71,6 +73,10 @@ func (r *postRepository) Insert(p entity.PostCreateForm, tagIDs []int) (int, err
 		return 0, err
 	}
 
+	if err = insertRevision(tx, postID, p.UserID); err != nil {
+		return 0, err
+	}
+
 	if err = tx.Commit(); err != nil {
 		return 0, err
 	}
@@ -80,7 +86,7 @@ func (r *postRepository) Insert(p entity.PostCreateForm, tagIDs []int) (int, err
 
 func (r *postRepository) Get(postID int) (entity.PostEntity, error) {
 	query := `
-		SELECT p.id, p.title, p.content, p.created_at, u.username,
+		SELECT p.id, p.title, p.content, p.created_at, p.updated_at, u.username,
 			SUM(CASE WHEN pr.is_like = true THEN 1 ELSE 0 END) as likes_count,
 			SUM(CASE WHEN pr.is_like = false THEN 1 ELSE 0 END) as dislikes_count,
 			(
@@ -107,10 +113,11 @@ func (r *postRepository) Get(postID int) (entity.PostEntity, error) {
 		`
 
 	var post entity.PostEntity
+	var updatedAt sql.NullTime
 	var tags sql.NullString
 	var imageName sql.NullString
 	if err := r.DB.QueryRow(query, postID).Scan(&post.ID, &post.Title, &post.Content,
-		&post.CreatedAt, &post.Username, &post.Likes, &post.Dislikes, &post.CommentsLen, &tags, &imageName); err != nil {
+		&post.CreatedAt, &updatedAt, &post.Username, &post.Likes, &post.Dislikes, &post.CommentsLen, &tags, &imageName); err != nil {
 
 		if errors.Is(err, sql.ErrNoRows) {
 			return entity.PostEntity{}, entity.ErrNoRecord
@@ -118,6 +125,9 @@ func (r *postRepository) Get(postID int) (entity.PostEntity, error) {
 		return entity.PostEntity{}, err
 	}
 
+	if updatedAt.Valid {
+		post.UpdatedAt = updatedAt.Time
+	}
 	if tags.Valid {
 		post.PostTags = tags.String
 	}
@@ -128,181 +138,52 @@ func (r *postRepository) Get(postID int) (entity.PostEntity, error) {
 	return post, nil
 }
 
-func (r *postRepository) GetAll() (*[]entity.PostEntity, error) {
-	query := `
-		SELECT p.id, p.title, p.content, p.created_at, u.username, 
-			SUM(CASE WHEN pr.is_like = true THEN 1 ELSE 0 END) as likes_count,
-			SUM(CASE WHEN pr.is_like = false THEN 1 ELSE 0 END) as dislikes_count,
-			(
-				SELECT COUNT(*)
-				FROM comments c
-				WHERE c.post_id = p.id
-			),
-			(
-				SELECT GROUP_CONCAT(t.name, ', ')
-				FROM tags t
-				LEFT JOIN posts_tags pt ON pt.tag_id = t.id
-				WHERE pt.post_id = p.id
-			),
-			(
-				SELECT name
-				FROM images
-				WHERE post_id = p.id
-			)
-		FROM posts p
-		INNER JOIN users u ON p.user_id = u.id
-		LEFT JOIN post_reactions pr ON p.id = pr.post_id
-		GROUP BY p.id
-		ORDER BY p.created_at DESC
-	`
-
-	return getAllPostsByQuery(r.DB, query)
+func (r *postRepository) GetAll(page entity.PageRequest) (*[]entity.PostEntity, error) {
+	return getPostsPage(r.DB, "", page)
 }
 
-func (r *postRepository) GetAllByTagId(tagID int) (*[]entity.PostEntity, error) {
-	query := `
-		SELECT p.id, p.title, p.content, p.created_at, u.username, 
-			SUM(CASE WHEN pr.is_like = true THEN 1 ELSE 0 END) as likes_count,
-			SUM(CASE WHEN pr.is_like = false THEN 1 ELSE 0 END) as dislikes_count,
-			(
-				SELECT COUNT(*)
-				FROM comments c
-				WHERE c.post_id = p.id
-			),
-			(
-				SELECT GROUP_CONCAT(t.name, ', ')
-				FROM tags t
-				LEFT JOIN posts_tags pt ON pt.tag_id = t.id
-				WHERE pt.post_id = p.id
-			),
-			(
-				SELECT name
-				FROM images
-				WHERE post_id = p.id
-			)
-		FROM posts p
-		INNER JOIN users u ON p.user_id = u.id
-		LEFT JOIN post_reactions pr ON p.id = pr.post_id
-		WHERE p.id IN (
+func (r *postRepository) GetAllByTagId(tagID int, page entity.PageRequest) (*[]entity.PostEntity, error) {
+	filter := `
+		p.id IN (
 			SELECT pt.post_id
 			FROM posts_tags pt
 			WHERE pt.tag_id = $1
-			)
-		GROUP BY p.id
-		ORDER BY p.created_at DESC
+		)
 	`
 
-	return getAllPostsByQuery(r.DB, query, tagID)
+	return getPostsPage(r.DB, filter, page, tagID)
 }
 
-func (r *postRepository) GetAllByUserID(userID int) (*[]entity.PostEntity, error) {
-	query := `
-		SELECT p.id, p.title, p.content, p.created_at, u.username,
-			SUM(CASE WHEN pr.is_like = true THEN 1 ELSE 0 END) as likes_count,
-			SUM(CASE WHEN pr.is_like = false THEN 1 ELSE 0 END) as dislikes_count,
-			(
-				SELECT COUNT(*)
-				FROM comments c
-				WHERE c.post_id = p.id
-			),
-			(
-				SELECT GROUP_CONCAT(t.name, ', ')
-				FROM tags t
-				LEFT JOIN posts_tags pt ON pt.tag_id = t.id
-				WHERE pt.post_id = p.id
-			),
-			(
-				SELECT name
-				FROM images
-				WHERE post_id = p.id
-			)
-		FROM posts p
-		INNER JOIN users u ON p.user_id = u.id
-		LEFT JOIN post_reactions pr ON p.id = pr.post_id
-		WHERE p.user_id = $1
-		GROUP BY p.id 
-		ORDER BY p.created_at DESC
+func (r *postRepository) GetAllByUserID(userID int, page entity.PageRequest) (*[]entity.PostEntity, error) {
+	filter := `
+		p.user_id = $1
 	`
 
-	return getAllPostsByQuery(r.DB, query, userID)
+	return getPostsPage(r.DB, filter, page, userID)
 }
 
-func (r *postRepository) GetAllByUserReaction(userID int) (*[]entity.PostEntity, error) {
-	query := `
-		SELECT p.id, p.title, p.content, p.created_at, u.username,
-			COALESCE(l.likes_count, 0) as likes_count,
-			COALESCE(d.dislikes_count, 0) as dislikes_count,
-			(
-				SELECT COUNT(*)
-				FROM comments c
-				WHERE c.post_id = p.id
-			),
-			(
-				SELECT GROUP_CONCAT(t.name, ', ')
-				FROM tags t
-				LEFT JOIN posts_tags pt ON pt.tag_id = t.id
-				WHERE pt.post_id = p.id 
-			),
-			(
-				SELECT name
-				FROM images
-				WHERE post_id = p.id
-			)
-		FROM posts p
-		INNER JOIN users u ON p.user_id = u.id
-		LEFT JOIN (
-			SELECT post_id, COUNT(*) as likes_count
-			FROM post_reactions
-			WHERE is_like = true
-			GROUP BY post_id
-		) l ON p.id = l.post_id
-		LEFT JOIN (
-			SELECT post_id, COUNT(*) as dislikes_count
-			FROM post_reactions
-			WHERE is_like = false
-			GROUP BY post_id
-		) d ON p.id = d.post_id
-		WHERE p.id IN (
+func (r *postRepository) GetAllByUserReaction(userID int, page entity.PageRequest) (*[]entity.PostEntity, error) {
+	filter := `
+		p.id IN (
 			SELECT post_id
-			FROM post_reactions 
+			FROM post_reactions
 			WHERE user_id = $1
 		)
-		GROUP BY p.id 
 	`
 
-	return getAllPostsByQuery(r.DB, query, userID)
+	return getPostsPage(r.DB, filter, page, userID)
 }
 
-func (r *postRepository) GetAllCommentedPosts(userID int) (*[]entity.PostEntity, error) {
-	query := `
-		SELECT p.id, p.title, p.content, p.created_at, u.username,
-			SUM(CASE WHEN pr.is_like = true THEN 1 ELSE 0 END) as likes_count,
-			SUM(CASE WHEN pr.is_like = false THEN 1 ELSE 0 END) as dislikes_count,
-			(
-				SELECT COUNT(*)
-				FROM comments c
-				WHERE c.post_id = p.id
-			),
-			(
-				SELECT GROUP_CONCAT(t.name, ', ')
-				FROM tags t
-				LEFT JOIN posts_tags pt ON pt.tag_id = t.id
-				WHERE pt.post_id = p.id 
-			),
-			(
-				SELECT name
-				FROM images
-				WHERE post_id = p.id
-			)
-		FROM posts p
-		INNER JOIN users u ON p.user_id = u.id
-		LEFT JOIN post_reactions pr ON p.id = pr.post_id
-		LEFT JOIN comments cm ON p.id = cm.post_id
-		WHERE cm.user_id = $1
-		GROUP BY p.id 
+func (r *postRepository) GetAllCommentedPosts(userID int, page entity.PageRequest) (*[]entity.PostEntity, error) {
+	filter := `
+		p.id IN (
+			SELECT post_id
+			FROM comments
+			WHERE user_id = $1
+		)
 	`
 
-	return getAllPostsByQuery(r.DB, query, userID)
+	return getPostsPage(r.DB, filter, page, userID)
 }
 
 func (r *postRepository) Exists(postID int) (bool, error) {
@@ -337,13 +218,20 @@ func (r *postRepository) Delete(postID int, userID int) error {
 	return nil
 }
 
-func (r *postRepository) DeleteByPrivileged(postID int) error {
+// DeleteByPrivileged deletes the post and records it in moderation log
+func (r *postRepository) DeleteByPrivileged(postID int, e entity.ModerationEntry) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
 	query := `
 		DELETE FROM posts
 		WHERE id = $1
 	`
 
-	_, err := r.DB.Exec(query, postID)
+	_, err = tx.Exec(query, postID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return entity.ErrPostNotFound
@@ -351,7 +239,11 @@ func (r *postRepository) DeleteByPrivileged(postID int) error {
 		return err
 	}
 
-	return nil
+	if err := moderation.Append(tx, e); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
 func (r *postRepository) GetAuthorID(postID int) (int, error) {
@@ -383,7 +275,7 @@ func (r *postRepository) Update(p entity.PostCreateForm, tagIDs []int, deleteIma
 
 	posts := `
 		UPDATE posts
-		SET title = $1, content = $2
+		SET title = $1, content = $2, updated_at = datetime('now', 'localtime')
 		WHERE id = $3
 	`
 
@@ -434,9 +326,52 @@ func (r *postRepository) Update(p entity.PostCreateForm, tagIDs []int, deleteIma
 		}
 	}
 
+	if err = insertRevision(tx, p.ID, p.UserID); err != nil {
+		return err
+	}
+
 	if err = tx.Commit(); err != nil {
 		return err
 	}
 
 	return nil
 }
+
+func (r *postRepository) GetRevisions(postID int) (*[]entity.PostRevision, error) {
+	query := `
+		SELECT r.id, r.post_id, r.title, r.content, r.tags, r.image_name,
+			COALESCE(u.username, ''), r.created_at
+		FROM post_revisions r
+		LEFT JOIN users u ON u.id = r.user_id
+		WHERE r.post_id = $1
+		ORDER BY r.id
+	`
+
+	rows, err := r.DB.Query(query, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revisions []entity.PostRevision
+
+	for rows.Next() {
+		var rev entity.PostRevision
+		var imageName sql.NullString
+		if err := rows.Scan(&rev.ID, &rev.PostID, &rev.Title, &rev.Content, &rev.Tags,
+			&imageName, &rev.Editor, &rev.CreatedAt); err != nil {
+
+			return nil, err
+		}
+		if imageName.Valid {
+			rev.ImageName = imageName.String
+		}
+		revisions = append(revisions, rev)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &revisions, nil
+}
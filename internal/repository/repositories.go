@@ -3,29 +3,53 @@ package repository
 import (
 	"database/sql"
 	"forum/internal/repository/comment"
+	"forum/internal/repository/identity"
 	"forum/internal/repository/image"
+	"forum/internal/repository/lockout"
+	"forum/internal/repository/moderation"
 	"forum/internal/repository/post"
 	"forum/internal/repository/reaction"
+	"forum/internal/repository/sanction"
+	"forum/internal/repository/search"
+	"forum/internal/repository/setting"
 	"forum/internal/repository/tag"
+	"forum/internal/repository/token"
+	"forum/internal/repository/twofactor"
 	"forum/internal/repository/user"
 )
 
 type Repositories struct {
-	Post     post.IPostRepository
-	User     user.IUserRepository
-	Comment  comment.ICommentRepository
-	Reaction reaction.IReactionRepository
-	Tag      tag.ITagRepository
-	Image    image.IImageRepository
+	Post       post.IPostRepository
+	User       user.IUserRepository
+	Comment    comment.ICommentRepository
+	Reaction   reaction.IReactionRepository
+	Tag        tag.ITagRepository
+	Image      image.IImageRepository
+	Search     search.ISearchRepository
+	Token      token.ITokenRepository
+	Identity   identity.IIdentityRepository
+	TwoFactor  twofactor.ITwoFactorRepository
+	Setting    setting.ISettingRepository
+	Lockout    lockout.ILockoutRepository
+	Sanction   sanction.ISanctionRepository
+	Moderation moderation.IModerationRepository
 }
 
 func New(db *sql.DB) *Repositories {
 	return &Repositories{
-		Post:     post.NewPostRepo(db),
-		User:     user.NewUserRepo(db),
-		Comment:  comment.NewCommentRepo(db),
-		Reaction: reaction.NewReactionRepo(db),
-		Tag:      tag.NewTagRepo(db),
-		Image:    image.NewImageRepo(db),
+		Post:       post.NewPostRepo(db),
+		User:       user.NewUserRepo(db),
+		Comment:    comment.NewCommentRepo(db),
+		Reaction:   reaction.NewReactionRepo(db),
+		Tag:        tag.NewTagRepo(db),
+		Image:      image.NewImageRepo(db),
+		Search:     search.NewSearchRepo(db),
+		Token:      token.NewTokenRepo(db),
+		Identity:   identity.NewIdentityRepo(db),
+		TwoFactor:  twofactor.NewTwoFactorRepo(db),
+		Setting:    setting.NewSettingRepo(db),
+		Lockout:    lockout.NewLockoutRepo(db),
+		Sanction:   sanction.NewSanctionRepo(db),
+		Moderation: moderation.NewModerationRepo(db),
 	}
 }
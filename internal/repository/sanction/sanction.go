@@ -0,0 +1,181 @@
+package sanction
+
+import (
+	"database/sql"
+	"fmt"
+	"forum/internal/entity"
+	"forum/internal/repository/moderation"
+	"time"
+)
+
+type ISanctionRepository interface {
+	Insert(s entity.Sanction, d time.Duration, e entity.ModerationEntry) (int, error)
+	GetCurrentByUser(userID int) ([]entity.Sanction, error)
+	GetCurrent() ([]entity.Sanction, error)
+	Get(sanctionID int) (entity.Sanction, error)
+	Revoke(sanctionID int, e entity.ModerationEntry) error
+}
+
+type sanctionRepo struct {
+	DB *sql.DB
+}
+
+var _ ISanctionRepository = (*sanctionRepo)(nil)
+
+func NewSanctionRepo(db *sql.DB) *sanctionRepo {
+	return &sanctionRepo{
+		DB: db,
+	}
+}
+
+// Insert saves sanction that lasts d from now and records it in moderation
+// log, sanction with zero d never expires
+func (r *sanctionRepo) Insert(s entity.Sanction, d time.Duration, e entity.ModerationEntry) (int, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO user_sanctions (user_id, issued_by, kind, reason, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, datetime('now', 'localtime', $5), datetime('now', 'localtime'))
+	`
+
+	// datetime is NULL with NULL modifier
+	var expiry sql.NullString
+	if d > 0 {
+		expiry = sql.NullString{String: modifier(d), Valid: true}
+	}
+
+	res, err := tx.Exec(query, s.UserID, s.IssuedBy, s.Kind, s.Reason, expiry)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	if err := moderation.Append(tx, e); err != nil {
+		return 0, err
+	}
+
+	return int(id), tx.Commit()
+}
+
+// GetCurrentByUser returns sanctions of the user that are neither revoked
+// nor expired
+func (r *sanctionRepo) GetCurrentByUser(userID int) ([]entity.Sanction, error) {
+	query := `
+		SELECT s.id, s.user_id, COALESCE(s.issued_by, 0), COALESCE(u.username, ''), s.kind, s.reason, s.expires_at, s.created_at
+		FROM user_sanctions s
+		LEFT JOIN users u ON u.id = s.issued_by
+		WHERE s.user_id = $1 AND s.revoked_at IS NULL
+			AND (s.expires_at IS NULL OR s.expires_at > datetime('now', 'localtime'))
+		ORDER BY s.created_at DESC
+	`
+
+	return r.query(query, userID)
+}
+
+// GetCurrent returns sanctions of all users that are neither revoked nor
+// expired
+func (r *sanctionRepo) GetCurrent() ([]entity.Sanction, error) {
+	query := `
+		SELECT s.id, s.user_id, COALESCE(s.issued_by, 0), COALESCE(u.username, ''), s.kind, s.reason, s.expires_at, s.created_at
+		FROM user_sanctions s
+		LEFT JOIN users u ON u.id = s.issued_by
+		WHERE s.revoked_at IS NULL
+			AND (s.expires_at IS NULL OR s.expires_at > datetime('now', 'localtime'))
+		ORDER BY s.created_at DESC
+	`
+
+	return r.query(query)
+}
+
+// Get returns sanction that isn't revoked
+func (r *sanctionRepo) Get(sanctionID int) (entity.Sanction, error) {
+	query := `
+		SELECT s.id, s.user_id, COALESCE(s.issued_by, 0), COALESCE(u.username, ''), s.kind, s.reason, s.expires_at, s.created_at
+		FROM user_sanctions s
+		LEFT JOIN users u ON u.id = s.issued_by
+		WHERE s.id = $1 AND s.revoked_at IS NULL
+	`
+
+	sanctions, err := r.query(query, sanctionID)
+	if err != nil {
+		return entity.Sanction{}, err
+	}
+	if len(sanctions) == 0 {
+		return entity.Sanction{}, entity.ErrSanctionNotFound
+	}
+
+	return sanctions[0], nil
+}
+
+// Revoke ends sanction before it expires and records it in moderation log
+func (r *sanctionRepo) Revoke(sanctionID int, e entity.ModerationEntry) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE user_sanctions
+		SET revoked_at = datetime('now', 'localtime')
+		WHERE id = $1 AND revoked_at IS NULL
+	`
+
+	res, err := tx.Exec(query, sanctionID)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return entity.ErrSanctionNotFound
+	}
+
+	if err := moderation.Append(tx, e); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *sanctionRepo) query(query string, args ...any) ([]entity.Sanction, error) {
+	rows, err := r.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sanctions []entity.Sanction
+
+	for rows.Next() {
+		var s entity.Sanction
+		var expiresAt sql.NullTime
+
+		err := rows.Scan(&s.ID, &s.UserID, &s.IssuedBy, &s.IssuerName, &s.Kind, &s.Reason, &expiresAt, &s.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		s.ExpiresAt = expiresAt.Time
+
+		sanctions = append(sanctions, s)
+	}
+
+	return sanctions, rows.Err()
+}
+
+// modifier formats duration as sqlite datetime modifier
+func modifier(d time.Duration) string {
+	return fmt.Sprintf("%+d seconds", int(d.Seconds()))
+}
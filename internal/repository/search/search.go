@@ -0,0 +1,124 @@
+package search
+
+import (
+	"database/sql"
+	"fmt"
+	"forum/internal/entity"
+	"strings"
+)
+
+// Markers wrapping matched terms in snippets. Private use code points are
+// used, so they can't be confused with html and are escaped along with the
+// rest of the text before being replaced with highlight tags
+const (
+	MarkStart = "\uE000"
+	MarkEnd   = "\uE001"
+)
+
+// Weights of posts_fts columns (title, content) in bm25 ranking
+const (
+	titleWeight   = 10.0
+	contentWeight = 1.0
+)
+
+type ISearchRepository interface {
+	Search(q entity.SearchQuery, match string) (*[]entity.SearchResultEntity, error)
+}
+
+type searchRepo struct {
+	DB *sql.DB
+}
+
+var _ ISearchRepository = (*searchRepo)(nil)
+
+func NewSearchRepo(db *sql.DB) *searchRepo {
+	return &searchRepo{
+		DB: db,
+	}
+}
+
+// Search looks for posts and comments matching given FTS5 match expression.
+// Results of both kinds are merged and sorted by relevance (bm25 returns
+// smaller values for better matches)
+func (r *searchRepo) Search(q entity.SearchQuery, match string) (*[]entity.SearchResultEntity, error) {
+	args := []interface{}{match}
+
+	postFilters := buildFilters(q, "p.created_at", &args)
+	commentFilters := buildFilters(q, "c.created_at", &args)
+
+	query := fmt.Sprintf(`
+		SELECT p.id, 0, p.title,
+			snippet(posts_fts, -1, '%[1]s', '%[2]s', '…', 24),
+			u.username, p.created_at,
+			bm25(posts_fts, %[3]f, %[4]f) AS rank
+		FROM posts_fts
+		INNER JOIN posts p ON p.id = posts_fts.rowid
+		INNER JOIN users u ON u.id = p.user_id
+		WHERE posts_fts MATCH $1%[5]s
+		UNION ALL
+		SELECT p.id, c.id, p.title,
+			snippet(comments_fts, 0, '%[1]s', '%[2]s', '…', 24),
+			u.username, c.created_at,
+			bm25(comments_fts) AS rank
+		FROM comments_fts
+		INNER JOIN comments c ON c.id = comments_fts.rowid
+		INNER JOIN posts p ON p.id = c.post_id
+		INNER JOIN users u ON u.id = c.user_id
+		WHERE comments_fts MATCH $1%[6]s
+		ORDER BY rank
+		LIMIT %[7]d OFFSET %[8]d
+	`, MarkStart, MarkEnd, titleWeight, contentWeight, postFilters, commentFilters, q.Limit, q.Offset)
+
+	rows, err := r.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []entity.SearchResultEntity
+	for rows.Next() {
+		var res entity.SearchResultEntity
+		if err := rows.Scan(&res.PostID, &res.CommentID, &res.Title, &res.Snippet,
+			&res.Username, &res.CreatedAt, &res.Rank); err != nil {
+			return nil, err
+		}
+		results = append(results, res)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &results, nil
+}
+
+// buildFilters returns additional WHERE conditions for tag, author and date
+// filters of given query and appends their arguments to args. Placeholders
+// are numbered after already existing arguments
+func buildFilters(q entity.SearchQuery, dateColumn string, args *[]interface{}) string {
+	var conds []string
+
+	add := func(cond string, arg interface{}) {
+		*args = append(*args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(*args)))
+	}
+
+	if q.TagID != 0 {
+		add("p.id IN (SELECT post_id FROM posts_tags WHERE tag_id = $%d)", q.TagID)
+	}
+	if q.Author != "" {
+		add("u.username = $%d COLLATE NOCASE", q.Author)
+	}
+	if q.From != "" {
+		add(dateColumn+" >= date($%d)", q.From)
+	}
+	if q.To != "" {
+		add(dateColumn+" < date($%d, '+1 day')", q.To)
+	}
+
+	if len(conds) == 0 {
+		return ""
+	}
+
+	return " AND " + strings.Join(conds, " AND ")
+}
@@ -0,0 +1,70 @@
+package setting
+
+import (
+	"database/sql"
+	"errors"
+	"forum/internal/entity"
+	"forum/internal/repository/moderation"
+)
+
+type ISettingRepository interface {
+	Get(key string) (string, error)
+	Set(key, value string, e entity.ModerationEntry) error
+}
+
+type settingRepo struct {
+	DB *sql.DB
+}
+
+var _ ISettingRepository = (*settingRepo)(nil)
+
+func NewSettingRepo(db *sql.DB) *settingRepo {
+	return &settingRepo{
+		DB: db,
+	}
+}
+
+// Get returns value of setting or entity.ErrNoRecord if it was never set
+func (r *settingRepo) Get(key string) (string, error) {
+	query := `
+		SELECT value
+		FROM settings
+		WHERE key = $1
+	`
+
+	var value string
+
+	err := r.DB.QueryRow(query, key).Scan(&value)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", entity.ErrNoRecord
+		}
+		return "", err
+	}
+
+	return value, nil
+}
+
+// Set saves value of setting and records the change in moderation log
+func (r *settingRepo) Set(key, value string, e entity.ModerationEntry) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		REPLACE INTO settings (key, value)
+		VALUES ($1, $2)
+	`
+
+	if _, err := tx.Exec(query, key, value); err != nil {
+		return err
+	}
+
+	if err := moderation.Append(tx, e); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
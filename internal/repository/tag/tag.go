@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"errors"
 	"forum/internal/entity"
+	"forum/internal/repository/moderation"
 	"strings"
 	"sync"
 
@@ -14,8 +15,9 @@ type ITagRepository interface {
 	GetAll() (*[]entity.TagEntity, error)
 	AreTagsExist([]int) (bool, error)
 	IsExist(int) (bool, error)
-	Delete(tagID int) error
-	Create(tag string) error
+	Get(tagID int) (entity.TagEntity, error)
+	Delete(tagID int, e entity.ModerationEntry) error
+	Create(tag string, e entity.ModerationEntry) error
 }
 
 type tagRepo struct {
@@ -112,13 +114,40 @@ func (r *tagRepo) IsExist(id int) (bool, error) {
 	return exists, err
 }
 
-func (r *tagRepo) Delete(tagID int) error {
+func (r *tagRepo) Get(tagID int) (entity.TagEntity, error) {
+	query := `
+		SELECT id, name, created_at
+		FROM tags
+		WHERE id = $1
+	`
+
+	var tag entity.TagEntity
+
+	err := r.DB.QueryRow(query, tagID).Scan(&tag.ID, &tag.Name, &tag.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return entity.TagEntity{}, entity.ErrTagNotFound
+		}
+		return entity.TagEntity{}, err
+	}
+
+	return tag, nil
+}
+
+// Delete deletes the tag and records it in moderation log
+func (r *tagRepo) Delete(tagID int, e entity.ModerationEntry) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
 	query := `
 		DELETE FROM tags
 		WHERE id = $1
 	`
 
-	res, err := r.DB.Exec(query, tagID)
+	res, err := tx.Exec(query, tagID)
 	if err != nil {
 		return err
 	}
@@ -132,16 +161,28 @@ func (r *tagRepo) Delete(tagID int) error {
 		return entity.ErrTagNotFound
 	}
 
-	return nil
+	if err := moderation.Append(tx, e); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-func (r *tagRepo) Create(tag string) error {
+// Create saves the tag and records it in moderation log with id of the new
+// tag as target
+func (r *tagRepo) Create(tag string, e entity.ModerationEntry) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
 	query := `
 		INSERT INTO tags (name, created_at)
 		VALUES ($1, datetime('now', 'localtime'))
 	`
 
-	_, err := r.DB.Exec(query, tag)
+	res, err := tx.Exec(query, tag)
 	if err != nil {
 		var sqlite3Err sqlite3.Error
 		if errors.As(err, &sqlite3Err) {
@@ -152,5 +193,15 @@ func (r *tagRepo) Create(tag string) error {
 		return err
 	}
 
-	return nil
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	e.TargetID = int(id)
+	if err := moderation.Append(tx, e); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
@@ -0,0 +1,118 @@
+package token
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"forum/internal/entity"
+	"time"
+)
+
+type ITokenRepository interface {
+	Insert(userID int, tokenHash, purpose string, ttl time.Duration) error
+	GetUserID(tokenHash, purpose string) (int, error)
+	Consume(tokenHash, purpose string) (int, error)
+	DeleteAll(userID int, purpose string) error
+	ExistsSince(userID int, purpose string, since time.Duration) (bool, error)
+}
+
+type tokenRepo struct {
+	DB *sql.DB
+}
+
+var _ ITokenRepository = (*tokenRepo)(nil)
+
+func NewTokenRepo(db *sql.DB) *tokenRepo {
+	return &tokenRepo{
+		DB: db,
+	}
+}
+
+func (r *tokenRepo) Insert(userID int, tokenHash, purpose string, ttl time.Duration) error {
+	query := `
+		INSERT INTO user_tokens (user_id, token_hash, purpose, expiry, created_at)
+		VALUES ($1, $2, $3, datetime('now', 'localtime', $4), datetime('now', 'localtime'))
+	`
+
+	_, err := r.DB.Exec(query, userID, tokenHash, purpose, modifier(ttl))
+	return err
+}
+
+// GetUserID returns id of user that owns token if it's neither used nor expired
+func (r *tokenRepo) GetUserID(tokenHash, purpose string) (int, error) {
+	query := `
+		SELECT user_id
+		FROM user_tokens
+		WHERE token_hash = $1 AND purpose = $2
+			AND used_at IS NULL AND expiry > datetime('now', 'localtime')
+	`
+
+	var userID int
+
+	err := r.DB.QueryRow(query, tokenHash, purpose).Scan(&userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, entity.ErrInvalidToken
+		}
+		return 0, err
+	}
+
+	return userID, nil
+}
+
+// Consume marks token as used and returns id of its owner. Token that is
+// already used or expired can't be consumed
+func (r *tokenRepo) Consume(tokenHash, purpose string) (int, error) {
+	query := `
+		UPDATE user_tokens
+		SET used_at = datetime('now', 'localtime')
+		WHERE token_hash = $1 AND purpose = $2
+			AND used_at IS NULL AND expiry > datetime('now', 'localtime')
+		RETURNING user_id
+	`
+
+	var userID int
+
+	err := r.DB.QueryRow(query, tokenHash, purpose).Scan(&userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, entity.ErrInvalidToken
+		}
+		return 0, err
+	}
+
+	return userID, nil
+}
+
+func (r *tokenRepo) DeleteAll(userID int, purpose string) error {
+	query := `
+		DELETE FROM user_tokens
+		WHERE user_id = $1 AND purpose = $2
+	`
+
+	_, err := r.DB.Exec(query, userID, purpose)
+	return err
+}
+
+// ExistsSince checks if token with given purpose was created for user during
+// the last since duration
+func (r *tokenRepo) ExistsSince(userID int, purpose string, since time.Duration) (bool, error) {
+	query := `
+		SELECT EXISTS(
+			SELECT true
+			FROM user_tokens
+			WHERE user_id = $1 AND purpose = $2
+				AND created_at > datetime('now', 'localtime', $3)
+		)
+	`
+
+	var exists bool
+
+	err := r.DB.QueryRow(query, userID, purpose, modifier(-since)).Scan(&exists)
+	return exists, err
+}
+
+// modifier formats duration as sqlite datetime modifier
+func modifier(d time.Duration) string {
+	return fmt.Sprintf("%+d seconds", int(d.Seconds()))
+}
@@ -0,0 +1,254 @@
+package twofactor
+
+import (
+	"database/sql"
+	"errors"
+	"forum/internal/entity"
+	"forum/internal/repository/moderation"
+)
+
+type ITwoFactorRepository interface {
+	Get(userID int) (entity.TwoFactor, error)
+	Upsert(userID int, secret string) error
+	Confirm(userID int, counter int64, codeHashes []string) error
+	UpdateCounter(userID int, counter int64) error
+	ReplaceRecoveryCodes(userID int, codeHashes []string) error
+	ConsumeRecoveryCode(userID int, codeHash string) error
+	CountRecoveryCodes(userID int) (int, error)
+	Delete(userID int) error
+	Reset(userID int, e entity.ModerationEntry) error
+}
+
+type twoFactorRepo struct {
+	DB *sql.DB
+}
+
+var _ ITwoFactorRepository = (*twoFactorRepo)(nil)
+
+func NewTwoFactorRepo(db *sql.DB) *twoFactorRepo {
+	return &twoFactorRepo{
+		DB: db,
+	}
+}
+
+func (r *twoFactorRepo) Get(userID int) (entity.TwoFactor, error) {
+	query := `
+		SELECT user_id, secret, last_counter, confirmed_at, created_at
+		FROM user_totp
+		WHERE user_id = $1
+	`
+
+	var tf entity.TwoFactor
+	var confirmedAt sql.NullTime
+
+	err := r.DB.QueryRow(query, userID).Scan(&tf.UserID, &tf.Secret, &tf.LastCounter, &confirmedAt, &tf.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return entity.TwoFactor{}, entity.ErrTwoFactorDisabled
+		}
+		return entity.TwoFactor{}, err
+	}
+	tf.ConfirmedAt = confirmedAt.Time
+
+	return tf, nil
+}
+
+// Upsert saves secret of new enrolment. Confirmed secret isn't replaced
+func (r *twoFactorRepo) Upsert(userID int, secret string) error {
+	query := `
+		INSERT INTO user_totp (user_id, secret, created_at)
+		VALUES ($1, $2, datetime('now', 'localtime'))
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = excluded.secret, last_counter = 0, created_at = excluded.created_at
+		WHERE confirmed_at IS NULL
+	`
+
+	result, err := r.DB.Exec(query, userID, secret)
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return entity.ErrTwoFactorEnabled
+	}
+
+	return nil
+}
+
+// Confirm enables two-factor authentication and saves recovery codes
+func (r *twoFactorRepo) Confirm(userID int, counter int64, codeHashes []string) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE user_totp
+		SET confirmed_at = datetime('now', 'localtime'), last_counter = $1
+		WHERE user_id = $2 AND confirmed_at IS NULL
+	`
+
+	result, err := tx.Exec(query, counter, userID)
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return entity.ErrTwoFactorEnabled
+	}
+
+	if err := replaceRecoveryCodes(tx, userID, codeHashes); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UpdateCounter saves counter of accepted code. Counter that isn't greater
+// than saved one means that code was already used
+func (r *twoFactorRepo) UpdateCounter(userID int, counter int64) error {
+	query := `
+		UPDATE user_totp
+		SET last_counter = $1
+		WHERE user_id = $2 AND last_counter < $1
+	`
+
+	result, err := r.DB.Exec(query, counter, userID)
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return entity.ErrInvalidPasscode
+	}
+
+	return nil
+}
+
+func (r *twoFactorRepo) ReplaceRecoveryCodes(userID int, codeHashes []string) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(tx, userID, codeHashes); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ConsumeRecoveryCode marks recovery code as used
+func (r *twoFactorRepo) ConsumeRecoveryCode(userID int, codeHash string) error {
+	query := `
+		UPDATE recovery_codes
+		SET used_at = datetime('now', 'localtime')
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`
+
+	result, err := r.DB.Exec(query, userID, codeHash)
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return entity.ErrInvalidPasscode
+	}
+
+	return nil
+}
+
+// CountRecoveryCodes returns number of unused recovery codes
+func (r *twoFactorRepo) CountRecoveryCodes(userID int) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM recovery_codes
+		WHERE user_id = $1 AND used_at IS NULL
+	`
+
+	var n int
+	err := r.DB.QueryRow(query, userID).Scan(&n)
+	return n, err
+}
+
+// Delete removes secret and recovery codes of the user
+func (r *twoFactorRepo) Delete(userID int) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := deleteSecret(tx, userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Reset removes secret and recovery codes of the user like Delete and
+// records it in moderation log
+func (r *twoFactorRepo) Reset(userID int, e entity.ModerationEntry) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := deleteSecret(tx, userID); err != nil {
+		return err
+	}
+
+	if err := moderation.Append(tx, e); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func deleteSecret(tx *sql.Tx, userID int) error {
+	if _, err := tx.Exec(`DELETE FROM recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`DELETE FROM user_totp WHERE user_id = $1`, userID)
+	return err
+}
+
+// replaceRecoveryCodes deletes old recovery codes of the user, so only the
+// last generated ones can be used
+func replaceRecoveryCodes(tx *sql.Tx, userID int, codeHashes []string) error {
+	if _, err := tx.Exec(`DELETE FROM recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO recovery_codes (user_id, code_hash, created_at)
+		VALUES ($1, $2, datetime('now', 'localtime'))
+	`
+
+	for _, hash := range codeHashes {
+		if _, err := tx.Exec(query, userID, hash); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
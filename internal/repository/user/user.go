@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"forum/internal/entity"
+	"forum/internal/repository/moderation"
 	"strings"
 
 	"github.com/mattn/go-sqlite3"
@@ -14,6 +15,7 @@ type IUserRepository interface {
 	Insert(user entity.UserSignupForm, hashedPassword []byte) (int, error)
 	GetByUsername(username string) (entity.UserEntity, error)
 	GetByEmail(email string) (entity.UserEntity, error)
+	GetByID(userID int) (entity.UserEntity, error)
 	GetUsernameByID(userID int) (string, error)
 	GetRole(userID int) (string, error)
 	CreateNotification(n entity.Notification) error
@@ -21,15 +23,22 @@ type IUserRepository interface {
 	CreateReport(report entity.Report) error
 	DeleteReport(reportID int) error
 	DeletePromotion(promotionID int) error
+	RejectReport(reportID int, e entity.ModerationEntry) error
+	RejectPromotion(promotionID int, e entity.ModerationEntry) error
 	GetNotifications(userID int) (*[]entity.Notification, error)
 	DeleteNotification(notificationID int) error
 	GetRequests() (*[]entity.Request, error)
 	GetReports() (*[]entity.Report, error)
-	Promote(userID int) error
-	Demote(userID int) error
+	GetPromotion(promotionID int) (entity.Request, error)
+	GetReport(reportID int) (entity.Report, error)
+	Promote(userID int, role string, e entity.ModerationEntry) error
+	SetRole(userID int, role string, e entity.ModerationEntry) error
 	GetUsers() (*[]entity.UserEntity, error)
 	FindNotification(nType string, userFrom, userTo int) (int, error)
 	GetNotificationsCount(userID int) (int, error)
+	UpdatePassword(userID int, hashedPassword []byte) error
+	SetVerified(userID int) error
+	UpdateEmail(userID int, email string) error
 }
 
 type userRepository struct {
@@ -102,6 +111,10 @@ func (r *userRepository) GetByUsername(username string) (entity.UserEntity, erro
 	return r.getUserByField("username", username)
 }
 
+func (r *userRepository) GetByID(userID int) (entity.UserEntity, error) {
+	return r.getUserByField("id", userID)
+}
+
 func (r *userRepository) GetUsernameByID(userID int) (string, error) {
 	user, err := r.getUserByField("id", userID)
 	return user.Username, err
@@ -109,16 +122,22 @@ func (r *userRepository) GetUsernameByID(userID int) (string, error) {
 
 func (r *userRepository) getUserByField(field string, value interface{}) (entity.UserEntity, error) {
 	var u entity.UserEntity
+	var verifiedAt sql.NullTime
 
-	query := fmt.Sprintf(`SELECT * FROM users WHERE %s = $1 COLLATE NOCASE`, field)
+	query := fmt.Sprintf(`
+		SELECT id, username, email, hashed_password, created_at, verified_at
+		FROM users
+		WHERE %s = $1 COLLATE NOCASE
+	`, field)
 
-	err := r.DB.QueryRow(query, value).Scan(&u.ID, &u.Username, &u.Email, &u.Password, &u.CreatedAt)
+	err := r.DB.QueryRow(query, value).Scan(&u.ID, &u.Username, &u.Email, &u.Password, &u.CreatedAt, &verifiedAt)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return entity.UserEntity{}, entity.ErrInvalidCredentials
 		}
 		return entity.UserEntity{}, err
 	}
+	u.VerifiedAt = verifiedAt.Time
 
 	return u, nil
 }
@@ -134,6 +153,9 @@ func (r *userRepository) GetRole(userID int) (string, error) {
 
 	err := r.DB.QueryRow(query, userID).Scan(&role)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", entity.ErrUserNotFound
+		}
 		return "", err
 	}
 
@@ -173,11 +195,11 @@ func (r *userRepository) CreatePromotion(userID int) error {
 
 func (r *userRepository) CreateReport(report entity.Report) error {
 	query := `
-		INSERT INTO reports (reason, user_from, source_id, source_type, created_at)
-		VALUES ($1, $2, $3, $4, datetime('now', 'localtime'))
+		INSERT INTO reports (reason, user_from, source_id, source_type, comment_id, created_at)
+		VALUES ($1, $2, $3, $4, NULLIF($5, 0), datetime('now', 'localtime'))
 	`
 
-	_, err := r.DB.Exec(query, report.Reason, report.UserFrom, report.SourceID, report.SourceType)
+	_, err := r.DB.Exec(query, report.Reason, report.UserFrom, report.SourceID, report.SourceType, report.CommentID)
 	if err != nil {
 		var sqliteError sqlite3.Error
 		if errors.As(err, &sqliteError) {
@@ -192,12 +214,64 @@ func (r *userRepository) CreateReport(report entity.Report) error {
 }
 
 func (r *userRepository) DeleteReport(reportID int) error {
+	return deleteReport(r.DB, reportID)
+}
+
+func (r *userRepository) DeletePromotion(promotionID int) error {
+	return deletePromotion(r.DB, promotionID)
+}
+
+// RejectReport deletes report and records it in moderation log
+func (r *userRepository) RejectReport(reportID int, e entity.ModerationEntry) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := deleteReport(tx, reportID); err != nil {
+		return err
+	}
+
+	if err := moderation.Append(tx, e); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// RejectPromotion deletes request for promotion and records it in moderation
+// log
+func (r *userRepository) RejectPromotion(promotionID int, e entity.ModerationEntry) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := deletePromotion(tx, promotionID); err != nil {
+		return err
+	}
+
+	if err := moderation.Append(tx, e); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// execer is implemented by both sql.DB and sql.Tx
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+func deleteReport(db execer, reportID int) error {
 	query := `
 		DELETE FROM reports
 		WHERE id = $1
 	`
 
-	res, err := r.DB.Exec(query, reportID)
+	res, err := db.Exec(query, reportID)
 	if err != nil {
 		return err
 	}
@@ -214,13 +288,13 @@ func (r *userRepository) DeleteReport(reportID int) error {
 	return nil
 }
 
-func (r *userRepository) DeletePromotion(promotionID int) error {
+func deletePromotion(db execer, promotionID int) error {
 	query := `
 		DELETE FROM requests
 		WHERE id = $1
 	`
 
-	res, err := r.DB.Exec(query, promotionID)
+	res, err := db.Exec(query, promotionID)
 	if err != nil {
 		return err
 	}
@@ -331,7 +405,7 @@ func (r *userRepository) GetRequests() (*[]entity.Request, error) {
 
 func (r *userRepository) GetReports() (*[]entity.Report, error) {
 	query := `
-		SELECT r.id, r.reason, r.user_from, r.source_id, r.source_type, r.created_at, u.username
+		SELECT r.id, r.reason, r.user_from, r.source_id, r.source_type, r.comment_id, r.created_at, u.username
 		FROM reports r
 		INNER JOIN users u ON u.id = r.user_from
 		ORDER BY r.created_at DESC
@@ -347,23 +421,77 @@ func (r *userRepository) GetReports() (*[]entity.Report, error) {
 
 	for rows.Next() {
 		var r entity.Report
-		if err := rows.Scan(&r.ID, &r.Reason, &r.UserFrom, &r.SourceID, &r.SourceType, &r.CreatedAt, &r.Username); err != nil {
+		var commentID sql.NullInt64
+		if err := rows.Scan(&r.ID, &r.Reason, &r.UserFrom, &r.SourceID, &r.SourceType, &commentID, &r.CreatedAt, &r.Username); err != nil {
 			return nil, err
 		}
+		r.CommentID = int(commentID.Int64)
 		reports = append(reports, r)
 	}
 
 	return &reports, nil
 }
 
-func (r *userRepository) Promote(userID int) error {
+func (r *userRepository) GetPromotion(promotionID int) (entity.Request, error) {
+	query := `
+		SELECT r.id, r.user_id, r.created_at, u.username
+		FROM requests r
+		INNER JOIN users u ON r.user_id = u.id
+		WHERE r.id = $1
+	`
+
+	var req entity.Request
+
+	err := r.DB.QueryRow(query, promotionID).Scan(&req.ID, &req.UserID, &req.CreatedAt, &req.Username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return entity.Request{}, entity.ErrPromotionNotFound
+		}
+		return entity.Request{}, err
+	}
+
+	return req, nil
+}
+
+func (r *userRepository) GetReport(reportID int) (entity.Report, error) {
+	query := `
+		SELECT r.id, r.reason, r.user_from, r.source_id, r.source_type, r.comment_id, r.created_at, u.username
+		FROM reports r
+		INNER JOIN users u ON u.id = r.user_from
+		WHERE r.id = $1
+	`
+
+	var report entity.Report
+	var commentID sql.NullInt64
+
+	err := r.DB.QueryRow(query, reportID).Scan(&report.ID, &report.Reason, &report.UserFrom, &report.SourceID, &report.SourceType, &commentID, &report.CreatedAt, &report.Username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return entity.Report{}, entity.ErrReportNotFound
+		}
+		return entity.Report{}, err
+	}
+	report.CommentID = int(commentID.Int64)
+
+	return report, nil
+}
+
+// Promote gives the user the role, deletes requests of the user for
+// promotion and records it in moderation log
+func (r *userRepository) Promote(userID int, role string, e entity.ModerationEntry) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
 	query := `
 		UPDATE roles
 		SET role = $1
 		WHERE user_id = $2
 	`
 
-	res, err := r.DB.Exec(query, entity.MODERATOR, userID)
+	res, err := tx.Exec(query, role, userID)
 	if err != nil {
 		return err
 	}
@@ -377,17 +505,37 @@ func (r *userRepository) Promote(userID int) error {
 		return entity.ErrUserNotFound
 	}
 
-	return err
+	query = `
+		DELETE FROM requests
+		WHERE user_id = $1
+	`
+
+	if _, err := tx.Exec(query, userID); err != nil {
+		return err
+	}
+
+	if err := moderation.Append(tx, e); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-func (r *userRepository) Demote(userID int) error {
+// SetRole changes role of the user and records it in moderation log
+func (r *userRepository) SetRole(userID int, role string, e entity.ModerationEntry) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
 	query := `
 		UPDATE roles
 		SET role = $1
 		WHERE user_id = $2
 	`
 
-	res, err := r.DB.Exec(query, entity.USER, userID)
+	res, err := tx.Exec(query, role, userID)
 	if err != nil {
 		return err
 	}
@@ -401,7 +549,92 @@ func (r *userRepository) Demote(userID int) error {
 		return entity.ErrUserNotFound
 	}
 
-	return err
+	if err := moderation.Append(tx, e); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *userRepository) UpdatePassword(userID int, hashedPassword []byte) error {
+	query := `
+		UPDATE users
+		SET hashed_password = $1
+		WHERE id = $2
+	`
+
+	res, err := r.DB.Exec(query, string(hashedPassword), userID)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return entity.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// UpdateEmail changes email address of the user, new address isn't verified
+func (r *userRepository) UpdateEmail(userID int, email string) error {
+	query := `
+		UPDATE users
+		SET email = $1, verified_at = NULL
+		WHERE id = $2
+	`
+
+	res, err := r.DB.Exec(query, email, userID)
+	if err != nil {
+		var sqliteError sqlite3.Error
+		if errors.As(err, &sqliteError) {
+			if sqliteError.Code == 19 && strings.Contains(sqliteError.Error(), "users.email") {
+				return entity.ErrDuplicateEmail
+			}
+		}
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return entity.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// SetVerified marks email address of user as verified. Time of the first
+// verification is kept
+func (r *userRepository) SetVerified(userID int) error {
+	query := `
+		UPDATE users
+		SET verified_at = COALESCE(verified_at, datetime('now', 'localtime'))
+		WHERE id = $1
+	`
+
+	res, err := r.DB.Exec(query, userID)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return entity.ErrUserNotFound
+	}
+
+	return nil
 }
 
 func (r *userRepository) GetUsers() (*[]entity.UserEntity, error) {
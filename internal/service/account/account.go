@@ -0,0 +1,246 @@
+package account
+
+import (
+	"errors"
+	"fmt"
+	"forum/internal/entity"
+	"forum/internal/repository/token"
+	"forum/internal/repository/user"
+	"forum/pkg/mailer"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	resetTokenTTL        = time.Hour
+	verificationTokenTTL = 24 * time.Hour
+
+	// sendInterval is the minimum time between two emails with tokens of
+	// the same purpose sent to the same user
+	sendInterval = time.Minute
+)
+
+type IAccountService interface {
+	RequestPasswordReset(form *entity.ForgotPasswordForm) error
+	CheckResetToken(token string) error
+	ResetPassword(form *entity.ResetPasswordForm) (int, error)
+	SendVerification(userID int) error
+	ChangeEmail(userID int, form *entity.ChangeEmailForm) error
+	VerifyEmail(token string) (int, error)
+	MarkVerified(userID int) error
+	IsVerified(userID int) (bool, error)
+}
+
+type accountService struct {
+	tokenRepo token.ITokenRepository
+	userRepo  user.IUserRepository
+	mailer    mailer.Mailer
+	baseURL   string
+}
+
+func NewAccountService(t token.ITokenRepository, u user.IUserRepository, m mailer.Mailer, baseURL string) *accountService {
+	return &accountService{
+		tokenRepo: t,
+		userRepo:  u,
+		mailer:    m,
+		baseURL:   baseURL,
+	}
+}
+
+var _ IAccountService = (*accountService)(nil)
+
+// RequestPasswordReset sends link with reset token to the user with given
+// email. It doesn't tell whether such user exists, so unknown emails and
+// repeated requests are silently ignored
+func (as *accountService) RequestPasswordReset(form *entity.ForgotPasswordForm) error {
+	if !IsRightForgot(form) {
+		return entity.ErrInvalidFormData
+	}
+
+	u, err := as.userRepo.GetByEmail(form.Email)
+	if err != nil {
+		if errors.Is(err, entity.ErrInvalidCredentials) {
+			return nil
+		}
+		return err
+	}
+
+	raw, err := as.issueToken(u.ID, entity.TOKEN_PASSWORD_RESET, resetTokenTTL)
+	if err != nil {
+		if errors.Is(err, entity.ErrTokenRecentlySent) {
+			return nil
+		}
+		return err
+	}
+
+	link := as.baseURL + "/user/reset?token=" + url.QueryEscape(raw)
+
+	return as.mailer.Send(mailer.Message{
+		To:      u.Email,
+		Subject: "Password reset",
+		Body: fmt.Sprintf("Hello, %s!\n\n"+
+			"Someone requested a password reset for your account. Follow the link to choose a new password:\n\n"+
+			"%s\n\n"+
+			"The link can be used once and expires in %d minutes. If you didn't request a reset, ignore this email, your password stays the same.\n",
+			u.Username, link, int(resetTokenTTL.Minutes())),
+	})
+}
+
+// CheckResetToken returns entity.ErrInvalidToken if token is unknown, used or expired
+func (as *accountService) CheckResetToken(token string) error {
+	_, err := as.tokenRepo.GetUserID(hashToken(token), entity.TOKEN_PASSWORD_RESET)
+	return err
+}
+
+// ResetPassword sets new password of the user that owns reset token and
+// returns id of that user. Token can't be used again after that
+func (as *accountService) ResetPassword(form *entity.ResetPasswordForm) (int, error) {
+	if !IsRightReset(form) {
+		return 0, entity.ErrInvalidFormData
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(form.Password), 12)
+	if err != nil {
+		return 0, err
+	}
+
+	userID, err := as.tokenRepo.Consume(hashToken(form.Token), entity.TOKEN_PASSWORD_RESET)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := as.userRepo.UpdatePassword(userID, hashedPassword); err != nil {
+		return 0, err
+	}
+
+	return userID, nil
+}
+
+// SendVerification sends link that confirms email address of the user.
+// It returns entity.ErrTokenRecentlySent if link was sent less than a
+// minute ago
+func (as *accountService) SendVerification(userID int) error {
+	u, err := as.userRepo.GetByID(userID)
+	if err != nil {
+		if errors.Is(err, entity.ErrInvalidCredentials) {
+			return entity.ErrUserNotFound
+		}
+		return err
+	}
+	if !u.VerifiedAt.IsZero() {
+		return entity.ErrAlreadyVerified
+	}
+
+	raw, err := as.issueToken(u.ID, entity.TOKEN_EMAIL_VERIFICATION, verificationTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	link := as.baseURL + "/user/verify?token=" + url.QueryEscape(raw)
+
+	return as.mailer.Send(mailer.Message{
+		To:      u.Email,
+		Subject: "Confirm your email address",
+		Body: fmt.Sprintf("Hello, %s!\n\n"+
+			"Follow the link to confirm your email address. You can create posts and comments after that:\n\n"+
+			"%s\n\n"+
+			"The link expires in %d hours. If you didn't sign up, ignore this email.\n",
+			u.Username, link, int(verificationTokenTTL.Hours())),
+	})
+}
+
+// ChangeEmail replaces email address of the user and sends link that
+// confirms the new one. Posts and comments can't be created until it's
+// confirmed. It returns entity.ErrTokenRecentlySent without changing the
+// address if link was sent less than a minute ago
+func (as *accountService) ChangeEmail(userID int, form *entity.ChangeEmailForm) error {
+	form.Email = strings.TrimSpace(form.Email)
+	if !IsRightEmail(form) {
+		return entity.ErrInvalidFormData
+	}
+
+	sent, err := as.tokenRepo.ExistsSince(userID, entity.TOKEN_EMAIL_VERIFICATION, sendInterval)
+	if err != nil {
+		return err
+	}
+	if sent {
+		return entity.ErrTokenRecentlySent
+	}
+
+	if err := as.userRepo.UpdateEmail(userID, form.Email); err != nil {
+		if errors.Is(err, entity.ErrDuplicateEmail) {
+			form.AddFieldError("email", "Email address is already in use")
+		}
+		return err
+	}
+
+	return as.SendVerification(userID)
+}
+
+// VerifyEmail marks email address of the token owner as verified and
+// returns id of that user
+func (as *accountService) VerifyEmail(token string) (int, error) {
+	userID, err := as.tokenRepo.Consume(hashToken(token), entity.TOKEN_EMAIL_VERIFICATION)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := as.MarkVerified(userID); err != nil {
+		return 0, err
+	}
+
+	return userID, nil
+}
+
+// MarkVerified verifies email address of the user without a token. It's used
+// when address is confirmed otherwise (external auth or created by admin)
+func (as *accountService) MarkVerified(userID int) error {
+	if err := as.userRepo.SetVerified(userID); err != nil {
+		return err
+	}
+
+	// Links that were sent earlier aren't needed anymore
+	return as.tokenRepo.DeleteAll(userID, entity.TOKEN_EMAIL_VERIFICATION)
+}
+
+func (as *accountService) IsVerified(userID int) (bool, error) {
+	u, err := as.userRepo.GetByID(userID)
+	if err != nil {
+		if errors.Is(err, entity.ErrInvalidCredentials) {
+			return false, entity.ErrUserNotFound
+		}
+		return false, err
+	}
+
+	return !u.VerifiedAt.IsZero(), nil
+}
+
+// issueToken creates new token for the user and returns it. Previous tokens
+// with the same purpose become invalid, so only the latest link works
+func (as *accountService) issueToken(userID int, purpose string, ttl time.Duration) (string, error) {
+	sent, err := as.tokenRepo.ExistsSince(userID, purpose, sendInterval)
+	if err != nil {
+		return "", err
+	}
+	if sent {
+		return "", entity.ErrTokenRecentlySent
+	}
+
+	if err := as.tokenRepo.DeleteAll(userID, purpose); err != nil {
+		return "", err
+	}
+
+	raw, hash, err := newToken()
+	if err != nil {
+		return "", err
+	}
+
+	if err := as.tokenRepo.Insert(userID, hash, purpose, ttl); err != nil {
+		return "", err
+	}
+
+	return raw, nil
+}
@@ -0,0 +1,63 @@
+package account
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"forum/internal/entity"
+	"forum/internal/service/user"
+	"forum/internal/validator"
+)
+
+const (
+	maxEmailLen    = 255
+	minPasswordLen = 8
+	maxPasswordLen = 500
+)
+
+// newToken returns random token that is sent to user and its hash that is
+// stored in database
+func newToken() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	return token, hashToken(token), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func IsRightForgot(f *entity.ForgotPasswordForm) bool {
+	f.CheckField(validator.NotBlank(f.Email), "email", "This field cannot be blank")
+	f.CheckField(validator.MaxChar(f.Email, maxEmailLen), "email", fmt.Sprintf("Maximum characters length exceeded - %d", maxEmailLen))
+	f.CheckField(validator.Matches(f.Email, user.EmailRX), "email", "Invalid email address")
+
+	return f.Valid()
+}
+
+func IsRightEmail(f *entity.ChangeEmailForm) bool {
+	f.CheckField(validator.NotBlank(f.Email), "email", "This field cannot be blank")
+	f.CheckField(validator.MaxChar(f.Email, maxEmailLen), "email", fmt.Sprintf("Maximum characters length exceeded - %d", maxEmailLen))
+	f.CheckField(validator.Matches(f.Email, user.EmailRX), "email", "Invalid email address")
+	f.CheckField(validator.ValidString(f.Email), "email", "Only valid characters (ascii standard) should be included")
+
+	return f.Valid()
+}
+
+func IsRightReset(f *entity.ResetPasswordForm) bool {
+	f.CheckField(validator.NotBlank(f.Password), "password", "This field cannot be blank")
+	f.CheckField(validator.MinChar(f.Password, minPasswordLen), "password", fmt.Sprintf("Minimum length for password: %d", minPasswordLen))
+	f.CheckField(validator.MaxChar(f.Password, maxPasswordLen), "password", fmt.Sprintf("Maximum characters length exceeded - %d", maxPasswordLen))
+	f.CheckField(validator.ValidString(f.Password), "password", "Only valid characters (ascii standard) should be included")
+	f.CheckField(f.Password == f.ConfirmPassword, "confirmPassword", "Passwords don't match")
+
+	return f.Valid()
+}
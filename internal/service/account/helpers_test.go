@@ -0,0 +1,75 @@
+package account
+
+import (
+	"forum/internal/assert"
+	"forum/internal/entity"
+	"strings"
+	"testing"
+)
+
+func TestNewToken(t *testing.T) {
+	token, hash, err := newToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, len(token), 43)
+	assert.Equal(t, hash, hashToken(token))
+
+	other, _, err := newToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, other != token, true)
+}
+
+func TestIsRightReset(t *testing.T) {
+	tests := []struct {
+		name    string
+		form    entity.ResetPasswordForm
+		invalid []string
+	}{
+		{
+			name: "Valid",
+			form: entity.ResetPasswordForm{Password: "password1", ConfirmPassword: "password1"},
+		},
+		{
+			name:    "Too short",
+			form:    entity.ResetPasswordForm{Password: "short", ConfirmPassword: "short"},
+			invalid: []string{"password"},
+		},
+		{
+			name:    "Mismatch",
+			form:    entity.ResetPasswordForm{Password: "password1", ConfirmPassword: "password2"},
+			invalid: []string{"confirmPassword"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, IsRightReset(&tt.form), len(tt.invalid) == 0)
+			assert.Equal(t, len(tt.form.FieldErrors), len(tt.invalid))
+			for _, field := range tt.invalid {
+				_, ok := tt.form.FieldErrors[field]
+				assert.Equal(t, ok, true)
+			}
+		})
+	}
+}
+
+func TestIsRightEmail(t *testing.T) {
+	tests := []struct {
+		email string
+		want  bool
+	}{
+		{"bob@example.com", true},
+		{"", false},
+		{"bob", false},
+		{"bob@" + strings.Repeat("a", 255) + ".com", false},
+	}
+
+	for _, tt := range tests {
+		f := entity.ChangeEmailForm{Email: tt.email}
+		assert.Equal(t, IsRightEmail(&f), tt.want)
+	}
+}
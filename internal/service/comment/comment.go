@@ -1,22 +1,27 @@
 package comment
 
 import (
+	"errors"
 	"forum/internal/entity"
 	"forum/internal/repository/comment"
+	"forum/internal/service/moderation"
 	"forum/internal/service/user"
 )
 
 type ICommentService interface {
-	SaveComment(*entity.CommentCreateForm, int, int) error
+	SaveComment(*entity.CommentCreateForm, int, int) (int, error)
 	GetAllCommentsForPost(int) (*[]entity.CommentView, error)
+	GetCommentThread(postID, commentID int) (*[]entity.CommentView, error)
+	GetVisibleThread(postID, commentID, threadID int) (int, error)
 	GetAllUserCommentsForPost(userID, postID int) (*[]entity.CommentView, error)
 	ExistsComment(int) (bool, error)
 	DeleteComment(commentID, userID int) error
-	DeleteCommentPrivileged(commentID int, userID int, userRole string) error
+	DeleteCommentPrivileged(commentID int, userID int, userRole, reason string) error
 	GetAuthorID(commentID int) (int, error)
 	GetComment(commentID int) (entity.CommentView, error)
 	UpdateComment(commentID int, content string) error
 	GetPostID(commentID int) (int, error)
+	GetCommentHistory(commentID int, userRole string) (entity.CommentHistory, error)
 }
 
 type commentService struct {
@@ -34,26 +39,93 @@ func NewCommentService(r comment.ICommentRepository, us user.IUserService) *comm
 	}
 }
 
-func (cs *commentService) SaveComment(c *entity.CommentCreateForm, postID int, userID int) error {
+// SaveComment saves the comment and returns its id
+func (cs *commentService) SaveComment(c *entity.CommentCreateForm, postID int, userID int) (int, error) {
 	if !IsRightComment(c) {
-		return entity.ErrInvalidFormData
+		return 0, entity.ErrInvalidFormData
 	}
 
-	err := cs.commentRepo.Insert(*c, postID, userID)
-	if err != nil {
-		return err
+	// Reply should be left under the same post as the comment it answers
+	if c.ParentID != 0 {
+		parentPostID, err := cs.commentRepo.GetPostID(c.ParentID)
+		if err != nil && !errors.Is(err, entity.ErrPostNotFound) {
+			return 0, err
+		}
+		if parentPostID != postID {
+			c.AddFieldError("parentID", "Comment you reply to doesn't exist")
+			return 0, entity.ErrInvalidFormData
+		}
 	}
 
-	return nil
+	return cs.commentRepo.Insert(*c, postID, userID)
 }
 
+// GetAllCommentsForPost returns top level comments of the post with replies
+// nested in them
 func (cs *commentService) GetAllCommentsForPost(postID int) (*[]entity.CommentView, error) {
 	comments, err := cs.commentRepo.GetAllForPost(postID)
 	if err != nil {
 		return nil, err
 	}
 
-	return ConvertEntitiesToViews(comments)
+	views, err := ConvertEntitiesToViews(comments)
+	if err != nil {
+		return nil, err
+	}
+
+	tree := BuildTree(*views, 0, 0)
+
+	return &tree, nil
+}
+
+// GetCommentThread returns the comment with all its replies nested in it.
+// It is used for threads that are too deep to be shown on the post page
+func (cs *commentService) GetCommentThread(postID, commentID int) (*[]entity.CommentView, error) {
+	comments, err := cs.commentRepo.GetAllForPost(postID)
+	if err != nil {
+		return nil, err
+	}
+
+	views, err := ConvertEntitiesToViews(comments)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range *views {
+		if c.ID == commentID {
+			c.Replies = BuildTree(*views, c.ID, 1)
+			thread := []entity.CommentView{c}
+			return &thread, nil
+		}
+	}
+
+	return nil, entity.ErrCommentNotFound
+}
+
+// GetVisibleThread returns id of the thread where the comment can be seen,
+// preferring given thread (0 for the post page). Comment that is nested too
+// deep there is shown in the thread of the comment it replies to
+func (cs *commentService) GetVisibleThread(postID, commentID, threadID int) (int, error) {
+	comments, err := cs.commentRepo.GetAllForPost(postID)
+	if err != nil {
+		return 0, err
+	}
+
+	parents := make(map[int]int, len(*comments))
+	for _, c := range *comments {
+		parents[c.ID] = c.ParentID
+	}
+
+	parentID, ok := parents[commentID]
+	if !ok {
+		return 0, entity.ErrCommentNotFound
+	}
+
+	if IsShownInThread(parents, commentID, threadID) {
+		return threadID, nil
+	}
+
+	return parentID, nil
 }
 
 func (cs *commentService) GetAllUserCommentsForPost(userID, postID int) (*[]entity.CommentView, error) {
@@ -85,7 +157,9 @@ func (cs *commentService) DeleteComment(commentID, userID int) error {
 	return cs.commentRepo.Delete(commentID, userID)
 }
 
-func (cs *commentService) DeleteCommentPrivileged(commentID int, userID int, userRole string) error {
+// DeleteCommentPrivileged deletes comment the same way as
+// DeletePostPrivileged deletes post
+func (cs *commentService) DeleteCommentPrivileged(commentID int, userID int, userRole, reason string) error {
 	exists, err := cs.commentRepo.Exists(commentID)
 	if err != nil {
 		return err
@@ -99,7 +173,17 @@ func (cs *commentService) DeleteCommentPrivileged(commentID int, userID int, use
 		return err
 	}
 
-	err = cs.commentRepo.DeleteByPrivileged(commentID)
+	before, err := cs.commentRepo.GetByID(commentID)
+	if err != nil {
+		return err
+	}
+
+	e, err := moderation.NewEntry(userID, entity.ACTION_DELETE_COMMENT, entity.COMMENT, commentID, reason, before)
+	if err != nil {
+		return err
+	}
+
+	err = cs.commentRepo.DeleteByPrivileged(commentID, e)
 	if err != nil {
 		return err
 	}
@@ -109,7 +193,9 @@ func (cs *commentService) DeleteCommentPrivileged(commentID int, userID int, use
 		UserFrom: userID,
 		UserTo:   authorID,
 	}
-	if userRole == entity.MODERATOR {
+	if reason != "" {
+		notificaiton.Content = ". Reason: " + reason
+	} else if userRole == entity.MODERATOR {
 		notificaiton.Content = ". Reason: obscene"
 	}
 
@@ -127,7 +213,7 @@ func (cs *commentService) GetComment(commentID int) (entity.CommentView, error)
 		return entity.CommentView{}, err
 	}
 
-	view := entity.CommentView(c)
+	view := ConvertEntityToView(c)
 
 	return view, nil
 }
@@ -139,3 +225,26 @@ func (cs *commentService) UpdateComment(commentID int, content string) error {
 func (cs *commentService) GetPostID(commentID int) (int, error) {
 	return cs.commentRepo.GetPostID(commentID)
 }
+
+// GetCommentHistory returns all versions of comment with changes made in each
+// of them. History is available only to moderators and admins
+func (cs *commentService) GetCommentHistory(commentID int, userRole string) (entity.CommentHistory, error) {
+	if userRole != entity.MODERATOR && userRole != entity.ADMIN {
+		return entity.CommentHistory{}, entity.ErrForbiddenAccess
+	}
+
+	c, err := cs.GetComment(commentID)
+	if err != nil {
+		return entity.CommentHistory{}, err
+	}
+
+	revisions, err := cs.commentRepo.GetRevisions(commentID)
+	if err != nil {
+		return entity.CommentHistory{}, err
+	}
+
+	return entity.CommentHistory{
+		Comment:   c,
+		Revisions: NumberRevisions(*revisions),
+	}, nil
+}
@@ -4,12 +4,23 @@ import (
 	"fmt"
 	"forum/internal/entity"
 	"forum/internal/validator"
+	"forum/pkg/diff"
+	"forum/pkg/markdown"
+	"time"
 )
 
 const (
 	commentMaxLen = 500
+
+	// Replies deeper than this are shown on separate thread page
+	maxReplyDepth = 4
+
+	// Number of comments which rendered content is kept in memory
+	renderCacheSize = 2048
 )
 
+var renderer = markdown.NewRenderer(renderCacheSize)
+
 func IsRightComment(c *entity.CommentCreateForm) bool {
 	c.CheckField(validator.NotBlank(c.Content), "commentContent", "This field cannot be blank")
 	c.CheckField(validator.MaxChar(c.Content, commentMaxLen), "commentContent", fmt.Sprintf("This cannot be longer than %d characters", commentMaxLen))
@@ -21,9 +32,132 @@ func ConvertEntitiesToViews(comments *[]entity.CommentEntity) (*[]entity.Comment
 	// Convert received CommentEntity's to CommentView's
 	var cViews []entity.CommentView
 	for _, c := range *comments {
-		comment := entity.CommentView(c)
+		comment := ConvertEntityToView(c)
 		cViews = append(cViews, comment)
 	}
 
 	return &cViews, nil
 }
+
+func ConvertEntityToView(c entity.CommentEntity) entity.CommentView {
+	return entity.CommentView{
+		ID:          c.ID,
+		Username:    c.Username,
+		Content:     c.Content,
+		ContentHTML: renderer.HTML(c.Content),
+		CreatedAt:   c.CreatedAt,
+		UpdatedAt:   c.UpdatedAt,
+		EditedAgo:   EditedAgo(c.UpdatedAt, time.Now()),
+		PostID:      c.PostID,
+		ParentID:    c.ParentID,
+		Likes:       c.Likes,
+		Dislikes:    c.Dislikes,
+	}
+}
+
+// BuildTree arranges flat list of comments into tree of replies to the comment
+// with given id (0 for top level comments). Returned comments get given depth,
+// order of siblings is preserved.
+//
+// Replies deeper than maxReplyDepth aren't included, only their number is kept
+func BuildTree(comments []entity.CommentView, rootID, depth int) []entity.CommentView {
+	children := make(map[int][]entity.CommentView)
+	for _, c := range comments {
+		children[c.ParentID] = append(children[c.ParentID], c)
+	}
+
+	var count func(parentID int) int
+	count = func(parentID int) int {
+		n := len(children[parentID])
+		for _, c := range children[parentID] {
+			n += count(c.ID)
+		}
+		return n
+	}
+
+	var build func(parentID, depth int) []entity.CommentView
+	build = func(parentID, depth int) []entity.CommentView {
+		var nodes []entity.CommentView
+		for _, c := range children[parentID] {
+			c.Depth = depth
+			if depth < maxReplyDepth {
+				c.Replies = build(c.ID, depth+1)
+			} else {
+				c.HiddenReplies = count(c.ID)
+			}
+			nodes = append(nodes, c)
+		}
+		return nodes
+	}
+
+	return build(rootID, depth)
+}
+
+// IsShownInThread reports whether the comment is shown in the thread of
+// given comment (0 for the post page) without following 'Continue thread'
+// links. Parents maps ids of comments to ids of comments they reply to
+func IsShownInThread(parents map[int]int, commentID, threadID int) bool {
+	depth := 0
+	for id := commentID; id != threadID; id = parents[id] {
+		if id == 0 {
+			// Top of the post is reached, thread is not above the comment
+			return false
+		}
+		depth++
+	}
+
+	// Top level comments are counted from 0 on the post page, while thread
+	// page starts from its own comment
+	if threadID == 0 {
+		depth--
+	}
+
+	return depth <= maxReplyDepth
+}
+
+// EditedAgo returns how long before now comment was edited, e.g. "5 minutes
+// ago". Comments that were never edited have zero editing time and get empty
+// string.
+//
+// Times are stored as local wall clock without time zone, so now is compared
+// by its wall clock too
+func EditedAgo(updatedAt, now time.Time) string {
+	if updatedAt.IsZero() {
+		return ""
+	}
+
+	now = time.Date(now.Year(), now.Month(), now.Day(), now.Hour(), now.Minute(), now.Second(), 0, updatedAt.Location())
+	elapsed := now.Sub(updatedAt)
+
+	plural := func(n int, unit string) string {
+		if n == 1 {
+			return fmt.Sprintf("1 %s ago", unit)
+		}
+		return fmt.Sprintf("%d %ss ago", n, unit)
+	}
+
+	switch {
+	case elapsed < time.Minute:
+		return "just now"
+	case elapsed < time.Hour:
+		return plural(int(elapsed/time.Minute), "minute")
+	case elapsed < 24*time.Hour:
+		return plural(int(elapsed/time.Hour), "hour")
+	case elapsed < 30*24*time.Hour:
+		return plural(int(elapsed/(24*time.Hour)), "day")
+	default:
+		return "on " + updatedAt.Format("02 Jan 2006")
+	}
+}
+
+// NumberRevisions numbers revisions from 1 and finds changes made in each of
+// them compared to the previous one
+func NumberRevisions(revisions []entity.CommentRevision) []entity.CommentRevision {
+	for i := range revisions {
+		revisions[i].Number = i + 1
+		if i > 0 {
+			revisions[i].Diff = diff.Words(revisions[i-1].Content, revisions[i].Content)
+		}
+	}
+	return revisions
+}
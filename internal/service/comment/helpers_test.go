@@ -0,0 +1,104 @@
+package comment
+
+import (
+	"forum/internal/assert"
+	"forum/internal/entity"
+	"testing"
+	"time"
+)
+
+func TestBuildTree(t *testing.T) {
+	// 1 <- 2 <- 3 <- 4 <- 5 <- 6 is a chain deeper than the cap, 7 is a
+	// second top level comment and 8 is the second reply to 1
+	comments := []entity.CommentView{
+		{ID: 1},
+		{ID: 2, ParentID: 1},
+		{ID: 3, ParentID: 2},
+		{ID: 4, ParentID: 3},
+		{ID: 5, ParentID: 4},
+		{ID: 6, ParentID: 5},
+		{ID: 7},
+		{ID: 8, ParentID: 1},
+	}
+
+	tree := BuildTree(comments, 0, 0)
+
+	assert.Equal(t, len(tree), 2)
+	assert.Equal(t, tree[0].ID, 1)
+	assert.Equal(t, tree[1].ID, 7)
+
+	assert.Equal(t, len(tree[0].Replies), 2)
+	assert.Equal(t, tree[0].Replies[0].ID, 2)
+	assert.Equal(t, tree[0].Replies[1].ID, 8)
+	assert.Equal(t, tree[0].Replies[1].Depth, 1)
+
+	// Comment 5 is on the last shown level, so its reply is hidden
+	deepest := tree[0].Replies[0].Replies[0].Replies[0].Replies[0]
+	assert.Equal(t, deepest.ID, 5)
+	assert.Equal(t, deepest.Depth, maxReplyDepth)
+	assert.Equal(t, len(deepest.Replies), 0)
+	assert.Equal(t, deepest.HiddenReplies, 1)
+
+	// Thread of comment 3 starts from its replies
+	thread := BuildTree(comments, 3, 1)
+	assert.Equal(t, len(thread), 1)
+	assert.Equal(t, thread[0].ID, 4)
+	assert.Equal(t, thread[0].Replies[0].Replies[0].ID, 6)
+}
+
+func TestIsShownInThread(t *testing.T) {
+	// Same chain as in TestBuildTree: 1 <- 2 <- 3 <- 4 <- 5 <- 6, 7 is a
+	// second top level comment
+	parents := map[int]int{1: 0, 2: 1, 3: 2, 4: 3, 5: 4, 6: 5, 7: 0}
+
+	tests := []struct {
+		name      string
+		commentID int
+		threadID  int
+		want      bool
+	}{
+		{name: "Top level comment on post page", commentID: 1, threadID: 0, want: true},
+		{name: "Last shown level on post page", commentID: 5, threadID: 0, want: true},
+		{name: "Too deep for post page", commentID: 6, threadID: 0, want: false},
+		{name: "Thread comment itself", commentID: 3, threadID: 3, want: true},
+		{name: "Deep reply in thread", commentID: 6, threadID: 3, want: true},
+		{name: "Too deep for thread", commentID: 6, threadID: 1, want: false},
+		{name: "Comment above thread", commentID: 2, threadID: 3, want: false},
+		{name: "Comment from another thread", commentID: 7, threadID: 3, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, IsShownInThread(parents, tt.commentID, tt.threadID), tt.want)
+		})
+	}
+}
+
+func TestEditedAgo(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.Local)
+	wall := func(d time.Duration) time.Time {
+		// Edit times come from database as wall clock in UTC
+		t := now.Add(-d)
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+	}
+
+	tests := []struct {
+		name      string
+		updatedAt time.Time
+		want      string
+	}{
+		{"Never edited", time.Time{}, ""},
+		{"Just now", wall(20 * time.Second), "just now"},
+		{"One minute", wall(time.Minute), "1 minute ago"},
+		{"Minutes", wall(42 * time.Minute), "42 minutes ago"},
+		{"Hours", wall(5 * time.Hour), "5 hours ago"},
+		{"Days", wall(3 * 24 * time.Hour), "3 days ago"},
+		{"Long ago", wall(90 * 24 * time.Hour), "on 11 Dec 2023"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, EditedAgo(tt.updatedAt, now), tt.want)
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package identity
+
+import (
+	"fmt"
+	"forum/internal/entity"
+	"forum/internal/service/user"
+	"forum/internal/validator"
+)
+
+const maxUsernameLen = 255
+
+// IsRightExternalSignup checks username picked by the user and email given
+// by provider, which user can't change
+func IsRightExternalSignup(f *entity.ExternalSignupForm) bool {
+	f.CheckField(validator.NotBlank(f.Username), "username", "This field cannot be blank")
+	f.CheckField(validator.MaxChar(f.Username, maxUsernameLen), "username", fmt.Sprintf("Maximum characters length exceeded - %d", maxUsernameLen))
+	f.CheckField(validator.ValidString(f.Username), "username", "Only valid characters (ascii standard) should be included")
+	f.CheckField(validator.Matches(f.Identity.Email, user.EmailRX), "email", "Provider didn't share a valid email address")
+
+	return f.Valid()
+}
@@ -0,0 +1,48 @@
+package identity
+
+import (
+	"forum/internal/assert"
+	"forum/internal/entity"
+	"testing"
+)
+
+func TestIsRightExternalSignup(t *testing.T) {
+	identity := entity.ExternalIdentity{Provider: "github", Subject: "1", Email: "octocat@github.com"}
+
+	tests := []struct {
+		name    string
+		form    entity.ExternalSignupForm
+		invalid []string
+	}{
+		{
+			name: "Valid",
+			form: entity.ExternalSignupForm{Username: "octocat", Identity: identity},
+		},
+		{
+			name:    "Blank username",
+			form:    entity.ExternalSignupForm{Identity: identity},
+			invalid: []string{"username"},
+		},
+		{
+			name:    "Non-ascii username",
+			form:    entity.ExternalSignupForm{Username: "Октокот", Identity: identity},
+			invalid: []string{"username"},
+		},
+		{
+			name:    "Invalid email",
+			form:    entity.ExternalSignupForm{Username: "octocat", Identity: entity.ExternalIdentity{Email: "octocat"}},
+			invalid: []string{"email"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, IsRightExternalSignup(&tt.form), len(tt.invalid) == 0)
+			assert.Equal(t, len(tt.form.FieldErrors), len(tt.invalid))
+			for _, field := range tt.invalid {
+				_, ok := tt.form.FieldErrors[field]
+				assert.Equal(t, ok, true)
+			}
+		})
+	}
+}
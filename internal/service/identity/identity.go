@@ -0,0 +1,114 @@
+package identity
+
+import (
+	"errors"
+	"forum/internal/entity"
+	"forum/internal/repository/identity"
+	"forum/internal/repository/user"
+)
+
+type IIdentityService interface {
+	Login(identity entity.ExternalIdentity) (int, error)
+	SignUp(form *entity.ExternalSignupForm) (int, error)
+	Link(userID int, identity entity.ExternalIdentity) error
+	Unlink(userID int, provider string) error
+	GetAll(userID int) ([]entity.Identity, error)
+}
+
+type identityService struct {
+	identityRepo identity.IIdentityRepository
+	userRepo     user.IUserRepository
+}
+
+func NewIdentityService(i identity.IIdentityRepository, u user.IUserRepository) *identityService {
+	return &identityService{
+		identityRepo: i,
+		userRepo:     u,
+	}
+}
+
+var _ IIdentityService = (*identityService)(nil)
+
+// Login returns id of user that identity is linked to. Accounts are never
+// matched by email or username given by provider
+func (is *identityService) Login(identity entity.ExternalIdentity) (int, error) {
+	return is.identityRepo.GetUserID(identity.Provider, identity.Subject)
+}
+
+// SignUp creates account for identity that isn't linked to any user. The
+// account has no password until user sets it through password reset
+func (is *identityService) SignUp(form *entity.ExternalSignupForm) (int, error) {
+	if !IsRightExternalSignup(form) {
+		return 0, entity.ErrInvalidFormData
+	}
+
+	// Email can't be changed by the user, so it's checked first
+	_, err := is.userRepo.GetByEmail(form.Identity.Email)
+	if err == nil {
+		return 0, entity.ErrDuplicateEmail
+	}
+	if !errors.Is(err, entity.ErrInvalidCredentials) {
+		return 0, err
+	}
+
+	_, err = is.userRepo.GetByUsername(form.Username)
+	if err == nil {
+		form.AddFieldError("username", "Username is already in use")
+		return 0, entity.ErrDuplicateUsername
+	}
+	if !errors.Is(err, entity.ErrInvalidCredentials) {
+		return 0, err
+	}
+
+	id, err := is.identityRepo.InsertWithUser(form.Username, form.Identity)
+	if err != nil {
+		if errors.Is(err, entity.ErrDuplicateUsername) {
+			form.AddFieldError("username", "Username is already in use")
+		}
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// Link connects identity to the user. Linking identity that is already
+// linked to the same user does nothing
+func (is *identityService) Link(userID int, identity entity.ExternalIdentity) error {
+	linkedID, err := is.identityRepo.GetUserID(identity.Provider, identity.Subject)
+	switch {
+	case err == nil && linkedID == userID:
+		return nil
+	case err == nil:
+		return entity.ErrIdentityLinked
+	case !errors.Is(err, entity.ErrIdentityNotLinked):
+		return err
+	}
+
+	return is.identityRepo.Insert(userID, identity)
+}
+
+// Unlink disconnects identity of provider from the user. The only identity
+// of user without password can't be disconnected, otherwise user couldn't
+// log in anymore
+func (is *identityService) Unlink(userID int, provider string) error {
+	identities, err := is.identityRepo.GetAll(userID)
+	if err != nil {
+		return err
+	}
+
+	if len(identities) == 1 && identities[0].Provider == provider {
+		u, err := is.userRepo.GetByID(userID)
+		if err != nil {
+			return err
+		}
+		if u.Password == "" {
+			return entity.ErrLastLoginMethod
+		}
+	}
+
+	return is.identityRepo.Delete(userID, provider)
+}
+
+func (is *identityService) GetAll(userID int) ([]entity.Identity, error) {
+	return is.identityRepo.GetAll(userID)
+}
@@ -0,0 +1,36 @@
+package lockout
+
+import "time"
+
+const (
+	// Failed logins in a row that are allowed before lock. Many users can
+	// share one address, so addresses get more of them
+	accountFreeFailures = 5
+	ipFreeFailures      = 20
+
+	// Lock starts at minLock and doubles with every next failure
+	minLock = time.Minute
+	maxLock = time.Hour
+
+	// failureWindow is time after the last failure when failures are
+	// counted from the start again
+	failureWindow = 24 * time.Hour
+)
+
+// lockDuration returns how long logins are refused after given number of
+// failures in a row, it's 0 if lock isn't needed yet
+func lockDuration(failures, free int) time.Duration {
+	if failures < free {
+		return 0
+	}
+
+	d := minLock
+	for i := free; i < failures; i++ {
+		d *= 2
+		if d >= maxLock {
+			return maxLock
+		}
+	}
+
+	return d
+}
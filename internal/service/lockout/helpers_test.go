@@ -0,0 +1,26 @@
+package lockout
+
+import (
+	"forum/internal/assert"
+	"testing"
+	"time"
+)
+
+func TestLockDuration(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{4, 0},
+		{5, time.Minute},
+		{6, 2 * time.Minute},
+		{8, 8 * time.Minute},
+		{11, time.Hour},
+		{1000, time.Hour},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, lockDuration(tt.failures, accountFreeFailures), tt.want)
+	}
+}
@@ -0,0 +1,154 @@
+package lockout
+
+import (
+	"errors"
+	"fmt"
+	"forum/internal/entity"
+	"forum/internal/repository/lockout"
+	"forum/internal/service/moderation"
+	"forum/internal/service/user"
+	"forum/internal/validator"
+	"time"
+)
+
+type ILockoutService interface {
+	Check(identifier, ip string) (time.Duration, error)
+	RecordFailure(identifier, ip string) error
+	RecordSuccess(userID int) error
+	GetLockouts() ([]entity.Lockout, error)
+	Clear(actorID, lockoutID int) error
+}
+
+type lockoutService struct {
+	lockoutRepo lockout.ILockoutRepository
+	userService user.IUserService
+}
+
+func NewLockoutService(l lockout.ILockoutRepository, u user.IUserService) *lockoutService {
+	return &lockoutService{
+		lockoutRepo: l,
+		userService: u,
+	}
+}
+
+var _ ILockoutService = (*lockoutService)(nil)
+
+// Check returns entity.ErrLoginLocked and time until login is allowed if
+// the account with given email or username or the address is locked
+func (ls *lockoutService) Check(identifier, ip string) (time.Duration, error) {
+	userID, err := ls.findUser(identifier)
+	if err != nil {
+		return 0, err
+	}
+
+	wait, err := ls.lockoutRepo.LockedFor(userID, ip)
+	if err != nil {
+		return 0, err
+	}
+	if wait > 0 {
+		return wait, entity.ErrLoginLocked
+	}
+
+	return 0, nil
+}
+
+// RecordFailure counts failed login of the account and of the address and
+// locks them if there were too many failures. Owner of the account is
+// notified when it's locked for the first time
+func (ls *lockoutService) RecordFailure(identifier, ip string) error {
+	if err := ls.lockoutRepo.DeleteStale(failureWindow); err != nil {
+		return err
+	}
+
+	l, err := ls.lockoutRepo.AddIPFailure(ip, failureWindow)
+	if err != nil {
+		return err
+	}
+	if d := lockDuration(l.Failures, ipFreeFailures); d > 0 {
+		if err := ls.lockoutRepo.Lock(l.ID, d); err != nil {
+			return err
+		}
+	}
+
+	userID, err := ls.findUser(identifier)
+	if err != nil || userID == 0 {
+		return err
+	}
+
+	l, err = ls.lockoutRepo.AddUserFailure(userID, failureWindow)
+	if err != nil {
+		return err
+	}
+
+	d := lockDuration(l.Failures, accountFreeFailures)
+	if d == 0 {
+		return nil
+	}
+	if err := ls.lockoutRepo.Lock(l.ID, d); err != nil {
+		return err
+	}
+
+	if l.Notified {
+		return nil
+	}
+
+	err = ls.userService.SendNotification(entity.Notification{
+		Type:     entity.LOGIN_LOCKED,
+		Content:  fmt.Sprintf(", the last one was made from %s. If it wasn't you, change your password", ip),
+		UserFrom: userID,
+		UserTo:   userID,
+	})
+	if err != nil {
+		return err
+	}
+
+	return ls.lockoutRepo.SetNotified(l.ID)
+}
+
+// RecordSuccess forgets failed logins of the account. Failures of the
+// address are kept, so one known password doesn't allow to guess others
+func (ls *lockoutService) RecordSuccess(userID int) error {
+	return ls.lockoutRepo.DeleteUser(userID)
+}
+
+// GetLockouts returns accounts and addresses with recent failed logins,
+// locked ones first
+func (ls *lockoutService) GetLockouts() ([]entity.Lockout, error) {
+	return ls.lockoutRepo.GetAll(failureWindow)
+}
+
+// Clear unlocks account or address and forgets its failed logins
+func (ls *lockoutService) Clear(actorID, lockoutID int) error {
+	l, err := ls.lockoutRepo.Get(lockoutID)
+	if err != nil {
+		return err
+	}
+
+	e, err := moderation.NewEntry(actorID, entity.ACTION_CLEAR_LOCKOUT, entity.TARGET_LOCKOUT, lockoutID, "", l)
+	if err != nil {
+		return err
+	}
+
+	return ls.lockoutRepo.Delete(lockoutID, e)
+}
+
+// findUser returns id of the user with given email or username or 0 if
+// there is no such user
+func (ls *lockoutService) findUser(identifier string) (int, error) {
+	var u entity.UserEntity
+	var err error
+
+	if validator.Matches(identifier, user.EmailRX) {
+		u, err = ls.userService.GetUserByEmail(identifier)
+	} else {
+		u, err = ls.userService.GetUserByUsername(identifier)
+	}
+	if err != nil {
+		if errors.Is(err, entity.ErrInvalidCredentials) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	return u.ID, nil
+}
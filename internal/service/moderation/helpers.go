@@ -0,0 +1,69 @@
+package moderation
+
+import (
+	"encoding/json"
+	"forum/internal/entity"
+	"slices"
+)
+
+const (
+	defaultLimit = 50
+	maxLimit     = 200
+)
+
+// IsRightFilter checks the filter and sets default limit if it isn't set
+func IsRightFilter(f *entity.ModerationFilter) bool {
+	if f.Action != "" && !slices.Contains(entity.ModerationActions, f.Action) {
+		return false
+	}
+	if f.TargetType != "" && !slices.Contains(entity.ModerationTargets, f.TargetType) {
+		return false
+	}
+	if f.TargetID < 0 || f.Before < 0 || f.Limit < 0 || f.Limit > maxLimit {
+		return false
+	}
+
+	if f.Limit == 0 {
+		f.Limit = defaultLimit
+	}
+
+	return true
+}
+
+// NewEntry makes entry of moderation log for privileged action, repository
+// saves it together with the action. Before is the target as it was before
+// the action, it's saved as JSON. Actor 0 is the command line
+func NewEntry(actorID int, action, targetType string, targetID int, reason string, before any) (entity.ModerationEntry, error) {
+	snapshot, err := snapshot(before)
+	if err != nil {
+		return entity.ModerationEntry{}, err
+	}
+
+	return entity.ModerationEntry{
+		ActorID:    actorID,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Reason:     reason,
+		Before:     snapshot,
+	}, nil
+}
+
+// snapshot encodes state of the target as JSON, nil (and nil slice of
+// things the target had) is encoded as empty string
+func snapshot(before any) (string, error) {
+	if before == nil {
+		return "", nil
+	}
+
+	b, err := json.Marshal(before)
+	if err != nil {
+		return "", err
+	}
+
+	if string(b) == "null" {
+		return "", nil
+	}
+
+	return string(b), nil
+}
@@ -0,0 +1,65 @@
+package moderation
+
+import (
+	"forum/internal/assert"
+	"forum/internal/entity"
+	"testing"
+)
+
+func TestIsRightFilter(t *testing.T) {
+	tests := []struct {
+		filter entity.ModerationFilter
+		want   bool
+	}{
+		{entity.ModerationFilter{}, true},
+		{entity.ModerationFilter{Actor: "admin", Action: entity.ACTION_DELETE_POST, TargetType: entity.POST, TargetID: 3, Before: 10, Limit: 20}, true},
+		{entity.ModerationFilter{Action: "drop_table"}, false},
+		{entity.ModerationFilter{TargetType: "forum"}, false},
+		{entity.ModerationFilter{TargetID: -1}, false},
+		{entity.ModerationFilter{Before: -1}, false},
+		{entity.ModerationFilter{Limit: maxLimit + 1}, false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, IsRightFilter(&tt.filter), tt.want)
+	}
+}
+
+func TestDefaultLimit(t *testing.T) {
+	f := entity.ModerationFilter{}
+	IsRightFilter(&f)
+	assert.Equal(t, f.Limit, defaultLimit)
+}
+
+func TestSnapshot(t *testing.T) {
+	s, err := snapshot(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, s, "")
+
+	var sanctions []entity.Sanction
+	s, err = snapshot(sanctions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, s, "")
+
+	s, err = snapshot(map[string]string{"username": "bob", "role": entity.USER})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, s, `{"role":"user","username":"bob"}`)
+}
+
+func TestNewEntry(t *testing.T) {
+	e, err := NewEntry(0, entity.ACTION_SET_ROLE, entity.TARGET_USER, 7, entity.ADMIN, map[string]string{"role": entity.USER})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, e.ActorID, 0)
+	assert.Equal(t, e.Action, entity.ACTION_SET_ROLE)
+	assert.Equal(t, e.TargetID, 7)
+	assert.Equal(t, e.Reason, entity.ADMIN)
+	assert.Equal(t, e.Before, `{"role":"user"}`)
+}
@@ -0,0 +1,46 @@
+package moderation
+
+import (
+	"forum/internal/entity"
+	"forum/internal/repository/moderation"
+)
+
+type IModerationService interface {
+	GetLog(f entity.ModerationFilter) ([]entity.ModerationEntry, int, error)
+}
+
+type moderationService struct {
+	moderationRepo moderation.IModerationRepository
+}
+
+func NewModerationService(m moderation.IModerationRepository) *moderationService {
+	return &moderationService{
+		moderationRepo: m,
+	}
+}
+
+var _ IModerationService = (*moderationService)(nil)
+
+// GetLog returns page of moderation log matching the filter and id to pass
+// as Before to get the next page, it's 0 if there are no more entries
+func (ms *moderationService) GetLog(f entity.ModerationFilter) ([]entity.ModerationEntry, int, error) {
+	if !IsRightFilter(&f) {
+		return nil, 0, entity.ErrInvalidModerationLog
+	}
+
+	// One more entry tells whether there is the next page
+	limit := f.Limit
+	f.Limit++
+
+	entries, err := ms.moderationRepo.GetAll(f)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if len(entries) <= limit {
+		return entries, 0, nil
+	}
+
+	entries = entries[:limit]
+	return entries, entries[limit-1].ID, nil
+}
@@ -1,17 +1,46 @@
 package post
 
 import (
+	"encoding/base64"
 	"fmt"
 	"forum/internal/entity"
 	"forum/internal/validator"
+	"forum/pkg/diff"
+	"forum/pkg/markdown"
+	"strconv"
 	"strings"
+	"time"
 )
 
 const (
 	maxTitleLen   = 100
 	maxContentLen = 5000
+
+	DefaultPageSize = 10
+	maxPageSize     = 100
+
+	// Number of posts which rendered content is kept in memory
+	renderCacheSize = 512
 )
 
+var renderer = markdown.NewRenderer(renderCacheSize)
+
+var sorts = map[interface{}]struct{}{
+	entity.SORT_NEW:           {},
+	entity.SORT_TOP:           {},
+	entity.SORT_COMMENTED:     {},
+	entity.SORT_CONTROVERSIAL: {},
+	entity.SORT_HOT:           {},
+}
+
+var periods = map[interface{}]struct{}{
+	entity.PERIOD_DAY:   {},
+	entity.PERIOD_WEEK:  {},
+	entity.PERIOD_MONTH: {},
+	entity.PERIOD_YEAR:  {},
+	entity.PERIOD_ALL:   {},
+}
+
 var types = map[interface{}]struct{}{
 	"image/jpeg": {},
 	"image/png":  {},
@@ -44,6 +73,7 @@ func ConvertEntitiesToViews(posts *[]entity.PostEntity) (*[]entity.PostView, err
 			ID:          p.ID,
 			Title:       p.Title,
 			Content:     p.Content,
+			ContentHTML: renderer.HTML(p.Content),
 			CreatedAt:   p.CreatedAt,
 			Username:    p.Username,
 			Likes:       p.Likes,
@@ -64,3 +94,191 @@ func ConvertToStrArr(tagsStr string) []string {
 	}
 	return strings.Split(tagsStr, ", ")
 }
+
+// NewPageRequest validates sorting and decodes page cursor from given params
+// into request for repository. One extra post is requested, so it can be
+// found out whether there are more posts after the page
+func NewPageRequest(p entity.PageParams) (entity.PageRequest, error) {
+	limit := p.Limit
+	if limit <= 0 || limit > maxPageSize {
+		limit = DefaultPageSize
+	}
+
+	req := entity.PageRequest{
+		Limit:  limit + 1,
+		Sort:   p.Sort,
+		Period: p.Period,
+		Ref:    time.Now(),
+	}
+
+	if req.Sort == "" {
+		req.Sort = entity.SORT_NEW
+	}
+	if !validator.ExistsInSet(req.Sort, sorts) {
+		return entity.PageRequest{}, entity.ErrInvalidSort
+	}
+
+	if req.Sort == entity.SORT_TOP {
+		if req.Period == "" {
+			req.Period = entity.PERIOD_WEEK
+		}
+		if !validator.ExistsInSet(req.Period, periods) {
+			return entity.PageRequest{}, entity.ErrInvalidSort
+		}
+	} else {
+		req.Period = ""
+	}
+
+	switch {
+	case p.After != "" && p.Before != "":
+		return entity.PageRequest{}, entity.ErrInvalidCursor
+	case p.After != "":
+		cursor, err := DecodeCursor(p.After)
+		if err != nil {
+			return entity.PageRequest{}, err
+		}
+		req.Cursor = &cursor
+	case p.Before != "":
+		cursor, err := DecodeCursor(p.Before)
+		if err != nil {
+			return entity.PageRequest{}, err
+		}
+		req.Cursor = &cursor
+		req.Backward = true
+	}
+
+	// Hot scores and top periods of all pages are calculated for the moment
+	// when first page was shown, so posts don't jump between pages
+	if req.Cursor != nil && !req.Cursor.Ref.IsZero() {
+		req.Ref = req.Cursor.Ref
+	}
+
+	return req, nil
+}
+
+// convertPage cuts extra post requested by NewPageRequest and converts posts
+// to views along with cursors of neighbour pages
+func convertPage(posts *[]entity.PostEntity, req entity.PageRequest) (*[]entity.PostView, entity.Page, error) {
+	limit := req.Limit - 1
+	hasMore := len(*posts) > limit
+
+	// Extra post is the farthest one from cursor - first on previous
+	// pages and last on next ones
+	if hasMore {
+		if req.Backward {
+			*posts = (*posts)[1:]
+		} else {
+			*posts = (*posts)[:limit]
+		}
+	}
+
+	page := entity.Page{
+		Sort:   req.Sort,
+		Period: req.Period,
+	}
+
+	if n := len(*posts); n != 0 {
+		first := entity.PostCursor{
+			Score:     (*posts)[0].Score,
+			CreatedAt: (*posts)[0].CreatedAt,
+			ID:        (*posts)[0].ID,
+			Ref:       req.Ref,
+		}
+		last := entity.PostCursor{
+			Score:     (*posts)[n-1].Score,
+			CreatedAt: (*posts)[n-1].CreatedAt,
+			ID:        (*posts)[n-1].ID,
+			Ref:       req.Ref,
+		}
+
+		if req.Backward {
+			page.Next = EncodeCursor(last)
+			if hasMore {
+				page.Prev = EncodeCursor(first)
+			}
+		} else {
+			if hasMore {
+				page.Next = EncodeCursor(last)
+			}
+			if req.Cursor != nil {
+				page.Prev = EncodeCursor(first)
+			}
+		}
+	}
+
+	views, err := ConvertEntitiesToViews(posts)
+	if err != nil {
+		return nil, entity.Page{}, err
+	}
+
+	return views, page, nil
+}
+
+// EncodeCursor returns opaque url-safe representation of given cursor
+func EncodeCursor(c entity.PostCursor) string {
+	raw := strings.Join([]string{
+		strconv.FormatInt(c.CreatedAt.Unix(), 10),
+		strconv.Itoa(c.ID),
+		strconv.FormatFloat(c.Score, 'g', -1, 64),
+		strconv.FormatInt(c.Ref.Unix(), 10),
+	}, "~")
+
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeCursor parses cursor returned by EncodeCursor
+func DecodeCursor(cursor string) (entity.PostCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return entity.PostCursor{}, entity.ErrInvalidCursor
+	}
+
+	parts := strings.Split(string(raw), "~")
+	if len(parts) != 4 {
+		return entity.PostCursor{}, entity.ErrInvalidCursor
+	}
+
+	createdAt, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return entity.PostCursor{}, entity.ErrInvalidCursor
+	}
+
+	id, err := strconv.Atoi(parts[1])
+	if err != nil || id <= 0 {
+		return entity.PostCursor{}, entity.ErrInvalidCursor
+	}
+
+	score, err := strconv.ParseFloat(parts[2], 64)
+	if err != nil {
+		return entity.PostCursor{}, entity.ErrInvalidCursor
+	}
+
+	ref, err := strconv.ParseInt(parts[3], 10, 64)
+	if err != nil {
+		return entity.PostCursor{}, entity.ErrInvalidCursor
+	}
+
+	return entity.PostCursor{
+		Score:     score,
+		CreatedAt: time.Unix(createdAt, 0).UTC(),
+		ID:        id,
+		Ref:       time.Unix(ref, 0),
+	}, nil
+}
+
+// BuildHistory compares revisions with given numbers, which should be valid
+// positions in the list of revisions (numbered from 1)
+func BuildHistory(post entity.PostView, revisions []entity.PostRevision, from, to int) entity.PostHistory {
+	a, b := revisions[from-1], revisions[to-1]
+
+	return entity.PostHistory{
+		Post:         post,
+		Revisions:    revisions,
+		From:         a,
+		To:           b,
+		TitleDiff:    diff.Words(a.Title, b.Title),
+		ContentDiff:  diff.Words(a.Content, b.Content),
+		TagsDiff:     diff.Words(a.Tags, b.Tags),
+		ImageChanged: a.ImageName != b.ImageName,
+	}
+}
@@ -0,0 +1,301 @@
+package post
+
+import (
+	"encoding/base64"
+	"errors"
+	"forum/internal/assert"
+	"forum/internal/entity"
+	"testing"
+	"time"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	createdAt := time.Date(2024, time.March, 8, 14, 30, 15, 0, time.UTC)
+	ref := time.Date(2024, time.March, 9, 8, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		cursor entity.PostCursor
+		want   entity.PostCursor
+	}{
+		{
+			name:   "Newest first",
+			cursor: entity.PostCursor{CreatedAt: createdAt, ID: 42, Ref: ref},
+			want:   entity.PostCursor{CreatedAt: createdAt, ID: 42, Ref: ref},
+		},
+		{
+			name:   "Negative score",
+			cursor: entity.PostCursor{Score: -3, CreatedAt: createdAt, ID: 1, Ref: ref},
+			want:   entity.PostCursor{Score: -3, CreatedAt: createdAt, ID: 1, Ref: ref},
+		},
+		{
+			name:   "Fractional score is kept exactly",
+			cursor: entity.PostCursor{Score: 0.1 + 0.2, CreatedAt: createdAt, ID: 1, Ref: ref},
+			want:   entity.PostCursor{Score: 0.1 + 0.2, CreatedAt: createdAt, ID: 1, Ref: ref},
+		},
+		{
+			name:   "Fraction of second is dropped",
+			cursor: entity.PostCursor{CreatedAt: createdAt.Add(999), ID: 7, Ref: ref.Add(999)},
+			want:   entity.PostCursor{CreatedAt: createdAt, ID: 7, Ref: ref},
+		},
+		{
+			name:   "Big id",
+			cursor: entity.PostCursor{CreatedAt: createdAt, ID: 1<<31 - 1, Ref: ref},
+			want:   entity.PostCursor{CreatedAt: createdAt, ID: 1<<31 - 1, Ref: ref},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeCursor(EncodeCursor(tt.cursor))
+			assert.Equal(t, err, nil)
+			assert.Equal(t, got.Score, tt.want.Score)
+			assert.Equal(t, got.CreatedAt, tt.want.CreatedAt)
+			assert.Equal(t, got.ID, tt.want.ID)
+			assert.Equal(t, got.Ref.Equal(tt.want.Ref), true)
+		})
+	}
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	encode := func(raw string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(raw))
+	}
+
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{name: "Not base64", cursor: "!!!"},
+		{name: "Padded base64", cursor: base64.URLEncoding.EncodeToString([]byte("1700000000~5~0~1700000000"))},
+		{name: "Too few parts", cursor: encode("1700000000~5~0")},
+		{name: "Too many parts", cursor: encode("1700000000~5~0~1700000000~1")},
+		{name: "Empty parts", cursor: encode("~~~")},
+		{name: "Time is not a number", cursor: encode("yesterday~5~0~1700000000")},
+		{name: "Id is not a number", cursor: encode("1700000000~five~0~1700000000")},
+		{name: "Zero id", cursor: encode("1700000000~0~0~1700000000")},
+		{name: "Negative id", cursor: encode("1700000000~-5~0~1700000000")},
+		{name: "Score is not a number", cursor: encode("1700000000~5~high~1700000000")},
+		{name: "Reference time is not a number", cursor: encode("1700000000~5~0~now")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DecodeCursor(tt.cursor)
+			assert.Equal(t, errors.Is(err, entity.ErrInvalidCursor), true)
+		})
+	}
+}
+
+func TestNewPageRequest(t *testing.T) {
+	ref := time.Date(2024, time.March, 8, 0, 0, 0, 0, time.UTC)
+	cursor := EncodeCursor(entity.PostCursor{CreatedAt: ref, ID: 3, Ref: ref})
+
+	tests := []struct {
+		name         string
+		params       entity.PageParams
+		wantErr      error
+		wantLimit    int
+		wantCursor   bool
+		wantBackward bool
+		wantSort     string
+		wantPeriod   string
+	}{
+		{
+			name:      "First page",
+			params:    entity.PageParams{},
+			wantLimit: DefaultPageSize + 1,
+		},
+		{
+			name:       "Top posts of the week by default",
+			params:     entity.PageParams{Sort: entity.SORT_TOP},
+			wantLimit:  DefaultPageSize + 1,
+			wantSort:   entity.SORT_TOP,
+			wantPeriod: entity.PERIOD_WEEK,
+		},
+		{
+			name:      "Period is dropped for other sorts",
+			params:    entity.PageParams{Sort: entity.SORT_HOT, Period: entity.PERIOD_DAY},
+			wantLimit: DefaultPageSize + 1,
+			wantSort:  entity.SORT_HOT,
+		},
+		{
+			name:    "Unknown sort",
+			params:  entity.PageParams{Sort: "random"},
+			wantErr: entity.ErrInvalidSort,
+		},
+		{
+			name:    "Unknown period",
+			params:  entity.PageParams{Sort: entity.SORT_TOP, Period: "decade"},
+			wantErr: entity.ErrInvalidSort,
+		},
+		{
+			name:      "Custom limit",
+			params:    entity.PageParams{Limit: 5},
+			wantLimit: 6,
+		},
+		{
+			name:      "Too big limit",
+			params:    entity.PageParams{Limit: maxPageSize + 1},
+			wantLimit: DefaultPageSize + 1,
+		},
+		{
+			name:       "Next page",
+			params:     entity.PageParams{After: cursor},
+			wantLimit:  DefaultPageSize + 1,
+			wantCursor: true,
+		},
+		{
+			name:         "Previous page",
+			params:       entity.PageParams{Before: cursor},
+			wantLimit:    DefaultPageSize + 1,
+			wantCursor:   true,
+			wantBackward: true,
+		},
+		{
+			name:    "Both directions",
+			params:  entity.PageParams{After: cursor, Before: cursor},
+			wantErr: entity.ErrInvalidCursor,
+		},
+		{
+			name:    "Bad cursor",
+			params:  entity.PageParams{After: "!!!"},
+			wantErr: entity.ErrInvalidCursor,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := NewPageRequest(tt.params)
+			assert.Equal(t, err, tt.wantErr)
+			if tt.wantErr != nil {
+				return
+			}
+
+			wantSort := tt.wantSort
+			if wantSort == "" {
+				wantSort = entity.SORT_NEW
+			}
+
+			assert.Equal(t, req.Limit, tt.wantLimit)
+			assert.Equal(t, req.Cursor != nil, tt.wantCursor)
+			assert.Equal(t, req.Backward, tt.wantBackward)
+			assert.Equal(t, req.Sort, wantSort)
+			assert.Equal(t, req.Period, tt.wantPeriod)
+
+			// Reference time is taken from cursor, so it is the same
+			// on all pages
+			if tt.wantCursor {
+				assert.Equal(t, req.Ref.Equal(ref), true)
+			}
+		})
+	}
+}
+
+func TestConvertPage(t *testing.T) {
+	older := time.Date(2024, time.March, 7, 0, 0, 0, 0, time.UTC)
+	tie := time.Date(2024, time.March, 8, 0, 0, 0, 0, time.UTC)
+	ref := time.Date(2024, time.March, 9, 0, 0, 0, 0, time.UTC)
+
+	// Posts 4, 3 and 2 have the same score and are created at the same
+	// second, so only id tells them apart
+	posts := func(ids ...int) *[]entity.PostEntity {
+		var res []entity.PostEntity
+		for _, id := range ids {
+			p := entity.PostEntity{ID: id, CreatedAt: tie, Score: 2}
+			if id == 1 {
+				p.CreatedAt, p.Score = older, 1
+			}
+			res = append(res, p)
+		}
+		return &res
+	}
+	cursor := func(id int) string {
+		c := entity.PostCursor{Score: 2, CreatedAt: tie, ID: id, Ref: ref}
+		if id == 1 {
+			c.CreatedAt, c.Score = older, 1
+		}
+		return EncodeCursor(c)
+	}
+	someCursor := &entity.PostCursor{Score: 2, CreatedAt: tie, ID: 5, Ref: ref}
+
+	tests := []struct {
+		name     string
+		posts    *[]entity.PostEntity
+		req      entity.PageRequest
+		wantIDs  []int
+		wantNext string
+		wantPrev string
+	}{
+		{
+			name:     "First page with more posts",
+			posts:    posts(4, 3, 2),
+			req:      entity.PageRequest{Limit: 3, Ref: ref},
+			wantIDs:  []int{4, 3},
+			wantNext: cursor(3),
+		},
+		{
+			name:    "Only page",
+			posts:   posts(4, 3),
+			req:     entity.PageRequest{Limit: 3, Ref: ref},
+			wantIDs: []int{4, 3},
+		},
+		{
+			name:     "Middle page",
+			posts:    posts(3, 2, 1),
+			req:      entity.PageRequest{Cursor: someCursor, Limit: 3, Ref: ref},
+			wantIDs:  []int{3, 2},
+			wantNext: cursor(2),
+			wantPrev: cursor(3),
+		},
+		{
+			name:     "Last page",
+			posts:    posts(2, 1),
+			req:      entity.PageRequest{Cursor: someCursor, Limit: 3, Ref: ref},
+			wantIDs:  []int{2, 1},
+			wantPrev: cursor(2),
+		},
+		{
+			name:     "Previous page with more posts",
+			posts:    posts(4, 3, 2),
+			req:      entity.PageRequest{Cursor: someCursor, Backward: true, Limit: 3, Ref: ref},
+			wantIDs:  []int{3, 2},
+			wantNext: cursor(2),
+			wantPrev: cursor(3),
+		},
+		{
+			name:     "Previous page is the first one",
+			posts:    posts(3, 2),
+			req:      entity.PageRequest{Cursor: someCursor, Backward: true, Limit: 3, Ref: ref},
+			wantIDs:  []int{3, 2},
+			wantNext: cursor(2),
+		},
+		{
+			name:    "Empty page",
+			posts:   posts(),
+			req:     entity.PageRequest{Cursor: someCursor, Limit: 3, Ref: ref},
+			wantIDs: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			views, page, err := convertPage(tt.posts, tt.req)
+			assert.Equal(t, err, nil)
+
+			var ids []int
+			for _, v := range *views {
+				ids = append(ids, v.ID)
+			}
+			assert.Equal(t, len(ids), len(tt.wantIDs))
+			for i := range tt.wantIDs {
+				if i < len(ids) {
+					assert.Equal(t, ids[i], tt.wantIDs[i])
+				}
+			}
+
+			assert.Equal(t, page.Next, tt.wantNext)
+			assert.Equal(t, page.Prev, tt.wantPrev)
+		})
+	}
+}
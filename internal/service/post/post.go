@@ -6,6 +6,7 @@ import (
 	"forum/internal/repository/post"
 	"forum/internal/service/comment"
 	"forum/internal/service/image"
+	"forum/internal/service/moderation"
 	"forum/internal/service/tag"
 	"forum/internal/service/user"
 	"strconv"
@@ -14,17 +15,18 @@ import (
 type IPostService interface {
 	SavePost(entity.PostCreateForm) (int, error)
 	GetPost(int) (entity.PostView, error)
-	GetAllPosts() (*[]entity.PostView, error)
-	GetAllPostsByTagId(int) (*[]entity.PostView, error)
-	GetAllPostsByUserId(int) (*[]entity.PostView, error)
-	GetAllPostsByUserReaction(int) (*[]entity.PostView, error)
-	GetAllCommentedPostsWithComments(userID int) (*[]entity.PostView, *[][]entity.CommentView, error)
+	GetAllPosts(entity.PageParams) (*[]entity.PostView, entity.Page, error)
+	GetAllPostsByTagId(int, entity.PageParams) (*[]entity.PostView, entity.Page, error)
+	GetAllPostsByUserId(int, entity.PageParams) (*[]entity.PostView, entity.Page, error)
+	GetAllPostsByUserReaction(int, entity.PageParams) (*[]entity.PostView, entity.Page, error)
+	GetAllCommentedPostsWithComments(userID int, p entity.PageParams) (*[]entity.PostView, *[][]entity.CommentView, entity.Page, error)
 	ExistsPost(postID int) (bool, error)
 	CheckPostAttrs(*entity.PostCreateForm, bool) (bool, error)
 	DeletePost(postID int, userID int) error
-	DeletePostPrivileged(postID int, userID int, userRole string) error
+	DeletePostPrivileged(postID int, userID int, userRole, reason string) error
 	GetAuthorID(postID int) (int, error)
 	UpdatePost(p entity.PostCreateForm, deleteImageStr string) error
+	GetPostHistory(postID, userID int, userRole string, from, to int) (entity.PostHistory, error)
 }
 
 type postService struct {
@@ -82,7 +84,9 @@ func (ps *postService) GetPost(postId int) (entity.PostView, error) {
 		ID:          post.ID,
 		Title:       post.Title,
 		Content:     post.Content,
+		ContentHTML: renderer.HTML(post.Content),
 		CreatedAt:   post.CreatedAt,
+		UpdatedAt:   post.UpdatedAt,
 		Username:    post.Username,
 		Likes:       post.Likes,
 		Dislikes:    post.Dislikes,
@@ -94,61 +98,86 @@ func (ps *postService) GetPost(postId int) (entity.PostView, error) {
 	return pView, nil
 }
 
-func (ps *postService) GetAllPosts() (*[]entity.PostView, error) {
-	posts, err := ps.postRepo.GetAll()
+func (ps *postService) GetAllPosts(p entity.PageParams) (*[]entity.PostView, entity.Page, error) {
+	req, err := NewPageRequest(p)
 	if err != nil {
-		return nil, err
+		return nil, entity.Page{}, err
 	}
 
-	return ConvertEntitiesToViews(posts)
+	posts, err := ps.postRepo.GetAll(req)
+	if err != nil {
+		return nil, entity.Page{}, err
+	}
+
+	return convertPage(posts, req)
 }
 
-func (ps *postService) GetAllPostsByTagId(tagID int) (*[]entity.PostView, error) {
-	posts, err := ps.postRepo.GetAllByTagId(tagID)
+func (ps *postService) GetAllPostsByTagId(tagID int, p entity.PageParams) (*[]entity.PostView, entity.Page, error) {
+	req, err := NewPageRequest(p)
+	if err != nil {
+		return nil, entity.Page{}, err
+	}
+
+	posts, err := ps.postRepo.GetAllByTagId(tagID, req)
 	if err != nil {
-		return nil, err
+		return nil, entity.Page{}, err
 	}
 
-	return ConvertEntitiesToViews(posts)
+	return convertPage(posts, req)
 }
 
-func (ps *postService) GetAllPostsByUserId(userID int) (*[]entity.PostView, error) {
-	posts, err := ps.postRepo.GetAllByUserID(userID)
+func (ps *postService) GetAllPostsByUserId(userID int, p entity.PageParams) (*[]entity.PostView, entity.Page, error) {
+	req, err := NewPageRequest(p)
 	if err != nil {
-		return nil, err
+		return nil, entity.Page{}, err
 	}
 
-	return ConvertEntitiesToViews(posts)
+	posts, err := ps.postRepo.GetAllByUserID(userID, req)
+	if err != nil {
+		return nil, entity.Page{}, err
+	}
+
+	return convertPage(posts, req)
 }
 
-func (ps *postService) GetAllPostsByUserReaction(userID int) (*[]entity.PostView, error) {
-	posts, err := ps.postRepo.GetAllByUserReaction(userID)
+func (ps *postService) GetAllPostsByUserReaction(userID int, p entity.PageParams) (*[]entity.PostView, entity.Page, error) {
+	req, err := NewPageRequest(p)
+	if err != nil {
+		return nil, entity.Page{}, err
+	}
+
+	posts, err := ps.postRepo.GetAllByUserReaction(userID, req)
 	if err != nil {
-		return nil, err
+		return nil, entity.Page{}, err
 	}
 
-	return ConvertEntitiesToViews(posts)
+	return convertPage(posts, req)
 }
 
-func (ps *postService) GetAllCommentedPostsWithComments(userID int) (*[]entity.PostView, *[][]entity.CommentView, error) {
-	postsEntities, err := ps.postRepo.GetAllCommentedPosts(userID)
+func (ps *postService) GetAllCommentedPostsWithComments(userID int, p entity.PageParams) (*[]entity.PostView, *[][]entity.CommentView, entity.Page, error) {
+	req, err := NewPageRequest(p)
+	if err != nil {
+		return nil, nil, entity.Page{}, err
+	}
+
+	postsEntities, err := ps.postRepo.GetAllCommentedPosts(userID, req)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, entity.Page{}, err
 	}
-	posts, _ := ConvertEntitiesToViews(postsEntities)
+	posts, page, _ := convertPage(postsEntities, req)
 
 	var allComments [][]entity.CommentView
 
 	for _, p := range *posts {
 		comments, err := ps.commentService.GetAllUserCommentsForPost(userID, p.ID)
 		if err != nil {
-			return nil, nil, err
+			return nil, nil, entity.Page{}, err
 		}
 
 		allComments = append(allComments, *comments)
 	}
 
-	return posts, &allComments, nil
+	return posts, &allComments, page, nil
 }
 
 func (ps *postService) ExistsPost(postID int) (bool, error) {
@@ -180,7 +209,10 @@ func (ps *postService) DeletePost(postID int, userID int) error {
 	return ps.postRepo.Delete(postID, userID)
 }
 
-func (ps *postService) DeletePostPrivileged(postID int, userID int, userRole string) error {
+// DeletePostPrivileged deletes post of another user by moderator or admin
+// and notifies the author. Reason is shown to the author, moderators that
+// don't give it delete posts as obscene
+func (ps *postService) DeletePostPrivileged(postID int, userID int, userRole, reason string) error {
 	exists, err := ps.postRepo.Exists(postID)
 	if err != nil {
 		return err
@@ -194,7 +226,17 @@ func (ps *postService) DeletePostPrivileged(postID int, userID int, userRole str
 		return err
 	}
 
-	err = ps.postRepo.DeleteByPrivileged(postID)
+	before, err := ps.postRepo.Get(postID)
+	if err != nil {
+		return err
+	}
+
+	e, err := moderation.NewEntry(userID, entity.ACTION_DELETE_POST, entity.POST, postID, reason, before)
+	if err != nil {
+		return err
+	}
+
+	err = ps.postRepo.DeleteByPrivileged(postID, e)
 	if err != nil {
 		return err
 	}
@@ -205,7 +247,9 @@ func (ps *postService) DeletePostPrivileged(postID int, userID int, userRole str
 		UserFrom: userID,
 		UserTo:   authorID,
 	}
-	if userRole == entity.MODERATOR {
+	if reason != "" {
+		notificaiton.Content = ". Reason: " + reason
+	} else if userRole == entity.MODERATOR {
 		notificaiton.Content = ". Reason: obscene"
 	}
 
@@ -240,3 +284,45 @@ func (ps *postService) UpdatePost(p entity.PostCreateForm, deleteImageStr string
 
 	return nil
 }
+
+// GetPostHistory returns revisions of post with changes made between given
+// revisions. By default the last edit is shown. History is available only to
+// post author, moderators and admins
+func (ps *postService) GetPostHistory(postID, userID int, userRole string, from, to int) (entity.PostHistory, error) {
+	authorID, err := ps.postRepo.GetAuthorID(postID)
+	if err != nil {
+		return entity.PostHistory{}, err
+	}
+	if authorID != userID && userRole != entity.MODERATOR && userRole != entity.ADMIN {
+		return entity.PostHistory{}, entity.ErrForbiddenAccess
+	}
+
+	post, err := ps.GetPost(postID)
+	if err != nil {
+		return entity.PostHistory{}, err
+	}
+
+	revisions, err := ps.postRepo.GetRevisions(postID)
+	if err != nil {
+		return entity.PostHistory{}, err
+	}
+	if len(*revisions) == 0 {
+		return entity.PostHistory{}, entity.ErrRevisionNotFound
+	}
+
+	for i := range *revisions {
+		(*revisions)[i].Number = i + 1
+	}
+
+	if to == 0 {
+		to = len(*revisions)
+	}
+	if from == 0 {
+		from = max(to-1, 1)
+	}
+	if from > len(*revisions) || to > len(*revisions) {
+		return entity.PostHistory{}, entity.ErrRevisionNotFound
+	}
+
+	return BuildHistory(post, *revisions, from, to), nil
+}
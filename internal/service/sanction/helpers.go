@@ -0,0 +1,52 @@
+package sanction
+
+import (
+	"fmt"
+	"forum/internal/entity"
+	"forum/internal/validator"
+)
+
+const (
+	maxReasonLen = 500
+	maxDays      = 365
+)
+
+func IsRightSanction(f *entity.SanctionForm) bool {
+	f.CheckField(validator.NotBlank(f.Reason), "reason", "This field cannot be blank")
+	f.CheckField(validator.MaxChar(f.Reason, maxReasonLen), "reason", fmt.Sprintf("Maximum characters length exceeded - %d", maxReasonLen))
+
+	switch f.Kind {
+	case entity.SANCTION_WARNING:
+	case entity.SANCTION_MUTE:
+		f.CheckField(f.Days > 0 && f.Days <= maxDays, "days", fmt.Sprintf("Mute lasts from 1 to %d days", maxDays))
+	case entity.SANCTION_BAN:
+		f.CheckField(f.Days >= 0 && f.Days <= maxDays, "days", fmt.Sprintf("Ban lasts up to %d days, 0 is permanent ban", maxDays))
+	default:
+		f.AddFieldError("kind", "Unknown sanction")
+	}
+
+	return f.Valid()
+}
+
+// term describes how long sanction lasts for notifications and messages
+func term(s entity.Sanction) string {
+	if s.ExpiresAt.IsZero() {
+		return "permanently"
+	}
+	return "until " + s.ExpiresAt.Format("02 Jan 2006 15:04")
+}
+
+// outlasts reports whether sanction s ends later than other one. Zero
+// sanction is outlasted by any sanction, permanent one outlasts any other
+func outlasts(s, other entity.Sanction) bool {
+	switch {
+	case other.ID == 0:
+		return true
+	case other.ExpiresAt.IsZero():
+		return false
+	case s.ExpiresAt.IsZero():
+		return true
+	default:
+		return s.ExpiresAt.After(other.ExpiresAt)
+	}
+}
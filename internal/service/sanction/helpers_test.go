@@ -0,0 +1,44 @@
+package sanction
+
+import (
+	"forum/internal/assert"
+	"forum/internal/entity"
+	"testing"
+	"time"
+)
+
+func TestIsRightSanction(t *testing.T) {
+	tests := []struct {
+		kind   string
+		days   int
+		reason string
+		want   bool
+	}{
+		{entity.SANCTION_WARNING, 0, "spam", true},
+		{entity.SANCTION_WARNING, 0, " ", false},
+		{entity.SANCTION_MUTE, 3, "flood", true},
+		{entity.SANCTION_MUTE, 0, "flood", false},
+		{entity.SANCTION_MUTE, 366, "flood", false},
+		{entity.SANCTION_BAN, 0, "bot", true},
+		{entity.SANCTION_BAN, -1, "bot", false},
+		{"kick", 0, "bot", false},
+	}
+
+	for _, tt := range tests {
+		f := entity.SanctionForm{Kind: tt.kind, Days: tt.days, Reason: tt.reason}
+		assert.Equal(t, IsRightSanction(&f), tt.want)
+	}
+}
+
+func TestOutlasts(t *testing.T) {
+	now := time.Now()
+	short := entity.Sanction{ID: 1, ExpiresAt: now.Add(time.Hour)}
+	long := entity.Sanction{ID: 2, ExpiresAt: now.Add(24 * time.Hour)}
+	permanent := entity.Sanction{ID: 3}
+
+	assert.Equal(t, outlasts(short, entity.Sanction{}), true)
+	assert.Equal(t, outlasts(long, short), true)
+	assert.Equal(t, outlasts(short, long), false)
+	assert.Equal(t, outlasts(permanent, long), true)
+	assert.Equal(t, outlasts(long, permanent), false)
+}
@@ -0,0 +1,180 @@
+package sanction
+
+import (
+	"errors"
+	"forum/internal/entity"
+	"forum/internal/repository/sanction"
+	"forum/internal/service/moderation"
+	"forum/internal/service/user"
+	"time"
+)
+
+type ISanctionService interface {
+	Issue(f *entity.SanctionForm, issuerID int) error
+	Revoke(actorID, sanctionID int) error
+	GetRestrictions(userID int) (entity.Restrictions, error)
+	GetCurrent() (map[int][]entity.Sanction, error)
+	Describe(s entity.Sanction) string
+}
+
+type sanctionService struct {
+	sanctionRepo sanction.ISanctionRepository
+	userService  user.IUserService
+}
+
+func NewSanctionService(s sanction.ISanctionRepository, u user.IUserService) *sanctionService {
+	return &sanctionService{
+		sanctionRepo: s,
+		userService:  u,
+	}
+}
+
+var _ ISanctionService = (*sanctionService)(nil)
+
+// Issue saves sanction and notifies the user about it. Admins and the
+// issuer themselves can't be sanctioned
+func (ss *sanctionService) Issue(f *entity.SanctionForm, issuerID int) error {
+	if !IsRightSanction(f) {
+		return entity.ErrInvalidFormData
+	}
+
+	if _, err := ss.userService.GetUsernameById(f.UserID); err != nil {
+		if errors.Is(err, entity.ErrInvalidCredentials) {
+			return entity.ErrUserNotFound
+		}
+		return err
+	}
+
+	role, err := ss.userService.GetUserRole(f.UserID)
+	if err != nil {
+		return err
+	}
+	if role == entity.ADMIN || f.UserID == issuerID {
+		return entity.ErrCannotSanction
+	}
+
+	s := entity.Sanction{
+		UserID:   f.UserID,
+		IssuedBy: issuerID,
+		Kind:     f.Kind,
+		Reason:   f.Reason,
+	}
+
+	var d time.Duration
+	if f.Kind != entity.SANCTION_WARNING && f.Days > 0 {
+		d = time.Duration(f.Days) * 24 * time.Hour
+		s.ExpiresAt = time.Now().Add(d)
+	}
+
+	// Sanctions the user already has are saved in moderation log
+	before, err := ss.sanctionRepo.GetCurrentByUser(f.UserID)
+	if err != nil {
+		return err
+	}
+
+	var action string
+	switch f.Kind {
+	case entity.SANCTION_WARNING:
+		action = entity.ACTION_WARN_USER
+	case entity.SANCTION_MUTE:
+		action = entity.ACTION_MUTE_USER
+	case entity.SANCTION_BAN:
+		action = entity.ACTION_BAN_USER
+	}
+
+	e, err := moderation.NewEntry(issuerID, action, entity.TARGET_USER, f.UserID, f.Reason, before)
+	if err != nil {
+		return err
+	}
+
+	if _, err := ss.sanctionRepo.Insert(s, d, e); err != nil {
+		return err
+	}
+
+	n := entity.Notification{
+		UserFrom: issuerID,
+		UserTo:   f.UserID,
+		Content:  ". Reason: " + f.Reason,
+	}
+
+	switch f.Kind {
+	case entity.SANCTION_WARNING:
+		n.Type = entity.WARNED
+	case entity.SANCTION_MUTE:
+		n.Type = entity.MUTED
+		n.Content = " " + term(s) + n.Content
+	case entity.SANCTION_BAN:
+		n.Type = entity.BANNED
+		n.Content = " " + term(s) + n.Content
+	}
+
+	return ss.userService.SendNotification(n)
+}
+
+func (ss *sanctionService) Revoke(actorID, sanctionID int) error {
+	s, err := ss.sanctionRepo.Get(sanctionID)
+	if err != nil {
+		return err
+	}
+
+	e, err := moderation.NewEntry(actorID, entity.ACTION_REVOKE_SANCTION, entity.TARGET_SANCTION, sanctionID, "", s)
+	if err != nil {
+		return err
+	}
+
+	return ss.sanctionRepo.Revoke(sanctionID, e)
+}
+
+// GetRestrictions returns ban and mute of the user that are in force. The
+// longest one is returned if there are several of them
+func (ss *sanctionService) GetRestrictions(userID int) (entity.Restrictions, error) {
+	var r entity.Restrictions
+
+	sanctions, err := ss.sanctionRepo.GetCurrentByUser(userID)
+	if err != nil {
+		return r, err
+	}
+
+	for _, s := range sanctions {
+		switch s.Kind {
+		case entity.SANCTION_BAN:
+			if outlasts(s, r.Ban) {
+				r.Ban = s
+			}
+		case entity.SANCTION_MUTE:
+			if outlasts(s, r.Mute) {
+				r.Mute = s
+			}
+		}
+	}
+
+	return r, nil
+}
+
+// GetCurrent returns sanctions that are neither revoked nor expired by id
+// of the user
+func (ss *sanctionService) GetCurrent() (map[int][]entity.Sanction, error) {
+	sanctions, err := ss.sanctionRepo.GetCurrent()
+	if err != nil {
+		return nil, err
+	}
+
+	byUser := make(map[int][]entity.Sanction)
+	for _, s := range sanctions {
+		byUser[s.UserID] = append(byUser[s.UserID], s)
+	}
+
+	return byUser, nil
+}
+
+// Describe explains ban or mute to the sanctioned user
+func (ss *sanctionService) Describe(s entity.Sanction) string {
+	switch s.Kind {
+	case entity.SANCTION_BAN:
+		return "Your account is banned " + term(s) + ". Reason: " + s.Reason
+	case entity.SANCTION_MUTE:
+		return "You can't post, comment or react " + term(s) + ". Reason: " + s.Reason
+	default:
+		return "You've got a warning. Reason: " + s.Reason
+	}
+}
@@ -0,0 +1,70 @@
+package search
+
+import (
+	"fmt"
+	"forum/internal/entity"
+	"forum/internal/repository/search"
+	"forum/internal/validator"
+	"html"
+	"html/template"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	PageSize    = 20
+	maxQueryLen = 100
+	maxTerms    = 10
+	dateLayout  = "2006-01-02"
+)
+
+func IsRightQuery(q *entity.SearchQuery) bool {
+	q.CheckField(validator.NotBlank(q.Query), "q", "This field cannot be blank")
+	q.CheckField(validator.MaxChar(q.Query, maxQueryLen), "q", fmt.Sprintf("Maximum characters length exceeded - %d", maxQueryLen))
+	q.CheckField(isValidDate(q.From), "from", "Date should be in YYYY-MM-DD format")
+	q.CheckField(isValidDate(q.To), "to", "Date should be in YYYY-MM-DD format")
+
+	if q.From != "" && q.To != "" && q.From > q.To {
+		q.AddNonFieldError("Start date should not be after end date")
+	}
+
+	return q.Valid()
+}
+
+// BuildMatchExpr converts user input into safe FTS5 match expression: every
+// word becomes quoted prefix term and all terms are required to match.
+// FTS5 operators and special characters are thus never interpreted
+func BuildMatchExpr(query string) string {
+	words := strings.FieldsFunc(query, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+
+	if len(words) > maxTerms {
+		words = words[:maxTerms]
+	}
+
+	terms := make([]string, 0, len(words))
+	for _, word := range words {
+		terms = append(terms, `"`+word+`"*`)
+	}
+
+	return strings.Join(terms, " ")
+}
+
+// Highlight escapes snippet text and replaces match markers with <mark> tags
+func Highlight(snippet string) template.HTML {
+	escaped := html.EscapeString(snippet)
+
+	replacer := strings.NewReplacer(search.MarkStart, "<mark>", search.MarkEnd, "</mark>")
+
+	return template.HTML(replacer.Replace(escaped))
+}
+
+func isValidDate(date string) bool {
+	if date == "" {
+		return true
+	}
+	_, err := time.Parse(dateLayout, date)
+	return err == nil
+}
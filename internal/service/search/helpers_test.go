@@ -0,0 +1,54 @@
+package search
+
+import (
+	"forum/internal/assert"
+	"forum/internal/repository/search"
+	"html/template"
+	"testing"
+)
+
+func TestBuildMatchExpr(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{
+			name:  "Single word",
+			query: "rabbit",
+			want:  `"rabbit"*`,
+		},
+		{
+			name:  "Several words",
+			query: "  white   rabbit ",
+			want:  `"white"* "rabbit"*`,
+		},
+		{
+			name:  "FTS syntax is dropped",
+			query: `title:"rabbit" OR NEAR(a b) -c*`,
+			want:  `"title"* "rabbit"* "OR"* "NEAR"* "a"* "b"* "c"*`,
+		},
+		{
+			name:  "Unicode",
+			query: "кролик, 2024",
+			want:  `"кролик"* "2024"*`,
+		},
+		{
+			name:  "No words",
+			query: `"" ** ()`,
+			want:  "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, BuildMatchExpr(tt.query), tt.want)
+		})
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	snippet := "<b>" + search.MarkStart + "rabbit" + search.MarkEnd + "</b> & co"
+
+	assert.Equal(t, Highlight(snippet), template.HTML("&lt;b&gt;<mark>rabbit</mark>&lt;/b&gt; &amp; co"))
+}
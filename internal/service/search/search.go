@@ -0,0 +1,72 @@
+package search
+
+import (
+	"forum/internal/entity"
+	"forum/internal/repository/search"
+	"strings"
+)
+
+type ISearchService interface {
+	Search(q *entity.SearchQuery) (*[]entity.SearchResultView, error)
+}
+
+type searchService struct {
+	searchRepo search.ISearchRepository
+}
+
+var _ ISearchService = (*searchService)(nil)
+
+func NewSearchService(r search.ISearchRepository) *searchService {
+	return &searchService{
+		searchRepo: r,
+	}
+}
+
+// Search validates given query and returns one page of matching posts and
+// comments sorted by relevance. Query is accepted by pointer for form error
+// messages handling
+func (ss *searchService) Search(q *entity.SearchQuery) (*[]entity.SearchResultView, error) {
+	q.Query = strings.TrimSpace(q.Query)
+	q.Author = strings.TrimSpace(q.Author)
+
+	if !IsRightQuery(q) {
+		return nil, entity.ErrInvalidFormData
+	}
+
+	match := BuildMatchExpr(q.Query)
+	if match == "" {
+		q.AddFieldError("q", "Query should contain at least one letter or digit")
+		return nil, entity.ErrInvalidFormData
+	}
+
+	if q.Page < 1 {
+		q.Page = 1
+	}
+	// One extra row is requested to find out whether there is next page
+	q.Limit = PageSize + 1
+	q.Offset = (q.Page - 1) * PageSize
+
+	results, err := ss.searchRepo.Search(*q, match)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(*results) > PageSize {
+		q.HasNext = true
+		*results = (*results)[:PageSize]
+	}
+
+	views := make([]entity.SearchResultView, 0, len(*results))
+	for _, res := range *results {
+		views = append(views, entity.SearchResultView{
+			PostID:    res.PostID,
+			CommentID: res.CommentID,
+			Title:     res.Title,
+			Snippet:   Highlight(res.Snippet),
+			Username:  res.Username,
+			CreatedAt: res.CreatedAt,
+		})
+	}
+
+	return &views, nil
+}
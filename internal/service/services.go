@@ -2,33 +2,60 @@ package service
 
 import (
 	"forum/internal/repository"
+	"forum/internal/service/account"
 	"forum/internal/service/comment"
+	"forum/internal/service/identity"
 	"forum/internal/service/image"
+	"forum/internal/service/lockout"
+	"forum/internal/service/moderation"
 	"forum/internal/service/post"
 	"forum/internal/service/reaction"
+	"forum/internal/service/sanction"
+	"forum/internal/service/search"
 	"forum/internal/service/tag"
+	"forum/internal/service/twofactor"
 	"forum/internal/service/user"
+	"forum/pkg/mailer"
 )
 
 type Services struct {
-	Post     post.IPostService
-	User     user.IUserService
-	Comment  comment.ICommentService
-	Reaction reaction.IReactionService
-	Tag      tag.ITagService
-	Image    image.IImageService
+	Post       post.IPostService
+	User       user.IUserService
+	Comment    comment.ICommentService
+	Reaction   reaction.IReactionService
+	Tag        tag.ITagService
+	Image      image.IImageService
+	Search     search.ISearchService
+	Account    account.IAccountService
+	Identity   identity.IIdentityService
+	TwoFactor  twofactor.ITwoFactorService
+	Lockout    lockout.ILockoutService
+	Sanction   sanction.ISanctionService
+	Moderation moderation.IModerationService
 }
 
-func New(r *repository.Repositories) *Services {
-	postService := post.NewPostsService(r.Post, image.NewImageService(r.Image), tag.NewTagService(r.Tag), comment.NewCommentService(r.Comment, user.NewUserService(r.User)), user.NewUserService(r.User))
-	commentService := comment.NewCommentService(r.Comment, user.NewUserService(r.User))
+// New returns all services. Mailer is used to send emails to users, baseURL
+// is the public address of the site that is used in links of those emails,
+// appName is shown by authenticator apps next to two-factor codes
+func New(r *repository.Repositories, m mailer.Mailer, baseURL, appName string) *Services {
 	userService := user.NewUserService(r.User)
+	tagService := tag.NewTagService(r.Tag)
+	imageService := image.NewImageService(r.Image)
+	commentService := comment.NewCommentService(r.Comment, userService)
+	postService := post.NewPostsService(r.Post, imageService, tagService, commentService, userService)
 	return &Services{
-		Post:     postService,
-		User:     userService,
-		Comment:  commentService,
-		Reaction: reaction.NewReactionService(r.Reaction, postService, commentService, userService),
-		Tag:      tag.NewTagService(r.Tag),
-		Image:    image.NewImageService(r.Image),
+		Post:       postService,
+		User:       userService,
+		Comment:    commentService,
+		Reaction:   reaction.NewReactionService(r.Reaction, postService, commentService, userService),
+		Tag:        tagService,
+		Image:      imageService,
+		Search:     search.NewSearchService(r.Search),
+		Account:    account.NewAccountService(r.Token, r.User, m, baseURL),
+		Identity:   identity.NewIdentityService(r.Identity, r.User),
+		TwoFactor:  twofactor.NewTwoFactorService(r.TwoFactor, r.Setting, appName),
+		Lockout:    lockout.NewLockoutService(r.Lockout, userService),
+		Sanction:   sanction.NewSanctionService(r.Sanction, userService),
+		Moderation: moderation.NewModerationService(r.Moderation),
 	}
 }
@@ -3,6 +3,7 @@ package tag
 import (
 	"forum/internal/entity"
 	"forum/internal/repository/tag"
+	"forum/internal/service/moderation"
 	"strconv"
 )
 
@@ -10,8 +11,8 @@ type ITagService interface {
 	GetAllTags() (*[]entity.TagEntity, error)
 	AreTagsExist([]string) (bool, error)
 	IsExist(int) (bool, error)
-	DeleteTag(tagID int) error
-	CreateTag(tag string) error
+	DeleteTag(actorID, tagID int) error
+	CreateTag(actorID int, tag string) error
 }
 
 type tagService struct {
@@ -47,14 +48,31 @@ func (ts *tagService) IsExist(id int) (bool, error) {
 	return ts.tagRepo.IsExist(id)
 }
 
-func (ts *tagService) DeleteTag(tagID int) error {
-	return ts.tagRepo.Delete(tagID)
+func (ts *tagService) DeleteTag(actorID, tagID int) error {
+	tag, err := ts.tagRepo.Get(tagID)
+	if err != nil {
+		return err
+	}
+
+	e, err := moderation.NewEntry(actorID, entity.ACTION_DELETE_TAG, entity.TARGET_TAG, tagID, "", tag)
+	if err != nil {
+		return err
+	}
+
+	return ts.tagRepo.Delete(tagID, e)
 }
 
-func (ts *tagService) CreateTag(tag string) error {
+// CreateTag saves new tag. Actor 0 is the command line
+func (ts *tagService) CreateTag(actorID int, tag string) error {
 	if !IsValidTag(tag) {
 		return entity.ErrInvalidTag
 	}
 
-	return ts.tagRepo.Create(tag)
+	// Id of the new tag is set by repository
+	e, err := moderation.NewEntry(actorID, entity.ACTION_CREATE_TAG, entity.TARGET_TAG, 0, "", nil)
+	if err != nil {
+		return err
+	}
+
+	return ts.tagRepo.Create(tag, e)
 }
@@ -0,0 +1,74 @@
+package twofactor
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+const (
+	recoveryCodesCount = 10
+	recoveryCodeLen    = 10
+
+	// recoveryAlphabet has no characters that are easy to confuse
+	recoveryAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+)
+
+// newRecoveryCodes returns recovery codes in form "xxxxx-xxxxx" that are
+// shown to user and their hashes that are stored in database
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodesCount)
+	hashes := make([]string, recoveryCodesCount)
+
+	for i := range codes {
+		code, err := randomString(recoveryCodeLen)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		half := recoveryCodeLen / 2
+		codes[i] = code[:half] + "-" + code[half:]
+		hashes[i] = hashRecoveryCode(normalizePasscode(codes[i]))
+	}
+
+	return codes, hashes, nil
+}
+
+// randomString returns string of random characters of recoveryAlphabet.
+// Bytes that would make some characters more likely are skipped
+func randomString(n int) (string, error) {
+	limit := 256 - 256%len(recoveryAlphabet)
+
+	s := make([]byte, 0, n)
+	b := make([]byte, n)
+	for len(s) < n {
+		if _, err := rand.Read(b); err != nil {
+			return "", err
+		}
+		for _, c := range b {
+			if int(c) < limit && len(s) < n {
+				s = append(s, recoveryAlphabet[int(c)%len(recoveryAlphabet)])
+			}
+		}
+	}
+
+	return string(s), nil
+}
+
+// normalizePasscode removes separators and spaces users may type, so codes
+// are compared in the same form
+func normalizePasscode(passcode string) string {
+	passcode = strings.ToLower(passcode)
+	return strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' {
+			return -1
+		}
+		return r
+	}, passcode)
+}
+
+func hashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,48 @@
+package twofactor
+
+import (
+	"forum/internal/assert"
+	"strings"
+	"testing"
+)
+
+func TestNewRecoveryCodes(t *testing.T) {
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, len(codes), recoveryCodesCount)
+	assert.Equal(t, len(hashes), recoveryCodesCount)
+
+	seen := make(map[string]bool)
+	for i, code := range codes {
+		assert.Equal(t, len(code), recoveryCodeLen+1)
+		assert.Equal(t, code[recoveryCodeLen/2], byte('-'))
+		assert.Equal(t, seen[code], false)
+		seen[code] = true
+
+		for _, c := range strings.ReplaceAll(code, "-", "") {
+			assert.Equal(t, strings.ContainsRune(recoveryAlphabet, c), true)
+		}
+
+		// Code is accepted as users may type it
+		assert.Equal(t, hashRecoveryCode(normalizePasscode(strings.ToUpper(code))), hashes[i])
+		assert.Equal(t, hashRecoveryCode(normalizePasscode(strings.ReplaceAll(code, "-", " "))), hashes[i])
+	}
+}
+
+func TestNormalizePasscode(t *testing.T) {
+	tests := []struct {
+		passcode string
+		want     string
+	}{
+		{"123456", "123456"},
+		{"123 456", "123456"},
+		{" AbCdE-fGhJk ", "abcdefghjk"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, normalizePasscode(tt.passcode), tt.want)
+	}
+}
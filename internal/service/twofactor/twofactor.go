@@ -0,0 +1,266 @@
+package twofactor
+
+import (
+	"errors"
+	"forum/internal/entity"
+	"forum/internal/repository/setting"
+	"forum/internal/repository/twofactor"
+	"forum/internal/service/moderation"
+	"forum/pkg/totp"
+	"strconv"
+	"time"
+)
+
+const (
+	// requiredKey is the setting that makes 2FA mandatory for staff roles
+	requiredKey = "require_staff_2fa"
+
+	defaultIssuer = "Forum"
+)
+
+type ITwoFactorService interface {
+	Status(userID int, role string) (entity.TwoFactorStatus, error)
+	IsEnabled(userID int) (bool, error)
+	Begin(userID int, account string) (entity.TwoFactorSetup, error)
+	Confirm(userID int, passcode string) ([]string, error)
+	Verify(userID int, passcode string) error
+	RegenerateRecoveryCodes(userID int, passcode string) ([]string, error)
+	Disable(userID int, role, passcode string) error
+	Reset(actorID, userID int) error
+	IsRequired(role string) (bool, error)
+	SetRequired(actorID int, required bool) error
+}
+
+type twoFactorService struct {
+	twoFactorRepo twofactor.ITwoFactorRepository
+	settingRepo   setting.ISettingRepository
+	issuer        string
+}
+
+// NewTwoFactorService returns service with given issuer, that is the name
+// of the site shown by authenticator apps next to the code
+func NewTwoFactorService(t twofactor.ITwoFactorRepository, s setting.ISettingRepository, issuer string) *twoFactorService {
+	if issuer == "" {
+		issuer = defaultIssuer
+	}
+
+	return &twoFactorService{
+		twoFactorRepo: t,
+		settingRepo:   s,
+		issuer:        issuer,
+	}
+}
+
+var _ ITwoFactorService = (*twoFactorService)(nil)
+
+func (ts *twoFactorService) Status(userID int, role string) (entity.TwoFactorStatus, error) {
+	var status entity.TwoFactorStatus
+
+	enabled, err := ts.IsEnabled(userID)
+	if err != nil {
+		return status, err
+	}
+	status.Enabled = enabled
+
+	status.Required, err = ts.IsRequired(role)
+	if err != nil {
+		return status, err
+	}
+
+	if enabled {
+		status.RecoveryCodes, err = ts.twoFactorRepo.CountRecoveryCodes(userID)
+		if err != nil {
+			return status, err
+		}
+	}
+
+	return status, nil
+}
+
+// IsEnabled reports whether user has confirmed TOTP secret
+func (ts *twoFactorService) IsEnabled(userID int) (bool, error) {
+	tf, err := ts.twoFactorRepo.Get(userID)
+	if err != nil {
+		if errors.Is(err, entity.ErrTwoFactorDisabled) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return !tf.ConfirmedAt.IsZero(), nil
+}
+
+// Begin starts enrolment. Secret of unfinished enrolment is kept, so page
+// can be reloaded after QR code is scanned
+func (ts *twoFactorService) Begin(userID int, account string) (entity.TwoFactorSetup, error) {
+	tf, err := ts.twoFactorRepo.Get(userID)
+	switch {
+	case err == nil && !tf.ConfirmedAt.IsZero():
+		return entity.TwoFactorSetup{}, entity.ErrTwoFactorEnabled
+	case err == nil:
+	case errors.Is(err, entity.ErrTwoFactorDisabled):
+		tf.Secret, err = totp.GenerateSecret()
+		if err != nil {
+			return entity.TwoFactorSetup{}, err
+		}
+		if err := ts.twoFactorRepo.Upsert(userID, tf.Secret); err != nil {
+			return entity.TwoFactorSetup{}, err
+		}
+	default:
+		return entity.TwoFactorSetup{}, err
+	}
+
+	return entity.TwoFactorSetup{
+		Secret: tf.Secret,
+		URI:    totp.URI(ts.issuer, account, tf.Secret),
+	}, nil
+}
+
+// Confirm finishes enrolment with code from authenticator app and returns
+// recovery codes. They are shown to the user only once, just hashes are kept
+func (ts *twoFactorService) Confirm(userID int, passcode string) ([]string, error) {
+	tf, err := ts.twoFactorRepo.Get(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !tf.ConfirmedAt.IsZero() {
+		return nil, entity.ErrTwoFactorEnabled
+	}
+
+	counter, ok := totp.Validate(tf.Secret, normalizePasscode(passcode), time.Now())
+	if !ok {
+		return nil, entity.ErrInvalidPasscode
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ts.twoFactorRepo.Confirm(userID, counter, hashes); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// Verify checks code from authenticator app or unused recovery code. Each
+// of them is accepted only once
+func (ts *twoFactorService) Verify(userID int, passcode string) error {
+	tf, err := ts.twoFactorRepo.Get(userID)
+	if err != nil {
+		return err
+	}
+	if tf.ConfirmedAt.IsZero() {
+		return entity.ErrTwoFactorDisabled
+	}
+
+	passcode = normalizePasscode(passcode)
+
+	if len(passcode) == totp.Digits {
+		counter, ok := totp.Validate(tf.Secret, passcode, time.Now())
+		if !ok {
+			return entity.ErrInvalidPasscode
+		}
+		return ts.twoFactorRepo.UpdateCounter(userID, counter)
+	}
+
+	return ts.twoFactorRepo.ConsumeRecoveryCode(userID, hashRecoveryCode(passcode))
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes of the user with new
+// ones after code is verified
+func (ts *twoFactorService) RegenerateRecoveryCodes(userID int, passcode string) ([]string, error) {
+	if err := ts.Verify(userID, passcode); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ts.twoFactorRepo.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// Disable turns off two-factor authentication after code is verified. It
+// can't be turned off if it's required for the role
+func (ts *twoFactorService) Disable(userID int, role, passcode string) error {
+	required, err := ts.IsRequired(role)
+	if err != nil {
+		return err
+	}
+	if required {
+		return entity.ErrTwoFactorRequired
+	}
+
+	if err := ts.Verify(userID, passcode); err != nil {
+		return err
+	}
+
+	return ts.twoFactorRepo.Delete(userID)
+}
+
+// Reset turns off two-factor authentication without code. It's used by
+// admins when user lost both authenticator app and recovery codes. Actor 0
+// is the command line
+func (ts *twoFactorService) Reset(actorID, userID int) error {
+	enabled, err := ts.IsEnabled(userID)
+	if err != nil {
+		return err
+	}
+
+	before := map[string]bool{"Enabled": enabled}
+
+	e, err := moderation.NewEntry(actorID, entity.ACTION_RESET_TWO_FACTOR, entity.TARGET_USER, userID, "", before)
+	if err != nil {
+		return err
+	}
+
+	return ts.twoFactorRepo.Reset(userID, e)
+}
+
+// IsRequired reports whether users with given role must use 2FA. It can be
+// required only for moderators and admins
+func (ts *twoFactorService) IsRequired(role string) (bool, error) {
+	if role != entity.MODERATOR && role != entity.ADMIN {
+		return false, nil
+	}
+
+	value, err := ts.settingRepo.Get(requiredKey)
+	if err != nil {
+		if errors.Is(err, entity.ErrNoRecord) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	required, _ := strconv.ParseBool(value)
+	return required, nil
+}
+
+// SetRequired makes two-factor authentication required or optional for
+// moderators and admins. Settings have no ids, so the change is saved in
+// moderation log with target id 0 and the setting in the snapshot
+func (ts *twoFactorService) SetRequired(actorID int, required bool) error {
+	before, err := ts.IsRequired(entity.ADMIN)
+	if err != nil {
+		return err
+	}
+
+	setting := map[string]string{
+		"Key":   requiredKey,
+		"Value": strconv.FormatBool(before),
+	}
+
+	e, err := moderation.NewEntry(actorID, entity.ACTION_CHANGE_SETTING, entity.TARGET_SETTING, 0, "", setting)
+	if err != nil {
+		return err
+	}
+
+	return ts.settingRepo.Set(requiredKey, strconv.FormatBool(required), e)
+}
@@ -14,6 +14,13 @@ const (
 	maxPasswordLen = 500
 )
 
+// userState is the user as saved in moderation log, without credentials
+type userState struct {
+	ID       int
+	Username string
+	Role     string
+}
+
 var EmailRX = regexp.MustCompile(`(?i)(?:[a-z0-9!#$%&'*+\/=?^_\x60{|}~-]+(?:\.[a-z0-9!#$%&'*+\/=?^_\x60{|}~-]+)*|"(?:[\x01-\x08\x0b\x0c\x0e-\x1f\x21\x23-\x5b\x5d-\x7f]|\\[\x01-\x09\x0b\x0c\x0e-\x7f])*")@(?:(?:[a-z0-9](?:[a-z0-9-]*[a-z0-9])?\.)+[a-z0-9](?:[a-z0-9-]*[a-z0-9])?|\[(?:(?:(2(5[0-5]|[0-4][0-9])|1[0-9][0-9]|[1-9]?[0-9]))\.){3}(?:(2(5[0-5]|[0-4][0-9])|1[0-9][0-9]|[1-9]?[0-9])|[a-z0-9-]*[a-z0-9]:(?:[\x01-\x08\x0b\x0c\x0e-\x1f\x21-\x5a\x53-\x7f]|\\[\x01-\x09\x0b\x0c\x0e-\x7f])+)\])`)
 
 func IsRightSignUp(u *entity.UserSignupForm) bool {
@@ -32,6 +39,18 @@ func IsRightSignUp(u *entity.UserSignupForm) bool {
 	return u.Valid()
 }
 
+// roleRank orders roles from the least to the most privileged
+func roleRank(role string) int {
+	switch role {
+	case entity.MODERATOR:
+		return 1
+	case entity.ADMIN:
+		return 2
+	default:
+		return 0
+	}
+}
+
 func IsRightLogin(u *entity.UserLoginForm) bool {
 	u.CheckField(validator.NotBlank(u.Identifier), "identifier", "This field cannot be blank")
 	u.CheckField(validator.NotBlank(u.Password), "password", "This field cannot be blank")
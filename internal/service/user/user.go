@@ -4,6 +4,7 @@ import (
 	"errors"
 	"forum/internal/entity"
 	"forum/internal/repository/user"
+	"forum/internal/service/moderation"
 	"forum/internal/validator"
 	"strings"
 
@@ -15,16 +16,21 @@ type IUserService interface {
 	Authenticate(*entity.UserLoginForm) (int, error)
 	GetUsernameById(int) (string, error)
 	GetUserByEmail(string) (entity.UserEntity, error)
+	GetUserByUsername(string) (entity.UserEntity, error)
 	GetUserRole(int) (string, error)
 	SendNotification(notification entity.Notification) error
 	SendPromotion(userID int) error
 	SendReport(report entity.Report) error
 	DeleteReport(reportID int) error
 	DeletePromotion(promotionID int) error
+	RejectReport(actorID, reportID int) error
+	RejectPromotion(actorID, promotionID int) error
 	GetRequests() (*[]entity.Request, error)
 	GetReports() (*[]entity.Report, error)
-	PromoteUser(userID int) error
-	DemoteUser(userID int) error
+	PromoteUser(actorID, userID int) error
+	PromoteToAdmin(actorID, userID int) error
+	DemoteUser(actorID, userID int) error
+	SetUserRole(actorID, userID int, role string) error
 	GetNotifications(userID int) (*[]entity.Notification, error)
 	DeleteNotification(notificationID int) error
 	GetUsers() (*[]entity.UserEntity, error)
@@ -102,6 +108,12 @@ func (us *userService) Authenticate(u *entity.UserLoginForm) (int, error) {
 		}
 	}
 
+	// Accounts created through external login have no password
+	if userFromDB.Password == "" {
+		u.AddNonFieldError("Email or password is incorrect")
+		return 0, entity.ErrInvalidCredentials
+	}
+
 	err = bcrypt.CompareHashAndPassword([]byte(userFromDB.Password), []byte(u.Password))
 	if err != nil {
 		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
@@ -123,6 +135,10 @@ func (us *userService) GetUserByEmail(email string) (entity.UserEntity, error) {
 	return us.userRepo.GetByEmail(email)
 }
 
+func (us *userService) GetUserByUsername(username string) (entity.UserEntity, error) {
+	return us.userRepo.GetByUsername(username)
+}
+
 func (us *userService) GetUserRole(userID int) (string, error) {
 	return us.userRepo.GetRole(userID)
 }
@@ -143,6 +159,8 @@ func (us *userService) SendNotification(n entity.Notification) error {
 		n.Content = "Disliked your comment"
 	case entity.COMMENTED:
 		n.Content = "Left a comment on your post"
+	case entity.REPLIED:
+		n.Content = "Replied to your comment"
 	case entity.REJECT_PROMOTION:
 		n.Content = "Your promotion was rejected"
 	case entity.REJECT_REPORT:
@@ -151,6 +169,14 @@ func (us *userService) SendNotification(n entity.Notification) error {
 		n.Content = "Your post/posts was/were deleted" + n.Content
 	case entity.DELETE_COMMENT:
 		n.Content = "Your comment/comments was/were deleted" + n.Content
+	case entity.WARNED:
+		n.Content = "You've got a warning" + n.Content
+	case entity.MUTED:
+		n.Content = "You can't post, comment or react" + n.Content
+	case entity.BANNED:
+		n.Content = "Your account is banned" + n.Content
+	case entity.LOGIN_LOCKED:
+		n.Content = "Your account is locked for a while after several failed attempts to log in" + n.Content
 	default:
 		return entity.ErrInvalidNotificaitonType
 	}
@@ -174,6 +200,36 @@ func (us *userService) DeletePromotion(promotionID int) error {
 	return us.userRepo.DeletePromotion(promotionID)
 }
 
+// RejectReport deletes report without deleting reported content
+func (us *userService) RejectReport(actorID, reportID int) error {
+	report, err := us.userRepo.GetReport(reportID)
+	if err != nil {
+		return err
+	}
+
+	e, err := moderation.NewEntry(actorID, entity.ACTION_REJECT_REPORT, entity.TARGET_REPORT, reportID, "", report)
+	if err != nil {
+		return err
+	}
+
+	return us.userRepo.RejectReport(reportID, e)
+}
+
+// RejectPromotion deletes request for promotion without promoting the user
+func (us *userService) RejectPromotion(actorID, promotionID int) error {
+	promotion, err := us.userRepo.GetPromotion(promotionID)
+	if err != nil {
+		return err
+	}
+
+	e, err := moderation.NewEntry(actorID, entity.ACTION_REJECT_PROMOTION, entity.TARGET_PROMOTION, promotionID, "", promotion)
+	if err != nil {
+		return err
+	}
+
+	return us.userRepo.RejectPromotion(promotionID, e)
+}
+
 func (us *userService) GetRequests() (*[]entity.Request, error) {
 	return us.userRepo.GetRequests()
 }
@@ -190,12 +246,84 @@ func (us *userService) GetNotificationsCount(userID int) (int, error) {
 	return us.userRepo.GetNotificationsCount(userID)
 }
 
-func (us *userService) PromoteUser(userID int) error {
-	return us.userRepo.Promote(userID)
+// PromoteUser makes the user a moderator. Actor 0 is the command line
+func (us *userService) PromoteUser(actorID, userID int) error {
+	return us.promote(actorID, userID, entity.MODERATOR)
+}
+
+// PromoteToAdmin makes the user an admin by the same rules as PromoteUser
+func (us *userService) PromoteToAdmin(actorID, userID int) error {
+	return us.promote(actorID, userID, entity.ADMIN)
 }
 
-func (us *userService) DemoteUser(userID int) error {
-	return us.userRepo.Demote(userID)
+// promote raises role of the user and records it in moderation log with the
+// role as reason. Role can't be given again or lowered this way, and
+// requests of the user for promotion are answered by it, so they're deleted
+func (us *userService) promote(actorID, userID int, role string) error {
+	before, err := us.state(userID)
+	if err != nil {
+		return err
+	}
+
+	if roleRank(before.Role) >= roleRank(role) {
+		return entity.ErrAlreadyPromoted
+	}
+
+	e, err := moderation.NewEntry(actorID, entity.ACTION_PROMOTE_USER, entity.TARGET_USER, userID, role, before)
+	if err != nil {
+		return err
+	}
+
+	return us.userRepo.Promote(userID, role, e)
+}
+
+func (us *userService) DemoteUser(actorID, userID int) error {
+	return us.changeRole(actorID, userID, entity.ACTION_DEMOTE_USER, entity.USER, "")
+}
+
+// SetUserRole gives the user any role, the role is saved as reason in
+// moderation log. Actor 0 is the command line
+func (us *userService) SetUserRole(actorID, userID int, role string) error {
+	switch role {
+	case entity.USER, entity.MODERATOR, entity.ADMIN:
+	default:
+		return entity.ErrInvalidRole
+	}
+
+	return us.changeRole(actorID, userID, entity.ACTION_SET_ROLE, role, role)
+}
+
+// changeRole sets role of the user and records it in moderation log
+func (us *userService) changeRole(actorID, userID int, action, role, reason string) error {
+	before, err := us.state(userID)
+	if err != nil {
+		return err
+	}
+
+	e, err := moderation.NewEntry(actorID, action, entity.TARGET_USER, userID, reason, before)
+	if err != nil {
+		return err
+	}
+
+	return us.userRepo.SetRole(userID, role, e)
+}
+
+// state returns snapshot of the user for moderation log
+func (us *userService) state(userID int) (userState, error) {
+	u, err := us.userRepo.GetByID(userID)
+	if err != nil {
+		if errors.Is(err, entity.ErrInvalidCredentials) {
+			return userState{}, entity.ErrUserNotFound
+		}
+		return userState{}, err
+	}
+
+	role, err := us.userRepo.GetRole(userID)
+	if err != nil {
+		return userState{}, err
+	}
+
+	return userState{ID: u.ID, Username: u.Username, Role: role}, nil
 }
 
 func (us *userService) DeleteNotification(notificationID int) error {
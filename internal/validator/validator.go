@@ -15,8 +15,8 @@ import (
 */
 
 type Validator struct {
-	NonFieldErrors []string
-	FieldErrors    map[string]string
+	NonFieldErrors []string          `json:"-"`
+	FieldErrors    map[string]string `json:"-"`
 }
 
 func (v *Validator) Valid() bool {
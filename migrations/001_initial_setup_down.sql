@@ -1,11 +1,13 @@
-DROP TABLE IF EXISTS users;
-DROP TABLE IF EXISTS posts;
-DROP TABLE IF EXISTS post_reactions;
-DROP TABLE IF EXISTS comments;
-DROP TABLE IF EXISTS comment_reactions;
-DROP TABLE IF EXISTS tags;
-DROP TABLE IF EXISTS posts_tags;
-DROP TABLE IF EXISTS sessions;
-DROP TABLE IF EXISTS images;
+DROP TABLE IF EXISTS requests;
+DROP TABLE IF EXISTS reports;
+DROP TABLE IF EXISTS notifications;
 DROP TABLE IF EXISTS roles;
-DROP TABLE IF EXISTS notifications;
\ No newline at end of file
+DROP TABLE IF EXISTS images;
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS posts_tags;
+DROP TABLE IF EXISTS tags;
+DROP TABLE IF EXISTS comment_reactions;
+DROP TABLE IF EXISTS comments;
+DROP TABLE IF EXISTS post_reactions;
+DROP TABLE IF EXISTS posts;
+DROP TABLE IF EXISTS users;
@@ -68,7 +68,7 @@ CREATE TABLE IF NOT EXISTS posts_tags (
 );
 
 CREATE TABLE IF NOT EXISTS sessions (
-    session_id TEXT PRIMARY KEY AUTOINCREMENT,
+    session_id TEXT PRIMARY KEY,
     expiry DATETIME NOT NULL,
     user_role VARCHAR(30) NOT NULL,
     user_id INTEGER NOT NULL,
@@ -104,7 +104,7 @@ CREATE TABLE IF NOT EXISTS notifications (
 
     FOREIGN KEY(user_from) REFERENCES users(id) ON DELETE CASCADE,
     FOREIGN KEY(user_to) REFERENCES users(id) ON DELETE CASCADE
-) 
+);
 
 CREATE TABLE IF NOT EXISTS reports (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -116,7 +116,7 @@ CREATE TABLE IF NOT EXISTS reports (
 
     FOREIGN KEY(user_from) REFERENCES users(id) ON DELETE CASCADE,
     FOREIGN KEY(source_id) REFERENCES posts(id) ON DELETE CASCADE
-)
+);
 
 CREATE TABLE IF NOT EXISTS requests (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -124,4 +124,4 @@ CREATE TABLE IF NOT EXISTS requests (
     created_at DATETIME NOT NULL,
 
     FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
-)
\ No newline at end of file
+);
@@ -1,2 +1,2 @@
-DROP INDEX expiry_delete;
-DROP INDEX role_index;
\ No newline at end of file
+DROP INDEX IF EXISTS expiry_delete;
+DROP INDEX IF EXISTS role_index;
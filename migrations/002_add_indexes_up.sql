@@ -1,2 +1,2 @@
-CREATE INDEX expiry_delete ON sessions (expiry);
-CREATE INDEX role_index ON roles (role);
\ No newline at end of file
+CREATE INDEX IF NOT EXISTS expiry_delete ON sessions (expiry);
+CREATE INDEX IF NOT EXISTS role_index ON roles (role);
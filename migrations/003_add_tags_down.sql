@@ -1 +1,2 @@
-DELETE FROM tags;
+DELETE FROM tags
+WHERE name IN ('Gaming', 'Travel', 'Sport', 'Art', 'Music');
@@ -1,5 +1,5 @@
--- Insert prepared tags
-INSERT INTO tags (name, created_at)
+-- Insert prepared tags (existing ones are kept untouched)
+INSERT OR IGNORE INTO tags (name, created_at)
 VALUES ('Gaming', DATETIME('now', 'localtime')),
        ('Travel', DATETIME('now', 'localtime')),
        ('Sport', DATETIME('now', 'localtime')),
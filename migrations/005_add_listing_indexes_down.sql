@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS posts_tags_tag_id_index;
+DROP INDEX IF EXISTS posts_user_id_index;
+DROP INDEX IF EXISTS posts_created_at_index;
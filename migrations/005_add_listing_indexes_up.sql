@@ -0,0 +1,4 @@
+-- Post listings are paginated by (created_at, id)
+CREATE INDEX IF NOT EXISTS posts_created_at_index ON posts (created_at, id);
+CREATE INDEX IF NOT EXISTS posts_user_id_index ON posts (user_id);
+CREATE INDEX IF NOT EXISTS posts_tags_tag_id_index ON posts_tags (tag_id);
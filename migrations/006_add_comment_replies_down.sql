@@ -0,0 +1,46 @@
+-- Column that is a part of foreign key can't be dropped, so the table is
+-- rebuilt. Dropping comments would also cascade to comment reactions, so
+-- they are put aside and restored afterwards
+CREATE TEMP TABLE comment_reactions_backup AS SELECT * FROM comment_reactions;
+
+DROP INDEX IF EXISTS comments_post_id_index;
+DROP INDEX IF EXISTS comments_parent_id_index;
+
+CREATE TABLE comments_old (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    content TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    post_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+INSERT INTO comments_old (id, content, created_at, post_id, user_id)
+SELECT id, content, created_at, post_id, user_id FROM comments;
+
+DROP TABLE comments;
+ALTER TABLE comments_old RENAME TO comments;
+
+DELETE FROM comment_reactions;
+INSERT INTO comment_reactions SELECT * FROM comment_reactions_backup;
+DROP TABLE comment_reactions_backup;
+
+-- Triggers are dropped along with the table
+CREATE TRIGGER IF NOT EXISTS comments_fts_insert AFTER INSERT ON comments BEGIN
+    INSERT INTO comments_fts (rowid, content)
+    VALUES (new.id, new.content);
+END;
+
+CREATE TRIGGER IF NOT EXISTS comments_fts_delete AFTER DELETE ON comments BEGIN
+    INSERT INTO comments_fts (comments_fts, rowid, content)
+    VALUES ('delete', old.id, old.content);
+END;
+
+CREATE TRIGGER IF NOT EXISTS comments_fts_update AFTER UPDATE OF content ON comments BEGIN
+    INSERT INTO comments_fts (comments_fts, rowid, content)
+    VALUES ('delete', old.id, old.content);
+    INSERT INTO comments_fts (rowid, content)
+    VALUES (new.id, new.content);
+END;
@@ -0,0 +1,6 @@
+-- Replies reference comment they answer, top level comments have no parent.
+-- Replies are deleted along with their parent
+ALTER TABLE comments ADD COLUMN parent_id INTEGER NULL REFERENCES comments(id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS comments_parent_id_index ON comments (parent_id);
+CREATE INDEX IF NOT EXISTS comments_post_id_index ON comments (post_id);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS post_revisions_post_id_index;
+DROP TABLE IF EXISTS post_revisions;
+
+ALTER TABLE posts DROP COLUMN updated_at;
@@ -0,0 +1,39 @@
+-- Time of the last edit, posts that were never edited have none
+ALTER TABLE posts ADD COLUMN updated_at DATETIME NULL;
+
+-- Every version of post including the original one. The latest revision
+-- is the current state of post. Editor is kept even if moderator who made
+-- the edit is deleted later
+CREATE TABLE IF NOT EXISTS post_revisions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    post_id INTEGER NOT NULL,
+    user_id INTEGER NULL,
+    title VARCHAR(150) NOT NULL,
+    content TEXT NOT NULL,
+    tags TEXT NOT NULL DEFAULT '',
+    image_name TEXT NULL,
+    created_at DATETIME NOT NULL,
+
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS post_revisions_post_id_index ON post_revisions (post_id, id);
+
+-- Existing posts start their history from the current state
+INSERT INTO post_revisions (post_id, user_id, title, content, tags, image_name, created_at)
+SELECT p.id, p.user_id, p.title, p.content,
+    COALESCE((
+        SELECT GROUP_CONCAT(t.name, ', ')
+        FROM tags t
+        INNER JOIN posts_tags pt ON pt.tag_id = t.id
+        WHERE pt.post_id = p.id
+    ), ''),
+    (
+        SELECT name
+        FROM images
+        WHERE post_id = p.id
+    ),
+    p.created_at
+FROM posts p
+ORDER BY p.id;
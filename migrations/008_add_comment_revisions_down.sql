@@ -0,0 +1,24 @@
+-- Column that is a part of foreign key can't be dropped, so reports
+-- table is rebuilt
+CREATE TABLE reports_old (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    reason VARCHAR(30) NOT NULL, 
+    user_from INT NOT NULL,
+    source_id INT NOT NULL,
+    source_type VARCHAR(30) NOT NULL,
+    created_at DATETIME NOT NULL,
+
+    FOREIGN KEY(user_from) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY(source_id) REFERENCES posts(id) ON DELETE CASCADE
+);
+
+INSERT INTO reports_old (id, reason, user_from, source_id, source_type, created_at)
+SELECT id, reason, user_from, source_id, source_type, created_at FROM reports;
+
+DROP TABLE reports;
+ALTER TABLE reports_old RENAME TO reports;
+
+DROP INDEX IF EXISTS comment_revisions_comment_id_index;
+DROP TABLE IF EXISTS comment_revisions;
+
+ALTER TABLE comments DROP COLUMN updated_at;
@@ -0,0 +1,25 @@
+-- Time of the last edit, comments that were never edited have none
+ALTER TABLE comments ADD COLUMN updated_at DATETIME NULL;
+
+-- Every version of comment including the original one. The latest revision
+-- is the current text of comment
+CREATE TABLE IF NOT EXISTS comment_revisions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    comment_id INTEGER NOT NULL,
+    content TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+
+    FOREIGN KEY (comment_id) REFERENCES comments(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS comment_revisions_comment_id_index ON comment_revisions (comment_id, id);
+
+-- Existing comments start their history from the current text
+INSERT INTO comment_revisions (comment_id, content, created_at)
+SELECT id, content, created_at
+FROM comments
+ORDER BY id;
+
+-- Reports of comments keep the id of post in source_id, so reported comment
+-- itself is referenced separately
+ALTER TABLE reports ADD COLUMN comment_id INTEGER NULL REFERENCES comments(id) ON DELETE CASCADE;
@@ -0,0 +1,6 @@
+-- Time when user confirmed the email address. Users without it can't create
+-- posts and comments
+ALTER TABLE users ADD COLUMN verified_at DATETIME NULL;
+
+-- Existing accounts were created before verification was required
+UPDATE users SET verified_at = created_at;
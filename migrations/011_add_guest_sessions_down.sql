@@ -0,0 +1,18 @@
+CREATE TABLE sessions_old (
+    session_id TEXT PRIMARY KEY,
+    expiry DATETIME NOT NULL,
+    user_role VARCHAR(30) NOT NULL,
+    user_id INTEGER NOT NULL,
+
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Guest sessions can't be kept
+INSERT INTO sessions_old (session_id, expiry, user_role, user_id)
+SELECT session_id, expiry, user_role, user_id FROM sessions
+WHERE user_id IS NOT NULL;
+
+DROP TABLE sessions;
+ALTER TABLE sessions_old RENAME TO sessions;
+
+CREATE INDEX IF NOT EXISTS expiry_delete ON sessions (expiry);
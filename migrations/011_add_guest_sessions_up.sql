@@ -0,0 +1,22 @@
+-- Sessions of guests are saved to keep state of login through external
+-- provider, so user_id becomes optional. Values put in session are kept
+-- encoded in data. Column that is a part of foreign key can't be altered,
+-- so sessions table is rebuilt
+CREATE TABLE sessions_new (
+    session_id TEXT PRIMARY KEY,
+    expiry DATETIME NOT NULL,
+    user_role VARCHAR(30) NOT NULL,
+    user_id INTEGER NULL,
+    data BLOB NULL,
+
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+INSERT INTO sessions_new (session_id, expiry, user_role, user_id)
+SELECT session_id, expiry, user_role, user_id FROM sessions;
+
+DROP TABLE sessions;
+ALTER TABLE sessions_new RENAME TO sessions;
+
+CREATE INDEX IF NOT EXISTS expiry_delete ON sessions (expiry);
+CREATE INDEX IF NOT EXISTS sessions_user_id_index ON sessions (user_id);
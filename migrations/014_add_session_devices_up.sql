@@ -0,0 +1,6 @@
+-- Device of the session, so users can tell their sessions apart and log
+-- out the ones they don't recognize
+ALTER TABLE sessions ADD COLUMN user_agent TEXT NULL;
+ALTER TABLE sessions ADD COLUMN ip TEXT NULL;
+ALTER TABLE sessions ADD COLUMN created_at DATETIME NULL;
+ALTER TABLE sessions ADD COLUMN last_seen DATETIME NULL;
@@ -0,0 +1,7 @@
+UPDATE sessions SET
+    expiry = datetime(expiry, 'localtime'),
+    created_at = datetime(created_at, 'localtime'),
+    last_seen = datetime(last_seen, 'localtime');
+
+ALTER TABLE sessions DROP COLUMN deadline;
+ALTER TABLE sessions DROP COLUMN remember;
@@ -0,0 +1,12 @@
+-- Expiry is moved by requests now, deadline is the end of session's lifetime
+-- that can't be moved. Remembered sessions live longer
+ALTER TABLE sessions ADD COLUMN deadline DATETIME NULL;
+ALTER TABLE sessions ADD COLUMN remember BOOLEAN NOT NULL DEFAULT 0;
+
+-- Session times are kept in UTC
+UPDATE sessions SET
+    expiry = datetime(expiry, 'utc'),
+    created_at = datetime(created_at, 'utc'),
+    last_seen = datetime(last_seen, 'utc');
+
+UPDATE sessions SET deadline = expiry;
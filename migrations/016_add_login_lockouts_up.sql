@@ -0,0 +1,17 @@
+-- Failed logins in a row of every account and every client address. Login
+-- is refused until locked_until after too many failures, lock gets longer
+-- with every next failure
+CREATE TABLE IF NOT EXISTS login_lockouts (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NULL UNIQUE,
+    ip TEXT NULL UNIQUE,
+    failures INTEGER NOT NULL DEFAULT 0,
+    last_failure DATETIME NOT NULL,
+    locked_until DATETIME NULL,
+    notified BOOLEAN NOT NULL DEFAULT 0,
+
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CHECK ((user_id IS NULL) <> (ip IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_lockouts_last_failure ON login_lockouts(last_failure);
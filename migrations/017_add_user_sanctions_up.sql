@@ -0,0 +1,17 @@
+-- Warnings, mutes and bans given to users by admins. Sanction without expiry
+-- is permanent, revoked sanction is kept for history
+CREATE TABLE IF NOT EXISTS user_sanctions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    issued_by INTEGER NULL,
+    kind TEXT NOT NULL CHECK (kind IN ('warning', 'mute', 'ban')),
+    reason TEXT NOT NULL,
+    expires_at DATETIME NULL,
+    revoked_at DATETIME NULL,
+    created_at DATETIME NOT NULL,
+
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (issued_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_sanctions_user_id ON user_sanctions(user_id);
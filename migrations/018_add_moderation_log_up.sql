@@ -0,0 +1,28 @@
+-- Privileged actions of moderators and admins. Actor and target aren't
+-- foreign keys, so entries stay after users and content are deleted
+CREATE TABLE IF NOT EXISTS moderation_log (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    actor_id INTEGER NOT NULL,
+    actor_name TEXT NOT NULL,
+    action TEXT NOT NULL,
+    target_type TEXT NOT NULL,
+    target_id INTEGER NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    before_snapshot TEXT NULL,
+    created_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_moderation_log_actor_name ON moderation_log(actor_name COLLATE NOCASE);
+CREATE INDEX IF NOT EXISTS idx_moderation_log_action ON moderation_log(action);
+CREATE INDEX IF NOT EXISTS idx_moderation_log_target ON moderation_log(target_type, target_id);
+
+-- Log is append-only
+CREATE TRIGGER IF NOT EXISTS moderation_log_no_update BEFORE UPDATE ON moderation_log
+BEGIN
+    SELECT RAISE(ABORT, 'moderation log is append-only');
+END;
+
+CREATE TRIGGER IF NOT EXISTS moderation_log_no_delete BEFORE DELETE ON moderation_log
+BEGIN
+    SELECT RAISE(ABORT, 'moderation log is append-only');
+END;
@@ -1,24 +1,29 @@
 package sqlite3
 
-import "database/sql"
+import (
+	"database/sql"
+	"strings"
+)
 
 // OpenDB opens connection to the database using standard sql library
 // with given Data Source Name (DSN)
 func OpenDB(dsn string) (*sql.DB, error) {
-	db, err := sql.Open("sqlite3", dsn)
-	if err != nil {
-		return nil, err
+	// Enable foreign keys (they are disabled by default for backwards compatibility).
+	// Pragma is set through DSN, so it is applied to every connection in the pool
+	if !strings.Contains(dsn, "_foreign_keys=") && !strings.Contains(dsn, "_fk=") {
+		if strings.Contains(dsn, "?") {
+			dsn += "&_foreign_keys=on"
+		} else {
+			dsn += "?_foreign_keys=on"
+		}
 	}
 
-	err = db.Ping()
+	db, err := sql.Open("sqlite3", dsn)
 	if err != nil {
-		db.Close()
 		return nil, err
 	}
 
-	// Enable foreign keys (they are disabled by default for backwards compatibility)
-	query := "PRAGMA foreign_keys = ON;"
-	_, err = db.Exec(query)
+	err = db.Ping()
 	if err != nil {
 		db.Close()
 		return nil, err
@@ -0,0 +1,145 @@
+package diff
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Kinds of diff operations
+const (
+	Equal  = "equal"
+	Insert = "insert"
+	Delete = "delete"
+)
+
+// maxCells limits size of the table used to find longest common subsequence.
+// Texts that differ in more words than that are shown as fully replaced
+const maxCells = 4_000_000
+
+var tokenRX = regexp.MustCompile(`\s+|[^\s]+`)
+
+// Op is a piece of text that is either kept, inserted or deleted
+type Op struct {
+	Kind string
+	Text string
+}
+
+// Words returns word-level diff that turns text a into text b. Whitespace
+// between words is compared as separate tokens, so line breaks are kept
+func Words(a, b string) []Op {
+	x := tokenRX.FindAllString(a, -1)
+	y := tokenRX.FindAllString(b, -1)
+
+	// Common beginning and ending don't need to go through the table
+	prefix := 0
+	for prefix < len(x) && prefix < len(y) && x[prefix] == y[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(x)-prefix && suffix < len(y)-prefix && x[len(x)-1-suffix] == y[len(y)-1-suffix] {
+		suffix++
+	}
+
+	var ops []Op
+	ops = appendOp(ops, Equal, x[:prefix]...)
+	ops = append(ops, lcs(x[prefix:len(x)-suffix], y[prefix:len(y)-suffix])...)
+	ops = appendOp(ops, Equal, x[len(x)-suffix:]...)
+
+	return merge(ops)
+}
+
+// lcs builds diff of two token lists from their longest common subsequence
+func lcs(x, y []string) []Op {
+	if len(x) == 0 || len(y) == 0 || len(x)*len(y) > maxCells {
+		var ops []Op
+		ops = appendOp(ops, Delete, x...)
+		return appendOp(ops, Insert, y...)
+	}
+
+	// lengths[i][j] is length of common subsequence of x[i:] and y[j:]
+	lengths := make([][]int32, len(x)+1)
+	for i := range lengths {
+		lengths[i] = make([]int32, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else {
+				lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+			}
+		}
+	}
+
+	var ops []Op
+	i, j := 0, 0
+	for i < len(x) && j < len(y) {
+		switch {
+		case x[i] == y[j]:
+			ops = appendOp(ops, Equal, x[i])
+			i++
+			j++
+		case lengths[i+1][j] >= lengths[i][j+1]:
+			ops = appendOp(ops, Delete, x[i])
+			i++
+		default:
+			ops = appendOp(ops, Insert, y[j])
+			j++
+		}
+	}
+	ops = appendOp(ops, Delete, x[i:]...)
+	ops = appendOp(ops, Insert, y[j:]...)
+
+	return ops
+}
+
+func appendOp(ops []Op, kind string, tokens ...string) []Op {
+	if len(tokens) == 0 {
+		return ops
+	}
+	return append(ops, Op{Kind: kind, Text: strings.Join(tokens, "")})
+}
+
+// merge joins neighbouring operations of the same kind, so each changed
+// region is shown as one deletion followed by one insertion. Whitespace
+// between two changes becomes part of them, so changed phrases are shown
+// as a whole
+func merge(ops []Op) []Op {
+	var merged []Op
+	var deleted, inserted strings.Builder
+
+	flush := func() {
+		if deleted.Len() > 0 {
+			merged = append(merged, Op{Kind: Delete, Text: deleted.String()})
+		}
+		if inserted.Len() > 0 {
+			merged = append(merged, Op{Kind: Insert, Text: inserted.String()})
+		}
+		deleted.Reset()
+		inserted.Reset()
+	}
+
+	for i, op := range ops {
+		changing := deleted.Len() > 0 || inserted.Len() > 0
+
+		switch {
+		case op.Kind == Delete:
+			deleted.WriteString(op.Text)
+		case op.Kind == Insert:
+			inserted.WriteString(op.Text)
+		case changing && strings.TrimSpace(op.Text) == "" && i+1 < len(ops) && ops[i+1].Kind != Equal:
+			deleted.WriteString(op.Text)
+			inserted.WriteString(op.Text)
+		default:
+			flush()
+			if n := len(merged); n > 0 && merged[n-1].Kind == Equal {
+				merged[n-1].Text += op.Text
+				continue
+			}
+			merged = append(merged, op)
+		}
+	}
+	flush()
+
+	return merged
+}
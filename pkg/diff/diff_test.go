@@ -0,0 +1,67 @@
+package diff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWords(t *testing.T) {
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		want []Op
+	}{
+		{
+			name: "Same text",
+			a:    "white rabbit",
+			b:    "white rabbit",
+			want: []Op{{Equal, "white rabbit"}},
+		},
+		{
+			name: "Replaced word",
+			a:    "the white rabbit runs",
+			b:    "the black rabbit runs",
+			want: []Op{{Equal, "the "}, {Delete, "white"}, {Insert, "black"}, {Equal, " rabbit runs"}},
+		},
+		{
+			name: "Inserted and deleted words",
+			a:    "a b c d",
+			b:    "a c d e",
+			want: []Op{{Equal, "a "}, {Delete, "b "}, {Equal, "c d"}, {Insert, " e"}},
+		},
+		{
+			name: "Changed phrase",
+			a:    "one two three four",
+			b:    "one 2 3 four",
+			want: []Op{{Equal, "one "}, {Delete, "two three"}, {Insert, "2 3"}, {Equal, " four"}},
+		},
+		{
+			name: "Line breaks are kept",
+			a:    "first\nsecond",
+			b:    "first\n\nsecond",
+			want: []Op{{Equal, "first"}, {Delete, "\n"}, {Insert, "\n\n"}, {Equal, "second"}},
+		},
+		{
+			name: "From empty text",
+			a:    "",
+			b:    "new text",
+			want: []Op{{Insert, "new text"}},
+		},
+		{
+			name: "To empty text",
+			a:    "old text",
+			b:    "",
+			want: []Op{{Delete, "old text"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Words(tt.a, tt.b)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got: %q; expected: %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package mailer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+	Mailer sends plain text emails. SMTP is used in production, while Log
+	only writes messages to given writer (file or stdout), so it can be
+	used in development and tests.
+*/
+
+var ErrInvalidHeader = errors.New("mailer: line break in header")
+
+// Message is a plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// SMTP sends messages through SMTP server. Connection is upgraded with
+// STARTTLS when server supports it
+type SMTP struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+var _ Mailer = (*SMTP)(nil)
+
+// NewSMTP returns mailer that sends messages from given address. Empty
+// username disables authentication
+func NewSMTP(host string, port int, username, password, from string) *SMTP {
+	m := &SMTP{
+		addr: net.JoinHostPort(host, strconv.Itoa(port)),
+		from: from,
+	}
+	if username != "" {
+		m.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return m
+}
+
+func (m *SMTP) Send(msg Message) error {
+	data, err := build(m.from, msg, time.Now())
+	if err != nil {
+		return err
+	}
+	return smtp.SendMail(m.addr, m.auth, m.from, []string{msg.To}, data)
+}
+
+// Log writes messages to writer instead of sending them. Written messages
+// are kept, so tests can check what was sent
+type Log struct {
+	mu   sync.Mutex
+	w    io.Writer
+	from string
+	sent []Message
+}
+
+var _ Mailer = (*Log)(nil)
+
+func NewLog(w io.Writer, from string) *Log {
+	return &Log{w: w, from: from}
+}
+
+func (m *Log) Send(msg Message) error {
+	data, err := build(m.from, msg, time.Now())
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, err := fmt.Fprintf(m.w, "%s\r\n", data); err != nil {
+		return err
+	}
+	m.sent = append(m.sent, msg)
+
+	return nil
+}
+
+// Sent returns all messages sent by mailer
+func (m *Log) Sent() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]Message(nil), m.sent...)
+}
+
+// build formats message with headers as it's sent over SMTP
+func build(from string, msg Message, date time.Time) ([]byte, error) {
+	for _, h := range []string{from, msg.To, msg.Subject} {
+		if strings.ContainsAny(h, "\r\n") {
+			return nil, ErrInvalidHeader
+		}
+	}
+
+	var b bytes.Buffer
+
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("\r\n")
+
+	// Body is sent as is, so links stay readable in logged messages. Lines
+	// only need to end with CRLF
+	body := strings.ReplaceAll(msg.Body, "\r\n", "\n")
+	b.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+
+	return b.Bytes(), nil
+}
@@ -0,0 +1,52 @@
+package mailer
+
+import (
+	"bytes"
+	"errors"
+	"forum/internal/assert"
+	"testing"
+	"time"
+)
+
+func TestBuild(t *testing.T) {
+	date := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	data, err := build("forum@example.com", Message{
+		To:      "user@example.com",
+		Subject: "Сброс пароля",
+		Body:    "Follow the link:\nhttps://example.com/user/reset?token=abc=",
+	}, date)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := string(data)
+	assert.StringContains(t, msg, "From: forum@example.com\r\n")
+	assert.StringContains(t, msg, "To: user@example.com\r\n")
+	assert.StringContains(t, msg, "Subject: =?utf-8?q?")
+	assert.StringContains(t, msg, "Date: Sun, 10 Mar 2024 12:00:00 +0000\r\n")
+	assert.StringContains(t, msg, "\r\n\r\nFollow the link:\r\nhttps://example.com/user/reset?token=abc=")
+}
+
+func TestBuildHeaderInjection(t *testing.T) {
+	_, err := build("forum@example.com", Message{
+		To:      "user@example.com\r\nBcc: victim@example.com",
+		Subject: "Hello",
+	}, time.Now())
+
+	assert.Equal(t, errors.Is(err, ErrInvalidHeader), true)
+}
+
+func TestLog(t *testing.T) {
+	var buf bytes.Buffer
+	m := NewLog(&buf, "forum@example.com")
+
+	msg := Message{To: "user@example.com", Subject: "Hello", Body: "text"}
+	if err := m.Send(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.StringContains(t, buf.String(), "Subject: Hello\r\n")
+	assert.Equal(t, len(m.Sent()), 1)
+	assert.Equal(t, m.Sent()[0], msg)
+}
@@ -0,0 +1,63 @@
+package markdown
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"html/template"
+	"sync"
+)
+
+// Renderer converts markdown to sanitized html and keeps the most
+// recently rendered results, so the same posts and comments aren't
+// parsed on every page view
+type Renderer struct {
+	mu    sync.Mutex
+	size  int
+	items map[[sha256.Size]byte]*list.Element
+	order *list.List
+}
+
+type cacheItem struct {
+	key  [sha256.Size]byte
+	html template.HTML
+}
+
+// NewRenderer returns renderer that caches up to size results
+func NewRenderer(size int) *Renderer {
+	return &Renderer{
+		size:  size,
+		items: make(map[[sha256.Size]byte]*list.Element),
+		order: list.New(),
+	}
+}
+
+// HTML returns sanitized html of given markdown source
+func (r *Renderer) HTML(src string) template.HTML {
+	key := sha256.Sum256([]byte(src))
+
+	r.mu.Lock()
+	if e, ok := r.items[key]; ok {
+		r.order.MoveToFront(e)
+		r.mu.Unlock()
+		return e.Value.(*cacheItem).html
+	}
+	r.mu.Unlock()
+
+	rendered := template.HTML(Sanitize(Render(src)))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.items[key]; ok || r.size <= 0 {
+		return rendered
+	}
+	r.items[key] = r.order.PushFront(&cacheItem{key: key, html: rendered})
+
+	for r.order.Len() > r.size {
+		oldest := r.order.Back()
+		r.order.Remove(oldest)
+		delete(r.items, oldest.Value.(*cacheItem).key)
+	}
+
+	return rendered
+}
@@ -0,0 +1,268 @@
+package markdown
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var autolinkRX = regexp.MustCompile(`^<((?i:https?://|mailto:)[^\s<>]+)>`)
+
+// renderInline renders emphasis, code spans and links of single line.
+// Any other text is escaped
+func renderInline(s string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(s); {
+		c := s[i]
+
+		switch {
+		case c == '\\' && i+1 < len(s) && isPunct(s[i+1]):
+			b.WriteString(html.EscapeString(s[i+1 : i+2]))
+			i += 2
+
+		case c == '`':
+			n := runLength(s, i)
+			end := findCodeEnd(s, i+n, n)
+			if end < 0 {
+				b.WriteString(s[i : i+n])
+				i += n
+				continue
+			}
+			code := s[i+n : end]
+			if len(code) > 1 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.Trim(code, " ") != "" {
+				code = code[1 : len(code)-1]
+			}
+			b.WriteString("<code>" + html.EscapeString(code) + "</code>")
+			i = end + n
+
+		case c == '[':
+			text, href, title, n, ok := parseLink(s[i:])
+			if !ok {
+				b.WriteByte('[')
+				i++
+				continue
+			}
+			if !safeURL(href) {
+				b.WriteString(renderInline(text))
+			} else {
+				b.WriteString(`<a href="` + html.EscapeString(href) + `"`)
+				if title != "" {
+					b.WriteString(` title="` + html.EscapeString(title) + `"`)
+				}
+				b.WriteString(">" + renderInline(text) + "</a>")
+			}
+			i += n
+
+		case c == '<':
+			m := autolinkRX.FindStringSubmatch(s[i:])
+			if m == nil || !safeURL(m[1]) {
+				b.WriteString("&lt;")
+				i++
+				continue
+			}
+			b.WriteString(`<a href="` + html.EscapeString(m[1]) + `">` + html.EscapeString(m[1]) + "</a>")
+			i += len(m[0])
+
+		case c == '*' || c == '_' || c == '~':
+			n := runLength(s, i)
+			inner, end, tags, ok := parseEmphasis(s, i, n)
+			if !ok {
+				b.WriteString(s[i : i+n])
+				i += n
+				continue
+			}
+			for _, tag := range tags {
+				b.WriteString("<" + tag + ">")
+			}
+			b.WriteString(renderInline(inner))
+			for j := len(tags) - 1; j >= 0; j-- {
+				b.WriteString("</" + tags[j] + ">")
+			}
+			i = end
+
+		default:
+			j := i + 1
+			for j < len(s) && !strings.ContainsRune("\\`[<*_~", rune(s[j])) {
+				j++
+			}
+			b.WriteString(html.EscapeString(s[i:j]))
+			i = j
+		}
+	}
+
+	return b.String()
+}
+
+// parseEmphasis looks for the closing delimiter of emphasis run of length n
+// that opens at s[i]. It returns emphasized text, index right after
+// the closing run and tags which should wrap the text
+func parseEmphasis(s string, i, n int) (string, int, []string, bool) {
+	c := s[i]
+
+	var tags []string
+	switch {
+	case c == '~' && n == 2:
+		tags = []string{"del"}
+	case c == '~':
+		return "", 0, nil, false
+	case n == 1:
+		tags = []string{"em"}
+	case n == 2:
+		tags = []string{"strong"}
+	case n == 3:
+		tags = []string{"strong", "em"}
+	default:
+		return "", 0, nil, false
+	}
+
+	// Opening run must be followed by text and underscores
+	// don't emphasize inside of words
+	if i+n >= len(s) || isSpaceAt(s, i+n) {
+		return "", 0, nil, false
+	}
+	if c == '_' && isWordBefore(s, i) {
+		return "", 0, nil, false
+	}
+
+	for j := i + n; j < len(s); {
+		if s[j] == '`' {
+			// Delimiters inside of code spans don't count
+			m := runLength(s, j)
+			if end := findCodeEnd(s, j+m, m); end >= 0 {
+				j = end + m
+				continue
+			}
+			j += m
+			continue
+		}
+		if s[j] == '\\' {
+			j += 2
+			continue
+		}
+		if s[j] != c {
+			j++
+			continue
+		}
+
+		m := runLength(s, j)
+		closing := j + m - n
+		if m >= n && closing > i+n && !isSpaceBefore(s, closing) &&
+			(c != '_' || j+m >= len(s) || !isWordAt(s, j+m)) {
+
+			return s[i+n : closing], j + m, tags, true
+		}
+		j += m
+	}
+
+	return "", 0, nil, false
+}
+
+// parseLink parses link of form [text](href "title") at the beginning of s
+// and returns its parts with length of the whole link
+func parseLink(s string) (string, string, string, int, bool) {
+	depth := 0
+	closing := -1
+
+	for i := 0; i < len(s) && closing < 0; i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				closing = i
+			}
+		}
+	}
+	if closing < 0 || closing+1 >= len(s) || s[closing+1] != '(' {
+		return "", "", "", 0, false
+	}
+
+	depth = 0
+	end := -1
+	for i := closing + 1; i < len(s) && end < 0; i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				end = i
+			}
+		}
+	}
+	if end < 0 {
+		return "", "", "", 0, false
+	}
+
+	dest := strings.TrimSpace(s[closing+2 : end])
+
+	href, title := dest, ""
+	if k := strings.IndexAny(dest, " \t"); k >= 0 {
+		href, title = dest[:k], strings.TrimSpace(dest[k:])
+		if len(title) < 2 || !strings.ContainsRune(`"'`, rune(title[0])) || title[len(title)-1] != title[0] {
+			return "", "", "", 0, false
+		}
+		title = title[1 : len(title)-1]
+	}
+	href = strings.TrimSuffix(strings.TrimPrefix(href, "<"), ">")
+
+	return s[1:closing], href, title, end + 1, true
+}
+
+// findCodeEnd returns index of backtick run of exactly n length that closes
+// code span or -1 if there is none
+func findCodeEnd(s string, from, n int) int {
+	for j := from; j < len(s); {
+		if s[j] != '`' {
+			j++
+			continue
+		}
+		m := runLength(s, j)
+		if m == n {
+			return j
+		}
+		j += m
+	}
+	return -1
+}
+
+// runLength returns number of same characters starting from s[i]
+func runLength(s string, i int) int {
+	n := 1
+	for i+n < len(s) && s[i+n] == s[i] {
+		n++
+	}
+	return n
+}
+
+func isSpaceAt(s string, i int) bool {
+	r, _ := utf8.DecodeRuneInString(s[i:])
+	return unicode.IsSpace(r)
+}
+
+func isSpaceBefore(s string, i int) bool {
+	r, _ := utf8.DecodeLastRuneInString(s[:i])
+	return unicode.IsSpace(r)
+}
+
+func isWordAt(s string, i int) bool {
+	r, _ := utf8.DecodeRuneInString(s[i:])
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func isWordBefore(s string, i int) bool {
+	r, _ := utf8.DecodeLastRuneInString(s[:i])
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func isPunct(c byte) bool {
+	return strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", c) >= 0
+}
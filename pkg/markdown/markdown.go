@@ -0,0 +1,290 @@
+package markdown
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+/*
+	Markdown is a small renderer of commonly used subset of Markdown:
+	headings, emphasis, strikethrough, inline code, code blocks, ordered
+	and unordered lists, block quotes, horizontal rules and links.
+
+	Raw html in the source is never passed through - it is escaped as
+	any other text. Rendered html is additionally filtered by Sanitize
+*/
+
+var (
+	headingRX = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	hrRX      = regexp.MustCompile(`^ {0,3}(?:(?:\*[ \t]*){3,}|(?:-[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	fenceRX   = regexp.MustCompile("^ {0,3}(```+|~~~+)")
+	quoteRX   = regexp.MustCompile(`^ {0,3}> ?`)
+	bulletRX  = regexp.MustCompile(`^( {0,3})([-*+])([ \t]+|$)`)
+	orderedRX = regexp.MustCompile(`^( {0,3})(\d{1,9})([.)])([ \t]+|$)`)
+)
+
+// Render converts markdown source to html
+func Render(src string) string {
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	src = strings.ReplaceAll(src, "\r", "\n")
+	src = strings.ReplaceAll(src, "\t", "    ")
+
+	var b strings.Builder
+	renderBlocks(&b, strings.Split(src, "\n"), false)
+
+	return b.String()
+}
+
+// renderBlocks renders block level elements. Paragraphs of tight lists
+// aren't wrapped into <p>
+func renderBlocks(b *strings.Builder, lines []string, tight bool) {
+	for i := 0; i < len(lines); {
+		line := lines[i]
+
+		switch {
+		case isBlank(line):
+			i++
+
+		case fenceRX.MatchString(line):
+			fence := fenceRX.FindStringSubmatch(line)[1]
+			indent := len(line) - len(strings.TrimLeft(line, " "))
+
+			i++
+			var code []string
+			for ; i < len(lines); i++ {
+				if strings.HasPrefix(strings.TrimLeft(lines[i], " "), fence) &&
+					strings.Trim(strings.TrimSpace(lines[i]), fence[:1]) == "" {
+					i++
+					break
+				}
+				code = append(code, trimIndent(lines[i], indent))
+			}
+			writeCode(b, code)
+
+		case indentation(line) >= 4:
+			var code []string
+			for ; i < len(lines) && (isBlank(lines[i]) || indentation(lines[i]) >= 4); i++ {
+				code = append(code, trimIndent(lines[i], 4))
+			}
+			for len(code) > 0 && isBlank(code[len(code)-1]) {
+				code = code[:len(code)-1]
+			}
+			writeCode(b, code)
+
+		case hrRX.MatchString(line):
+			b.WriteString("<hr>\n")
+			i++
+
+		case headingRX.MatchString(line):
+			m := headingRX.FindStringSubmatch(line)
+			level := strconv.Itoa(len(m[1]))
+			b.WriteString("<h" + level + ">")
+			b.WriteString(renderInline(m[2]))
+			b.WriteString("</h" + level + ">\n")
+			i++
+
+		case quoteRX.MatchString(line):
+			var quote []string
+			for ; i < len(lines) && !isBlank(lines[i]); i++ {
+				quote = append(quote, quoteRX.ReplaceAllString(lines[i], ""))
+			}
+			b.WriteString("<blockquote>\n")
+			renderBlocks(b, quote, false)
+			b.WriteString("</blockquote>\n")
+
+		case bulletRX.MatchString(line) || orderedRX.MatchString(line):
+			i = renderList(b, lines, i)
+
+		default:
+			var para []string
+			for ; i < len(lines) && !isBlank(lines[i]) && !interruptsParagraph(lines[i]); i++ {
+				para = append(para, strings.TrimLeft(lines[i], " "))
+			}
+			if tight {
+				b.WriteString(renderParagraph(para))
+				b.WriteString("\n")
+			} else {
+				b.WriteString("<p>")
+				b.WriteString(renderParagraph(para))
+				b.WriteString("</p>\n")
+			}
+		}
+	}
+}
+
+// renderList renders list that starts at lines[start] and returns index of
+// the first line after the list
+func renderList(b *strings.Builder, lines []string, start int) int {
+	first := lines[start]
+	ordered := !bulletRX.MatchString(first)
+
+	// Items of one list share the marker kind ('-', '*', '+' or '.', ')')
+	marker := func(line string) (string, int, bool) {
+		if ordered {
+			m := orderedRX.FindStringSubmatch(line)
+			if m == nil {
+				return "", 0, false
+			}
+			return m[3], len(m[0]), true
+		}
+		m := bulletRX.FindStringSubmatch(line)
+		if m == nil {
+			return "", 0, false
+		}
+		return m[2], len(m[0]), true
+	}
+
+	kind, _, _ := marker(first)
+
+	if ordered {
+		num, _ := strconv.Atoi(orderedRX.FindStringSubmatch(first)[2])
+		if num != 1 {
+			b.WriteString(`<ol start="` + strconv.Itoa(num) + `">` + "\n")
+		} else {
+			b.WriteString("<ol>\n")
+		}
+	} else {
+		b.WriteString("<ul>\n")
+	}
+
+	var items [][]string
+	loose := false
+
+	i := start
+	for i < len(lines) {
+		k, width, ok := marker(lines[i])
+		if !ok || k != kind {
+			break
+		}
+		// Empty item still needs some content offset
+		if strings.TrimSpace(lines[i][width:]) == "" {
+			width = len(lines[i])
+		}
+
+		item := []string{lines[i][width:]}
+		i++
+
+		for i < len(lines) {
+			line := lines[i]
+			if isBlank(line) {
+				// Blank line continues the item only if something indented follows
+				j := i
+				for j < len(lines) && isBlank(lines[j]) {
+					j++
+				}
+				if j < len(lines) && indentation(lines[j]) >= width {
+					for ; i < j; i++ {
+						item = append(item, "")
+					}
+					loose = true
+					continue
+				}
+				if j < len(lines) {
+					if k, _, ok := marker(lines[j]); ok && k == kind {
+						loose = true
+					}
+				}
+				break
+			}
+			if indentation(line) >= width {
+				item = append(item, trimIndent(line, width))
+				i++
+				continue
+			}
+			// Lazy continuation of the item's paragraph
+			if _, _, isItem := marker(line); !isItem && !interruptsParagraph(line) && !isBlank(item[len(item)-1]) {
+				item = append(item, strings.TrimLeft(line, " "))
+				i++
+				continue
+			}
+			break
+		}
+
+		items = append(items, item)
+
+		for i < len(lines) && isBlank(lines[i]) {
+			i++
+		}
+	}
+
+	for _, item := range items {
+		b.WriteString("<li>")
+		renderBlocks(b, item, !loose)
+		b.WriteString("</li>\n")
+	}
+
+	if ordered {
+		b.WriteString("</ol>\n")
+	} else {
+		b.WriteString("</ul>\n")
+	}
+
+	return i
+}
+
+// renderParagraph joins paragraph lines. Lines ending with two spaces or
+// backslash are separated with hard line break
+func renderParagraph(lines []string) string {
+	var b strings.Builder
+
+	for i, line := range lines {
+		hardBreak := false
+		if i != len(lines)-1 {
+			original := line
+			line = strings.TrimRight(line, " ")
+			switch {
+			case len(original)-len(line) >= 2:
+				hardBreak = true
+			case strings.HasSuffix(line, `\`) && !strings.HasSuffix(line, `\\`):
+				line = strings.TrimSuffix(line, `\`)
+				hardBreak = true
+			}
+		}
+
+		b.WriteString(renderInline(line))
+
+		if i != len(lines)-1 {
+			if hardBreak {
+				b.WriteString("<br>")
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	return b.String()
+}
+
+func writeCode(b *strings.Builder, code []string) {
+	b.WriteString("<pre><code>")
+	for _, line := range code {
+		b.WriteString(html.EscapeString(line))
+		b.WriteString("\n")
+	}
+	b.WriteString("</code></pre>\n")
+}
+
+// interruptsParagraph reports whether line starts new block even without
+// blank line before it
+func interruptsParagraph(line string) bool {
+	return fenceRX.MatchString(line) || hrRX.MatchString(line) || headingRX.MatchString(line) ||
+		quoteRX.MatchString(line) || bulletRX.MatchString(line) ||
+		(orderedRX.MatchString(line) && orderedRX.FindStringSubmatch(line)[2] == "1")
+}
+
+func isBlank(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
+
+func indentation(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// trimIndent removes up to n leading spaces
+func trimIndent(line string, n int) string {
+	for i := 0; i < n && strings.HasPrefix(line, " "); i++ {
+		line = line[1:]
+	}
+	return line
+}
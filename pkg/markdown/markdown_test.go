@@ -0,0 +1,182 @@
+package markdown
+
+import (
+	"crypto/sha256"
+	"forum/internal/assert"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			name: "Paragraphs",
+			src:  "first\nline\n\nsecond",
+			want: "<p>first\nline</p>\n<p>second</p>\n",
+		},
+		{
+			name: "Hard line break",
+			src:  "first  \nsecond",
+			want: "<p>first<br>\nsecond</p>\n",
+		},
+		{
+			name: "Headings",
+			src:  "# One\n### Three ###\n#no",
+			want: "<h1>One</h1>\n<h3>Three</h3>\n<p>#no</p>\n",
+		},
+		{
+			name: "Emphasis",
+			src:  "*em* **strong** ***both*** _em_ ~~del~~ snake_case_name",
+			want: "<p><em>em</em> <strong>strong</strong> <strong><em>both</em></strong> <em>em</em> <del>del</del> snake_case_name</p>\n",
+		},
+		{
+			name: "Nested emphasis",
+			src:  "**bold *and em***",
+			want: "<p><strong>bold <em>and em</em></strong></p>\n",
+		},
+		{
+			name: "Unclosed emphasis",
+			src:  "2 * 3 = 6 and **open",
+			want: "<p>2 * 3 = 6 and **open</p>\n",
+		},
+		{
+			name: "Inline code",
+			src:  "use `a *b* <c>` and `` ` ``",
+			want: "<p>use <code>a *b* &lt;c&gt;</code> and <code>`</code></p>\n",
+		},
+		{
+			name: "Fenced code",
+			src:  "```go\nfunc main() {\n\t<b>\n}\n```",
+			want: "<pre><code>func main() {\n    &lt;b&gt;\n}\n</code></pre>\n",
+		},
+		{
+			name: "Indented code",
+			src:  "text\n\n    a := 1\n\n    b := 2\n",
+			want: "<p>text</p>\n<pre><code>a := 1\n\nb := 2\n</code></pre>\n",
+		},
+		{
+			name: "Unordered list",
+			src:  "- one\n- *two*\n- three",
+			want: "<ul>\n<li>one\n</li>\n<li><em>two</em>\n</li>\n<li>three\n</li>\n</ul>\n",
+		},
+		{
+			name: "Ordered list",
+			src:  "3. three\n4. four",
+			want: "<ol start=\"3\">\n<li>three\n</li>\n<li>four\n</li>\n</ol>\n",
+		},
+		{
+			name: "Nested list",
+			src:  "- one\n  - inner\n- two",
+			want: "<ul>\n<li>one\n<ul>\n<li>inner\n</li>\n</ul>\n</li>\n<li>two\n</li>\n</ul>\n",
+		},
+		{
+			name: "Loose list",
+			src:  "- one\n\n- two",
+			want: "<ul>\n<li><p>one</p>\n</li>\n<li><p>two</p>\n</li>\n</ul>\n",
+		},
+		{
+			name: "Quote",
+			src:  "> quoted\n> # title\n>> nested",
+			want: "<blockquote>\n<p>quoted</p>\n<h1>title</h1>\n<blockquote>\n<p>nested</p>\n</blockquote>\n</blockquote>\n",
+		},
+		{
+			name: "Horizontal rule",
+			src:  "above\n\n---\n\nbelow",
+			want: "<p>above</p>\n<hr>\n<p>below</p>\n",
+		},
+		{
+			name: "Links",
+			src:  `[site](https://example.com "Title") [*em*](/posts/1) <https://a.b/c?d=1&e=2>`,
+			want: `<p><a href="https://example.com" title="Title">site</a> <a href="/posts/1"><em>em</em></a> <a href="https://a.b/c?d=1&amp;e=2">https://a.b/c?d=1&amp;e=2</a></p>` + "\n",
+		},
+		{
+			name: "Unsafe link",
+			src:  "[click](javascript:alert(1)) [x](JaVaScRiPt:alert(1))",
+			want: "<p>click x</p>\n",
+		},
+		{
+			name: "Raw html is escaped",
+			src:  `<script>alert("x")</script> <img src=x onerror=alert(1)>`,
+			want: "<p>&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt; &lt;img src=x onerror=alert(1)&gt;</p>\n",
+		},
+		{
+			name: "Backslash escapes",
+			src:  `\*not em\* \[not link\]`,
+			want: "<p>*not em* [not link]</p>\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, Render(tt.src), tt.want)
+		})
+	}
+}
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{
+			name: "Allowed tags",
+			html: "<p><strong>a</strong><br><code>b</code></p>",
+			want: "<p><strong>a</strong><br><code>b</code></p>",
+		},
+		{
+			name: "Disallowed tags are dropped",
+			html: `<script>alert(1)</script><iframe src="x"></iframe><p>ok</p>`,
+			want: "alert(1)<p>ok</p>",
+		},
+		{
+			name: "Disallowed attributes are dropped",
+			html: `<p onclick="x()" style="color: red">a</p><ol start="2" type="a"></ol>`,
+			want: `<p>a</p><ol start="2"></ol>`,
+		},
+		{
+			name: "Safe link",
+			html: `<a href="https://example.com" target="_blank">a</a>`,
+			want: `<a href="https://example.com" rel="nofollow ugc noopener">a</a>`,
+		},
+		{
+			name: "Unsafe link",
+			html: `<a href="jav&#x61;script:alert(1)">a</a><a href='data:text/html,x'>b</a>`,
+			want: `<a rel="nofollow ugc noopener">a</a><a rel="nofollow ugc noopener">b</a>`,
+		},
+		{
+			name: "Unclosed and stray tags",
+			html: "</li><blockquote><p>text",
+			want: "<blockquote><p>text</p></blockquote>",
+		},
+		{
+			name: "Broken markup is escaped",
+			html: `a < b > c <p`,
+			want: "a &lt; b &gt; c &lt;p",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, Sanitize(tt.html), tt.want)
+		})
+	}
+}
+
+func TestRendererCache(t *testing.T) {
+	r := NewRenderer(2)
+
+	first := r.HTML("**a**")
+	assert.Equal(t, string(first), "<p><strong>a</strong></p>\n")
+	assert.Equal(t, r.HTML("**a**"), first)
+
+	r.HTML("b")
+	r.HTML("c")
+
+	assert.Equal(t, r.order.Len(), 2)
+	_, cached := r.items[sha256.Sum256([]byte("**a**"))]
+	assert.Equal(t, cached, false)
+}
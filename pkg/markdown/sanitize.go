@@ -0,0 +1,134 @@
+package markdown
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// allowedTags are tags that may appear in sanitized html mapped to
+// attributes they may have
+var allowedTags = map[string][]string{
+	"p": nil, "br": nil, "hr": nil,
+	"h1": nil, "h2": nil, "h3": nil, "h4": nil, "h5": nil, "h6": nil,
+	"em": nil, "strong": nil, "del": nil, "code": nil, "pre": nil,
+	"blockquote": nil, "ul": nil, "li": nil,
+	"ol": {"start"},
+	"a":  {"href", "title"},
+}
+
+var voidTags = map[string]bool{"br": true, "hr": true}
+
+var allowedSchemes = map[string]bool{"": true, "http": true, "https": true, "mailto": true}
+
+var (
+	tagRX    = regexp.MustCompile(`^<(/?)([a-zA-Z][a-zA-Z0-9]*)((?:\s+[a-zA-Z][a-zA-Z0-9-]*(?:\s*=\s*(?:"[^"]*"|'[^']*'|[^\s"'<>=]+))?)*)\s*/?>`)
+	attrRX   = regexp.MustCompile(`([a-zA-Z][a-zA-Z0-9-]*)(?:\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'<>=]+)))?`)
+	digitsRX = regexp.MustCompile(`^\d{1,9}$`)
+)
+
+// Sanitize filters given html by allow-list of tags and attributes. Other
+// tags are dropped, while their text stays escaped. Links are only allowed
+// to point to http(s), mailto or relative urls and are marked as
+// user-generated. Unclosed tags are closed, so sanitized html can't break
+// the markup of page it is placed to
+func Sanitize(s string) string {
+	var b strings.Builder
+	var open []string
+
+	for len(s) > 0 {
+		k := strings.IndexByte(s, '<')
+		if k < 0 {
+			k = len(s)
+		}
+		b.WriteString(escapeText(s[:k]))
+		s = s[k:]
+		if s == "" {
+			break
+		}
+
+		m := tagRX.FindStringSubmatch(s)
+		if m == nil {
+			b.WriteString("&lt;")
+			s = s[1:]
+			continue
+		}
+		s = s[len(m[0]):]
+
+		name := strings.ToLower(m[2])
+		attrs, ok := allowedTags[name]
+		if !ok {
+			continue
+		}
+
+		if m[1] == "/" {
+			// Close everything that was opened after matching tag
+			for i := len(open) - 1; i >= 0; i-- {
+				if open[i] != name {
+					continue
+				}
+				for j := len(open) - 1; j >= i; j-- {
+					b.WriteString("</" + open[j] + ">")
+				}
+				open = open[:i]
+				break
+			}
+			continue
+		}
+
+		b.WriteString("<" + name)
+		for _, attr := range attrRX.FindAllStringSubmatch(m[3], -1) {
+			key := strings.ToLower(attr[1])
+			value := html.UnescapeString(attr[2] + attr[3] + attr[4])
+			if !contains(attrs, key) {
+				continue
+			}
+			if key == "href" && !safeURL(value) || key == "start" && !digitsRX.MatchString(value) {
+				continue
+			}
+			b.WriteString(" " + key + `="` + html.EscapeString(value) + `"`)
+		}
+		if name == "a" {
+			b.WriteString(` rel="nofollow ugc noopener"`)
+		}
+		b.WriteString(">")
+
+		if !voidTags[name] {
+			open = append(open, name)
+		}
+	}
+
+	for i := len(open) - 1; i >= 0; i-- {
+		b.WriteString("</" + open[i] + ">")
+	}
+
+	return b.String()
+}
+
+// safeURL reports whether link may point to given url
+func safeURL(raw string) bool {
+	if raw == "" || strings.ContainsAny(raw, "\x00\t\n\r") {
+		return false
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	return allowedSchemes[strings.ToLower(u.Scheme)]
+}
+
+// escapeText escapes text between tags, keeping entities that are
+// already there
+func escapeText(s string) string {
+	return html.EscapeString(html.UnescapeString(s))
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,304 @@
+package migrate
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/*
+	Migrate is a small versioned migration runner. It reads pairs of
+	'<version>_<name>_up.sql' and '<version>_<name>_down.sql' files from
+	given file system and keeps track of applied versions in the
+	'schema_migrations' table.
+*/
+
+var (
+	ErrInvalidFileName = errors.New("migrate: invalid migration file name")
+	ErrMissingUp       = errors.New("migrate: migration has no up file")
+	ErrMissingDown     = errors.New("migrate: migration has no down file")
+	ErrUnknownVersion  = errors.New("migrate: unknown migration version")
+	ErrDuplicate       = errors.New("migrate: duplicate migration version")
+)
+
+const (
+	upSuffix   = "_up.sql"
+	downSuffix = "_down.sql"
+)
+
+// Migration is a single versioned schema change
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+// New returns new Migrator with migrations read from given file system.
+//
+// It returns an error if any of the files has invalid name or if any
+// migration lacks one of its up/down parts
+func New(db *sql.DB, files fs.FS) (*Migrator, error) {
+	migrations, err := readMigrations(files)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+	}, nil
+}
+
+// Migrations returns all known migrations sorted by version
+func (m *Migrator) Migrations() []Migration {
+	return m.migrations
+}
+
+// Version returns the latest applied version (0 if nothing is applied)
+func (m *Migrator) Version() (int, error) {
+	if err := m.ensureTable(); err != nil {
+		return 0, err
+	}
+
+	var version sql.NullInt64
+	err := m.db.QueryRow(`SELECT MAX(version) FROM schema_migrations`).Scan(&version)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(version.Int64), nil
+}
+
+// Up applies all pending migrations in ascending order.
+//
+// It returns versions that were applied. Every migration is applied in
+// its own transaction, so failed migration doesn't leave schema half-changed
+func (m *Migrator) Up() ([]int, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	var done []int
+	for _, mg := range m.migrations {
+		if _, ok := applied[mg.Version]; ok {
+			continue
+		}
+
+		query := `
+			INSERT INTO schema_migrations (version, name, applied_at)
+			VALUES ($1, $2, datetime('now', 'localtime'))
+		`
+		if err := m.run(mg.Up, query, mg.Version, mg.Name); err != nil {
+			return done, fmt.Errorf("migrate: apply %03d_%s: %w", mg.Version, mg.Name, err)
+		}
+		done = append(done, mg.Version)
+	}
+
+	return done, nil
+}
+
+// Down rolls back applied migrations in descending order until given version
+// becomes the latest applied one. Passing 0 rolls back everything.
+//
+// It returns versions that were rolled back
+func (m *Migrator) Down(version int) ([]int, error) {
+	if version < 0 {
+		return nil, ErrUnknownVersion
+	}
+	if version != 0 && m.find(version) == nil {
+		return nil, ErrUnknownVersion
+	}
+
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	var done []int
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		mg := m.migrations[i]
+		if mg.Version <= version {
+			break
+		}
+		if _, ok := applied[mg.Version]; !ok {
+			continue
+		}
+
+		query := `
+			DELETE FROM schema_migrations
+			WHERE version = $1
+		`
+		if err := m.run(mg.Down, query, mg.Version); err != nil {
+			return done, fmt.Errorf("migrate: rollback %03d_%s: %w", mg.Version, mg.Name, err)
+		}
+		done = append(done, mg.Version)
+	}
+
+	return done, nil
+}
+
+// run executes migration script and bookkeeping query in one transaction
+func (m *Migrator) run(script, query string, args ...interface{}) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if strings.TrimSpace(script) != "" {
+		if _, err := tx.Exec(script); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(query, args...); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// applied returns set of versions stored in schema_migrations table
+func (m *Migrator) applied() (map[int]struct{}, error) {
+	if err := m.ensureTable(); err != nil {
+		return nil, err
+	}
+
+	rows, err := m.db.Query(`SELECT version FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := make(map[int]struct{})
+	for rows.Next() {
+		var v int
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		versions[v] = struct{}{}
+	}
+
+	return versions, rows.Err()
+}
+
+func (m *Migrator) ensureTable() error {
+	query := `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			applied_at DATETIME NOT NULL
+		)
+	`
+
+	_, err := m.db.Exec(query)
+	return err
+}
+
+func (m *Migrator) find(version int) *Migration {
+	for i := range m.migrations {
+		if m.migrations[i].Version == version {
+			return &m.migrations[i]
+		}
+	}
+	return nil
+}
+
+// readMigrations collects up and down files from root of given file system
+// into sorted slice of migrations
+func readMigrations(files fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(files, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+
+	for _, e := range entries {
+		fname := e.Name()
+		if e.IsDir() || !strings.HasSuffix(fname, ".sql") {
+			continue
+		}
+
+		version, name, isUp, err := parseFileName(fname)
+		if err != nil {
+			return nil, err
+		}
+
+		content, err := fs.ReadFile(files, fname)
+		if err != nil {
+			return nil, err
+		}
+
+		mg, ok := byVersion[version]
+		if !ok {
+			mg = &Migration{Version: version, Name: name}
+			byVersion[version] = mg
+		} else if mg.Name != name {
+			return nil, fmt.Errorf("%w: %03d", ErrDuplicate, version)
+		}
+
+		if isUp {
+			mg.Up = string(content)
+		} else {
+			mg.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mg := range byVersion {
+		switch {
+		case mg.Up == "":
+			return nil, fmt.Errorf("%w: %03d_%s", ErrMissingUp, mg.Version, mg.Name)
+		case mg.Down == "":
+			return nil, fmt.Errorf("%w: %03d_%s", ErrMissingDown, mg.Version, mg.Name)
+		}
+		migrations = append(migrations, *mg)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// parseFileName splits '001_initial_setup_up.sql' into version (1),
+// name ('initial_setup') and direction (up)
+func parseFileName(fname string) (int, string, bool, error) {
+	var isUp bool
+	var base string
+
+	switch {
+	case strings.HasSuffix(fname, upSuffix):
+		isUp = true
+		base = strings.TrimSuffix(fname, upSuffix)
+	case strings.HasSuffix(fname, downSuffix):
+		base = strings.TrimSuffix(fname, downSuffix)
+	default:
+		return 0, "", false, fmt.Errorf("%w: %s", ErrInvalidFileName, fname)
+	}
+
+	versionStr, name, ok := strings.Cut(base, "_")
+	if !ok || name == "" {
+		return 0, "", false, fmt.Errorf("%w: %s", ErrInvalidFileName, fname)
+	}
+
+	version, err := strconv.Atoi(versionStr)
+	if err != nil || version <= 0 {
+		return 0, "", false, fmt.Errorf("%w: %s", ErrInvalidFileName, fname)
+	}
+
+	return version, name, isUp, nil
+}
@@ -0,0 +1,140 @@
+package migrate
+
+import (
+	"database/sql"
+	"errors"
+	"forum/internal/assert"
+	"testing"
+	"testing/fstest"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func newTestDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every new connection to ':memory:' is a new database
+	db.SetMaxOpenConns(1)
+
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+var testFiles = fstest.MapFS{
+	"001_users_up.sql":   {Data: []byte("CREATE TABLE users (id INTEGER PRIMARY KEY);")},
+	"001_users_down.sql": {Data: []byte("DROP TABLE users;")},
+	"002_posts_up.sql":   {Data: []byte("CREATE TABLE posts (id INTEGER PRIMARY KEY);")},
+	"002_posts_down.sql": {Data: []byte("DROP TABLE posts;")},
+	"003_tags_up.sql":    {Data: []byte("CREATE TABLE tags (id INTEGER PRIMARY KEY);")},
+	"003_tags_down.sql":  {Data: []byte("DROP TABLE tags;")},
+}
+
+func tableExists(t *testing.T, db *sql.DB, name string) bool {
+	var exists bool
+	query := `SELECT EXISTS(SELECT true FROM sqlite_master WHERE type = 'table' AND name = $1)`
+	if err := db.QueryRow(query, name).Scan(&exists); err != nil {
+		t.Fatal(err)
+	}
+	return exists
+}
+
+func TestUpDown(t *testing.T) {
+	db := newTestDB(t)
+
+	m, err := New(db, testFiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	applied, err := m.Up()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(applied), 3)
+
+	version, _ := m.Version()
+	assert.Equal(t, version, 3)
+	assert.Equal(t, tableExists(t, db, "tags"), true)
+
+	// Second run has nothing to apply
+	applied, err = m.Up()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(applied), 0)
+
+	rolledBack, err := m.Down(1)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(rolledBack), 2)
+	assert.Equal(t, rolledBack[0], 3)
+
+	version, _ = m.Version()
+	assert.Equal(t, version, 1)
+	assert.Equal(t, tableExists(t, db, "posts"), false)
+	assert.Equal(t, tableExists(t, db, "users"), true)
+
+	_, err = m.Down(42)
+	assert.Equal(t, errors.Is(err, ErrUnknownVersion), true)
+}
+
+func TestFailedMigrationIsRolledBack(t *testing.T) {
+	db := newTestDB(t)
+
+	files := fstest.MapFS{
+		"001_users_up.sql":    {Data: []byte("CREATE TABLE users (id INTEGER PRIMARY KEY);")},
+		"001_users_down.sql":  {Data: []byte("DROP TABLE users;")},
+		"002_broken_up.sql":   {Data: []byte("CREATE TABLE broken (id INTEGER PRIMARY KEY); CREATE TABLE")},
+		"002_broken_down.sql": {Data: []byte("DROP TABLE broken;")},
+	}
+
+	m, err := New(db, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	applied, err := m.Up()
+	assert.Equal(t, err != nil, true)
+	assert.Equal(t, len(applied), 1)
+	assert.Equal(t, tableExists(t, db, "broken"), false)
+
+	version, _ := m.Version()
+	assert.Equal(t, version, 1)
+}
+
+func TestInvalidFiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   fstest.MapFS
+		wantErr error
+	}{
+		{
+			name:    "No version",
+			files:   fstest.MapFS{"users_up.sql": {}},
+			wantErr: ErrInvalidFileName,
+		},
+		{
+			name:    "No direction",
+			files:   fstest.MapFS{"001_users.sql": {}},
+			wantErr: ErrInvalidFileName,
+		},
+		{
+			name:    "Missing down",
+			files:   fstest.MapFS{"001_users_up.sql": {Data: []byte("SELECT 1;")}},
+			wantErr: ErrMissingDown,
+		},
+		{
+			name: "Duplicate version",
+			files: fstest.MapFS{
+				"001_users_up.sql": {Data: []byte("SELECT 1;")},
+				"001_posts_up.sql": {Data: []byte("SELECT 1;")},
+			},
+			wantErr: ErrDuplicate,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(nil, tt.files)
+			assert.Equal(t, errors.Is(err, tt.wantErr), true)
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+/*
+	Oauth implements authorization code flow of OAuth 2.0 with state and
+	PKCE (RFC 7636). State protects callback from login CSRF, verifier
+	makes intercepted code useless without the session it was issued for.
+
+	Providers that support OpenID Connect are discovered by issuer and
+	their ID tokens are verified with published keys.
+*/
+
+var (
+	ErrExchange = errors.New("oauth: code exchange failed")
+	ErrRequest  = errors.New("oauth: provider request failed")
+)
+
+// defaultClient is used if config doesn't have its own client
+var defaultClient = &http.Client{Timeout: 10 * time.Second}
+
+// Config describes OAuth application registered at provider
+type Config struct {
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	AuthURL      string
+	TokenURL     string
+	Scopes       []string
+	Client       *http.Client
+}
+
+// Token is a response of token endpoint. IDToken is returned only by
+// OpenID Connect providers
+type Token struct {
+	AccessToken string
+	IDToken     string
+}
+
+// AuthCodeURL returns address of provider's consent page. Verifier is sent
+// as S256 code challenge, empty verifier disables PKCE. Extra parameters
+// (like OpenID Connect nonce) are added as is
+func (c *Config) AuthCodeURL(state, verifier string, extra ...[2]string) string {
+	v := url.Values{
+		"client_id":     {c.ClientID},
+		"redirect_uri":  {c.RedirectURL},
+		"response_type": {"code"},
+		"state":         {state},
+	}
+	if len(c.Scopes) > 0 {
+		v.Set("scope", strings.Join(c.Scopes, " "))
+	}
+	if verifier != "" {
+		v.Set("code_challenge", Challenge(verifier))
+		v.Set("code_challenge_method", "S256")
+	}
+	for _, kv := range extra {
+		v.Set(kv[0], kv[1])
+	}
+
+	sep := "?"
+	if strings.Contains(c.AuthURL, "?") {
+		sep = "&"
+	}
+
+	return c.AuthURL + sep + v.Encode()
+}
+
+// Exchange trades authorization code for tokens
+func (c *Config) Exchange(ctx context.Context, code, verifier string) (Token, error) {
+	v := url.Values{
+		"client_id":     {c.ClientID},
+		"client_secret": {c.ClientSecret},
+		"code":          {code},
+		"redirect_uri":  {c.RedirectURL},
+		"grant_type":    {"authorization_code"},
+	}
+	if verifier != "" {
+		v.Set("code_verifier", verifier)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.TokenURL, strings.NewReader(v.Encode()))
+	if err != nil {
+		return Token{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	// GitHub answers with form encoded body unless json is asked
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.client().Do(req)
+	if err != nil {
+		return Token{}, err
+	}
+	defer resp.Body.Close()
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		IDToken     string `json:"id_token"`
+		Error       string `json:"error"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&token); err != nil {
+		return Token{}, fmt.Errorf("%w: %v", ErrExchange, err)
+	}
+	if resp.StatusCode != http.StatusOK || token.AccessToken == "" {
+		return Token{}, fmt.Errorf("%w: status %d %s", ErrExchange, resp.StatusCode, token.Error)
+	}
+
+	return Token{AccessToken: token.AccessToken, IDToken: token.IDToken}, nil
+}
+
+// Get requests resource of provider's API with access token (if it's not
+// empty) and decodes json response into v
+func (c *Config) Get(ctx context.Context, resourceURL, accessToken string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, resourceURL, nil)
+	if err != nil {
+		return err
+	}
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.client().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: %s - status %d", ErrRequest, resourceURL, resp.StatusCode)
+	}
+
+	return decodeJSON(resp.Body, v)
+}
+
+// decodeJSON decodes json body with limited size. Numbers are kept as
+// json.Number, so numeric ids aren't turned into floats
+func decodeJSON(r io.Reader, v any) error {
+	d := json.NewDecoder(io.LimitReader(r, 1<<20))
+	d.UseNumber()
+	return d.Decode(v)
+}
+
+func (c *Config) client() *http.Client {
+	if c.Client != nil {
+		return c.Client
+	}
+	return defaultClient
+}
+
+// RandomString returns url safe random string that is used as state and
+// PKCE verifier
+func RandomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Challenge returns S256 code challenge of verifier
+func Challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,95 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"forum/internal/assert"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// newProvider starts stand-in provider that issues access token only for
+// the known code with matching PKCE verifier
+func newProvider(t *testing.T, code, verifier string) *httptest.Server {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Fatal(err)
+		}
+		if r.PostForm.Get("code") != code || r.PostForm.Get("code_verifier") != verifier || r.PostForm.Get("client_secret") != "secret" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "token-1", "token_type": "bearer"})
+	})
+
+	mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token-1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"email": "user@example.com"})
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestAuthCodeURL(t *testing.T) {
+	c := Config{
+		ClientID:    "client",
+		RedirectURL: "http://localhost/callback",
+		AuthURL:     "https://provider.example/auth?prompt=consent",
+		Scopes:      []string{"email", "profile"},
+	}
+
+	u, err := url.Parse(c.AuthCodeURL("state-1", "verifier-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+
+	assert.Equal(t, q.Get("prompt"), "consent")
+	assert.Equal(t, q.Get("client_id"), "client")
+	assert.Equal(t, q.Get("state"), "state-1")
+	assert.Equal(t, q.Get("scope"), "email profile")
+	assert.Equal(t, q.Get("code_challenge"), Challenge("verifier-1"))
+	assert.Equal(t, q.Get("code_challenge_method"), "S256")
+}
+
+func TestChallenge(t *testing.T) {
+	// Example from RFC 7636, appendix B
+	assert.Equal(t, Challenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"), "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM")
+}
+
+func TestExchange(t *testing.T) {
+	srv := newProvider(t, "code-1", "verifier-1")
+	c := Config{ClientID: "client", ClientSecret: "secret", TokenURL: srv.URL + "/token"}
+
+	token, err := c.Exchange(context.Background(), "code-1", "verifier-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, token.AccessToken, "token-1")
+
+	var info struct {
+		Email string `json:"email"`
+	}
+	if err := c.Get(context.Background(), srv.URL+"/user", token.AccessToken, &info); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, info.Email, "user@example.com")
+
+	_, err = c.Exchange(context.Background(), "code-1", "other-verifier")
+	assert.Equal(t, errors.Is(err, ErrExchange), true)
+
+	err = c.Get(context.Background(), srv.URL+"/user", "wrong", &info)
+	assert.Equal(t, errors.Is(err, ErrRequest), true)
+}
@@ -0,0 +1,386 @@
+package oauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrDiscovery = errors.New("oauth: provider discovery failed")
+
+// ProviderConfig describes external login provider. If Issuer is set the
+// provider is treated as OpenID Connect one: missing endpoints are taken
+// from its discovery document and ID token is verified. Explicitly set
+// endpoints are never overridden
+type ProviderConfig struct {
+	Name        string // used in urls, e.g. /login/{name}
+	DisplayName string // shown on login button
+
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+
+	Issuer      string
+	AuthURL     string
+	TokenURL    string
+	UserInfoURL string
+	JWKSURL     string
+
+	Scopes      []string
+	Claims      Claims
+	DisablePKCE bool
+
+	// EmailDomain is used to make placeholder email when provider doesn't
+	// share user's email
+	EmailDomain string
+}
+
+// Claims maps fields of ID token or user info response to user data
+type Claims struct {
+	Subject  string
+	Email    string
+	Username string
+
+	// EmailVerified is a boolean claim. Email is considered verified if
+	// it's not set, because provider only shares verified addresses
+	EmailVerified string
+}
+
+// Identity is user data returned by provider
+type Identity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	Username      string
+	EmailVerified bool
+
+	// Placeholder is true if email is made up because provider didn't share it
+	Placeholder bool
+}
+
+var oidcClaims = Claims{Subject: "sub", Email: "email", Username: "preferred_username", EmailVerified: "email_verified"}
+
+// Presets hold known settings of popular providers, so only client
+// credentials need to be configured for them
+var Presets = map[string]ProviderConfig{
+	"google": {
+		DisplayName: "Google",
+		Issuer:      "https://accounts.google.com",
+		Scopes:      []string{"openid", "email", "profile"},
+		Claims:      Claims{Subject: "sub", Email: "email", Username: "name", EmailVerified: "email_verified"},
+	},
+	"github": {
+		DisplayName: "GitHub",
+		AuthURL:     "https://github.com/login/oauth/authorize",
+		TokenURL:    "https://github.com/login/oauth/access_token",
+		UserInfoURL: "https://api.github.com/user",
+		Claims:      Claims{Subject: "id", Email: "email", Username: "login"},
+	},
+	"gitlab": {
+		DisplayName: "GitLab",
+		Issuer:      "https://gitlab.com",
+		Scopes:      []string{"openid", "email", "profile"},
+		Claims:      Claims{Subject: "sub", Email: "email", Username: "nickname", EmailVerified: "email_verified"},
+	},
+}
+
+// Provider is configured external login provider
+type Provider struct {
+	cfg    ProviderConfig
+	client *http.Client
+	now    func() time.Time
+
+	mu         sync.Mutex
+	discovered bool
+	keys       *keySet
+}
+
+// NewProvider returns provider with settings of preset with the same name
+// filled in where config doesn't have them. Client is used for all
+// requests to the provider, default client is used if it's nil
+func NewProvider(cfg ProviderConfig, client *http.Client) *Provider {
+	if preset, ok := Presets[cfg.Name]; ok {
+		cfg = withPreset(cfg, preset)
+	}
+	if cfg.DisplayName == "" {
+		cfg.DisplayName = cfg.Name
+	}
+	if cfg.Issuer != "" && len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "email", "profile"}
+	}
+	// Unset claims have standard names. Email verification is only known
+	// from OpenID Connect providers
+	cfg.Claims = withDefaultClaims(cfg.Claims, cfg.Issuer != "")
+	if cfg.EmailDomain == "" {
+		cfg.EmailDomain = cfg.Name + ".invalid"
+	}
+	if client == nil {
+		client = defaultClient
+	}
+
+	return &Provider{cfg: cfg, client: client, now: time.Now}
+}
+
+func withPreset(cfg, preset ProviderConfig) ProviderConfig {
+	setDefault(&cfg.DisplayName, preset.DisplayName)
+	setDefault(&cfg.Issuer, preset.Issuer)
+	setDefault(&cfg.AuthURL, preset.AuthURL)
+	setDefault(&cfg.TokenURL, preset.TokenURL)
+	setDefault(&cfg.UserInfoURL, preset.UserInfoURL)
+	setDefault(&cfg.JWKSURL, preset.JWKSURL)
+	setDefault(&cfg.EmailDomain, preset.EmailDomain)
+	setDefault(&cfg.Claims.Subject, preset.Claims.Subject)
+	setDefault(&cfg.Claims.Email, preset.Claims.Email)
+	setDefault(&cfg.Claims.Username, preset.Claims.Username)
+	setDefault(&cfg.Claims.EmailVerified, preset.Claims.EmailVerified)
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = preset.Scopes
+	}
+	return cfg
+}
+
+func withDefaultClaims(c Claims, oidc bool) Claims {
+	setDefault(&c.Subject, oidcClaims.Subject)
+	setDefault(&c.Email, oidcClaims.Email)
+	setDefault(&c.Username, oidcClaims.Username)
+	if oidc {
+		setDefault(&c.EmailVerified, oidcClaims.EmailVerified)
+	}
+	return c
+}
+
+// setDefault sets v to def if it's empty
+func setDefault(v *string, def string) {
+	if *v == "" {
+		*v = def
+	}
+}
+
+func (p *Provider) Name() string {
+	return p.cfg.Name
+}
+
+func (p *Provider) DisplayName() string {
+	return p.cfg.DisplayName
+}
+
+// IsOIDC reports whether ID tokens of provider are verified
+func (p *Provider) IsOIDC() bool {
+	return p.cfg.Issuer != ""
+}
+
+// AuthCodeURL returns address of provider's consent page
+func (p *Provider) AuthCodeURL(ctx context.Context, state, verifier string) (string, error) {
+	c, err := p.config(ctx)
+	if err != nil {
+		return "", err
+	}
+	if p.cfg.DisablePKCE {
+		verifier = ""
+	}
+
+	if p.IsOIDC() {
+		return c.AuthCodeURL(state, verifier, [2]string{"nonce", nonce(state)}), nil
+	}
+	return c.AuthCodeURL(state, verifier), nil
+}
+
+// Identify exchanges code for tokens and returns identity of the user. For
+// OpenID Connect providers claims are taken from verified ID token and
+// completed from user info endpoint
+func (p *Provider) Identify(ctx context.Context, code, state, verifier string) (Identity, error) {
+	c, err := p.config(ctx)
+	if err != nil {
+		return Identity{}, err
+	}
+	if p.cfg.DisablePKCE {
+		verifier = ""
+	}
+
+	token, err := c.Exchange(ctx, code, verifier)
+	if err != nil {
+		return Identity{}, err
+	}
+
+	claims := map[string]any{}
+
+	if p.IsOIDC() {
+		if token.IDToken == "" {
+			return Identity{}, fmt.Errorf("%w: no id token", ErrInvalidIDToken)
+		}
+		claims, err = verifyIDToken(ctx, token.IDToken, p.keys, idTokenExpectation{
+			issuer:   p.cfg.Issuer,
+			clientID: p.cfg.ClientID,
+			nonce:    nonce(state),
+			now:      p.now(),
+		})
+		if err != nil {
+			return Identity{}, err
+		}
+	}
+
+	incomplete := claimString(claims, p.cfg.Claims.Email) == "" || claimString(claims, p.cfg.Claims.Username) == ""
+	if p.cfg.UserInfoURL != "" && (!p.IsOIDC() || incomplete) {
+		info := map[string]any{}
+		if err := c.Get(ctx, p.cfg.UserInfoURL, token.AccessToken, &info); err != nil {
+			return Identity{}, err
+		}
+		// Subject of verified ID token can't be replaced by user info
+		for k, v := range info {
+			if _, ok := claims[k]; !ok || !p.IsOIDC() {
+				claims[k] = v
+			}
+		}
+	}
+
+	return p.identity(claims)
+}
+
+func (p *Provider) identity(claims map[string]any) (Identity, error) {
+	id := Identity{
+		Provider:      p.cfg.Name,
+		Subject:       claimString(claims, p.cfg.Claims.Subject),
+		Email:         strings.ToLower(claimString(claims, p.cfg.Claims.Email)),
+		Username:      claimString(claims, p.cfg.Claims.Username),
+		EmailVerified: true,
+	}
+	if id.Subject == "" {
+		return Identity{}, fmt.Errorf("oauth: %s didn't return %q claim", p.cfg.Name, p.cfg.Claims.Subject)
+	}
+	if p.cfg.Claims.EmailVerified != "" {
+		verified, _ := claims[p.cfg.Claims.EmailVerified].(bool)
+		// Some providers send it as a string
+		if s, ok := claims[p.cfg.Claims.EmailVerified].(string); ok {
+			verified = s == "true"
+		}
+		id.EmailVerified = verified
+	}
+	if id.Username == "" {
+		id.Username = id.Subject
+	}
+	if id.Email == "" {
+		id.Email = strings.ToLower(id.Username) + "@" + p.cfg.EmailDomain
+		id.Placeholder = true
+	}
+
+	return id, nil
+}
+
+// config returns OAuth client of provider. Endpoints of OpenID Connect
+// providers are discovered on the first use, so unavailable provider
+// doesn't prevent server from starting
+func (p *Provider) config(ctx context.Context) (*Config, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.IsOIDC() && !p.discovered {
+		if err := p.discover(ctx); err != nil {
+			return nil, err
+		}
+		p.keys = &keySet{url: p.cfg.JWKSURL, client: p.client}
+		p.discovered = true
+	}
+
+	return &Config{
+		ClientID:     p.cfg.ClientID,
+		ClientSecret: p.cfg.ClientSecret,
+		RedirectURL:  p.cfg.RedirectURL,
+		AuthURL:      p.cfg.AuthURL,
+		TokenURL:     p.cfg.TokenURL,
+		Scopes:       p.cfg.Scopes,
+		Client:       p.client,
+	}, nil
+}
+
+// discover fills missing endpoints from OpenID Connect discovery document
+func (p *Provider) discover(ctx context.Context) error {
+	if p.cfg.AuthURL != "" && p.cfg.TokenURL != "" && p.cfg.JWKSURL != "" {
+		return nil
+	}
+
+	c := Config{Client: p.client}
+	var doc struct {
+		Issuer      string `json:"issuer"`
+		AuthURL     string `json:"authorization_endpoint"`
+		TokenURL    string `json:"token_endpoint"`
+		UserInfoURL string `json:"userinfo_endpoint"`
+		JWKSURL     string `json:"jwks_uri"`
+	}
+	discoveryURL := strings.TrimSuffix(p.cfg.Issuer, "/") + "/.well-known/openid-configuration"
+	if err := c.Get(ctx, discoveryURL, "", &doc); err != nil {
+		return fmt.Errorf("%w: %v", ErrDiscovery, err)
+	}
+	if doc.Issuer != p.cfg.Issuer {
+		return fmt.Errorf("%w: issuer %q doesn't match %q", ErrDiscovery, doc.Issuer, p.cfg.Issuer)
+	}
+
+	setDefault(&p.cfg.AuthURL, doc.AuthURL)
+	setDefault(&p.cfg.TokenURL, doc.TokenURL)
+	setDefault(&p.cfg.UserInfoURL, doc.UserInfoURL)
+	setDefault(&p.cfg.JWKSURL, doc.JWKSURL)
+
+	if p.cfg.AuthURL == "" || p.cfg.TokenURL == "" || p.cfg.JWKSURL == "" {
+		return fmt.Errorf("%w: endpoints are missing", ErrDiscovery)
+	}
+	return nil
+}
+
+// nonce binds ID token to the login attempt. It's derived from state, which
+// is already kept in the session of attempt
+func nonce(state string) string {
+	sum := sha256.Sum256([]byte("nonce:" + state))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func claimString(claims map[string]any, name string) string {
+	if name == "" {
+		return ""
+	}
+	switch v := claims[name].(type) {
+	case string:
+		return v
+	case fmt.Stringer: // json.Number
+		return v.String()
+	}
+	return ""
+}
+
+// Registry holds configured providers in order they should be shown
+type Registry struct {
+	providers map[string]*Provider
+	order     []*Provider
+}
+
+// NewRegistry returns registry of providers. Providers without client id are skipped
+func NewRegistry(configs []ProviderConfig, client *http.Client) *Registry {
+	r := &Registry{providers: make(map[string]*Provider)}
+
+	for _, cfg := range configs {
+		if cfg.Name == "" || cfg.ClientID == "" {
+			continue
+		}
+		if _, ok := r.providers[cfg.Name]; ok {
+			continue
+		}
+		p := NewProvider(cfg, client)
+		r.providers[cfg.Name] = p
+		r.order = append(r.order, p)
+	}
+
+	return r
+}
+
+func (r *Registry) Get(name string) (*Provider, bool) {
+	p, ok := r.providers[name]
+	return p, ok
+}
+
+func (r *Registry) List() []*Provider {
+	return r.order
+}
@@ -0,0 +1,263 @@
+package oauth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"forum/internal/assert"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// oidcProvider is a stand-in OpenID Connect provider. Token endpoint returns
+// ID token made by sign with claims that can be changed by tests
+type oidcProvider struct {
+	*httptest.Server
+	key    crypto.Signer
+	alg    string
+	claims map[string]any
+	nonce  string
+}
+
+func newOIDCProvider(t *testing.T, key crypto.Signer, alg string) *oidcProvider {
+	p := &oidcProvider{key: key, alg: alg}
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 p.URL,
+			"authorization_endpoint": p.URL + "/auth",
+			"token_endpoint":         p.URL + "/token",
+			"userinfo_endpoint":      p.URL + "/userinfo",
+			"jwks_uri":               p.URL + "/jwks",
+		})
+	})
+
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		jwk := map[string]string{"kid": "k1", "use": "sig"}
+		switch pub := key.Public().(type) {
+		case *rsa.PublicKey:
+			jwk["kty"] = "RSA"
+			jwk["n"] = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk["e"] = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case *ecdsa.PublicKey:
+			jwk["kty"] = "EC"
+			jwk["crv"] = "P-256"
+			jwk["x"] = base64.RawURLEncoding.EncodeToString(pub.X.FillBytes(make([]byte, 32)))
+			jwk["y"] = base64.RawURLEncoding.EncodeToString(pub.Y.FillBytes(make([]byte, 32)))
+		}
+		json.NewEncoder(w).Encode(map[string]any{"keys": []any{jwk}})
+	})
+
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		claims := map[string]any{
+			"iss":            p.URL,
+			"aud":            "client",
+			"sub":            "42",
+			"email":          "User@Example.com",
+			"email_verified": true,
+			"nonce":          p.nonce,
+			"iat":            time.Now().Unix(),
+			"exp":            time.Now().Add(time.Hour).Unix(),
+		}
+		for k, v := range p.claims {
+			claims[k] = v
+		}
+		json.NewEncoder(w).Encode(map[string]string{
+			"access_token": "token-1",
+			"id_token":     sign(t, p.key, p.alg, claims),
+		})
+	})
+
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"sub": "other", "preferred_username": "user"})
+	})
+
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Close)
+
+	return p
+}
+
+func sign(t *testing.T, key crypto.Signer, alg string, claims map[string]any) string {
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": "k1", "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	digest := sha256.Sum256([]byte(signed))
+
+	var sig []byte
+	var err error
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		sig, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+	case *ecdsa.PrivateKey:
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, k, digest[:])
+		sig = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+// login goes through the flow as browser would do and returns identity
+func login(t *testing.T, srv *oidcProvider, p *Provider) (Identity, error) {
+	authURL, err := p.AuthCodeURL(context.Background(), "state-1", "verifier-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.nonce = u.Query().Get("nonce")
+
+	return p.Identify(context.Background(), "code-1", "state-1", "verifier-1")
+}
+
+func TestProviderOIDC(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		alg string
+		key crypto.Signer
+	}{{"RS256", rsaKey}, {"ES256", ecKey}} {
+		t.Run(tt.alg, func(t *testing.T) {
+			srv := newOIDCProvider(t, tt.key, tt.alg)
+			p := NewProvider(ProviderConfig{Name: "keycloak", ClientID: "client", Issuer: srv.URL}, nil)
+
+			id, err := login(t, srv, p)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, id.Provider, "keycloak")
+			assert.Equal(t, id.Subject, "42")
+			assert.Equal(t, id.Email, "user@example.com")
+			assert.Equal(t, id.EmailVerified, true)
+			// Username is completed from user info, subject is not replaced
+			assert.Equal(t, id.Username, "user")
+		})
+	}
+}
+
+func TestProviderInvalidIDToken(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		claims map[string]any
+		key    crypto.Signer
+		alg    string
+		now    time.Time
+	}{
+		{name: "Wrong audience", claims: map[string]any{"aud": "other-client"}},
+		{name: "Audience list", claims: map[string]any{"aud": []string{"other-client"}}},
+		{name: "Wrong issuer", claims: map[string]any{"iss": "https://attacker.example"}},
+		{name: "Wrong nonce", claims: map[string]any{"nonce": "replayed"}},
+		{name: "Expired", now: time.Now().Add(2 * time.Hour)},
+		{name: "Wrong key", key: otherKey},
+		{name: "Algorithm none", alg: "none"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newOIDCProvider(t, key, "RS256")
+			srv.claims = tt.claims
+			if tt.key != nil {
+				srv.key = tt.key
+			}
+			if tt.alg != "" {
+				srv.alg = tt.alg
+			}
+
+			p := NewProvider(ProviderConfig{Name: "keycloak", ClientID: "client", Issuer: srv.URL}, nil)
+			if !tt.now.IsZero() {
+				p.now = func() time.Time { return tt.now }
+			}
+
+			_, err := login(t, srv, p)
+			assert.Equal(t, errors.Is(err, ErrInvalidIDToken), true)
+		})
+	}
+}
+
+func TestProviderOAuth2(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "token-1"})
+	})
+	mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id": 12345678901, "login": "Octocat", "email": null}`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	// Endpoints of preset are replaced by stand-in ones
+	p := NewProvider(ProviderConfig{
+		Name:        "github",
+		ClientID:    "client",
+		TokenURL:    srv.URL + "/token",
+		UserInfoURL: srv.URL + "/user",
+	}, nil)
+
+	assert.Equal(t, p.DisplayName(), "GitHub")
+	assert.Equal(t, p.IsOIDC(), false)
+
+	id, err := p.Identify(context.Background(), "code-1", "state-1", "verifier-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, id.Subject, "12345678901")
+	assert.Equal(t, id.Username, "Octocat")
+	assert.Equal(t, id.Email, "octocat@github.invalid")
+	assert.Equal(t, id.Placeholder, true)
+}
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry([]ProviderConfig{
+		{Name: "github", ClientID: "a"},
+		{Name: "google"},
+		{Name: "keycloak", ClientID: "b", Issuer: "https://kc.example/realms/forum", DisplayName: "Company SSO"},
+		{Name: "github", ClientID: "c"},
+	}, nil)
+
+	assert.Equal(t, len(r.List()), 2)
+	assert.Equal(t, r.List()[1].DisplayName(), "Company SSO")
+
+	_, ok := r.Get("google")
+	assert.Equal(t, ok, false)
+
+	p, ok := r.Get("github")
+	assert.Equal(t, ok, true)
+	assert.Equal(t, p.cfg.ClientID, "a")
+}
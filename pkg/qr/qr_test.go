@@ -0,0 +1,234 @@
+package qr
+
+import (
+	"fmt"
+	"forum/internal/assert"
+	"strings"
+	"testing"
+)
+
+// Error correction of "HELLO WORLD" from version 1-M example of the standard
+func TestReedSolomon(t *testing.T) {
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	want := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+
+	got := reedSolomonRemainder(data, reedSolomonDivisor(10))
+	assert.Equal(t, string(got), string(want))
+}
+
+func TestFunctionBits(t *testing.T) {
+	// Medium level with mask 0 and version 7 information from the standard
+	assert.Equal(t, formatBits(0), 0b101010000010010)
+	assert.Equal(t, versionBits(7), 0b000111110010010100)
+
+	assert.Equal(t, len(alignmentPatternPositions(1)), 0)
+	assert.Equal(t, fmt.Sprint(alignmentPatternPositions(2)), "[6 18]")
+	assert.Equal(t, fmt.Sprint(alignmentPatternPositions(7)), "[6 22 38]")
+	assert.Equal(t, fmt.Sprint(alignmentPatternPositions(32)), "[6 34 60 86 112 138]")
+}
+
+func TestEncodeVersion(t *testing.T) {
+	tests := []struct {
+		length  int
+		version int
+	}{
+		{0, 1},
+		{14, 1},
+		{15, 2},
+		{213, 10},
+		{2331, 40},
+	}
+
+	for _, tt := range tests {
+		c, err := Encode(strings.Repeat("a", tt.length))
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, c.version, tt.version)
+		assert.Equal(t, c.Size(), tt.version*4+17)
+	}
+
+	_, err := Encode(strings.Repeat("a", 2332))
+	assert.Equal(t, err, ErrTooLong)
+}
+
+// Codewords read back in zigzag order after removing chosen mask must hold
+// encoded text, and error correction of every block must match its data
+func TestEncodeLayout(t *testing.T) {
+	tests := []struct {
+		text    string
+		version int
+	}{
+		{"otpauth://totp/Forum:john?secret=JBSWY3DP", 3}, // single block
+		{"otpauth://totp/Forum:john%20doe?algorithm=SHA1&digits=6&issuer=Forum&period=30&secret=KONTCCWTPO753RG5ID5RQJJSC26ZR4CX", 7},
+		{strings.Repeat("forum", 60), 13}, // blocks of different length
+	}
+
+	for _, tt := range tests {
+		c, err := Encode(tt.text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, c.version, tt.version)
+
+		checkFunctionPatterns(t, c)
+		mask := readMask(t, c)
+		assert.Equal(t, formatBits(mask), readFormat(c))
+
+		c.applyMask(mask)
+		data := readData(t, c, readCodewords(c))
+
+		// Byte mode and length are followed by the text
+		bits := func(from, n int) int {
+			v := 0
+			for i := from; i < from+n; i++ {
+				v = v<<1 | int(data[i>>3]>>(7-i&7)&1)
+			}
+			return v
+		}
+		count := charCountBits(c.version)
+		assert.Equal(t, bits(0, 4), 0x4)
+		assert.Equal(t, bits(4, count), len(tt.text))
+
+		got := make([]byte, len(tt.text))
+		for i := range got {
+			got[i] = byte(bits(4+count+i*8, 8))
+		}
+		assert.Equal(t, string(got), tt.text)
+	}
+}
+
+func checkFunctionPatterns(t *testing.T, c *Code) {
+	t.Helper()
+
+	// Finder pattern corners, timing pattern and dark module
+	assert.Equal(t, c.Dark(0, 0) && c.Dark(c.size-1, 0) && c.Dark(0, c.size-1), true)
+	assert.Equal(t, c.Dark(1, 1), false)
+	assert.Equal(t, c.Dark(8, 6) && !c.Dark(9, 6), true)
+	assert.Equal(t, c.Dark(8, c.size-8), true)
+
+	// Both copies of version information
+	if c.version >= 7 {
+		var first, second int
+		for i := 0; i < 18; i++ {
+			a, b := c.size-11+i%3, i/3
+			first |= b2i(c.Dark(a, b)) << i
+			second |= b2i(c.Dark(b, a)) << i
+		}
+		assert.Equal(t, first, versionBits(c.version))
+		assert.Equal(t, second, versionBits(c.version))
+	}
+}
+
+// readMask returns mask from format bits, both copies must be the same
+func readMask(t *testing.T, c *Code) int {
+	t.Helper()
+
+	var second int
+	for i := 0; i < 8; i++ {
+		second |= b2i(c.Dark(c.size-1-i, 8)) << i
+	}
+	for i := 8; i < 15; i++ {
+		second |= b2i(c.Dark(8, c.size-15+i)) << i
+	}
+
+	first := readFormat(c)
+	assert.Equal(t, first, second)
+
+	return (first ^ 0x5412) >> 10 & 7
+}
+
+func readFormat(c *Code) int {
+	var bits int
+	for i := 0; i <= 5; i++ {
+		bits |= b2i(c.Dark(8, i)) << i
+	}
+	bits |= b2i(c.Dark(8, 7))<<6 | b2i(c.Dark(8, 8))<<7 | b2i(c.Dark(7, 8))<<8
+	for i := 9; i < 15; i++ {
+		bits |= b2i(c.Dark(14-i, 8)) << i
+	}
+	return bits
+}
+
+// readCodewords reads modules that aren't function patterns in zigzag order
+func readCodewords(c *Code) []byte {
+	var bits []bool
+	for right := c.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < c.size; vert++ {
+			for j := 0; j < 2; j++ {
+				x, y := right-j, vert
+				if (right+1)&2 == 0 {
+					y = c.size - 1 - vert
+				}
+				if !c.isFunction[y][x] {
+					bits = append(bits, c.modules[y][x])
+				}
+			}
+		}
+	}
+
+	codewords := make([]byte, len(bits)/8)
+	for i := range codewords {
+		for j := 0; j < 8; j++ {
+			codewords[i] = codewords[i]<<1 | byte(b2i(bits[i*8+j]))
+		}
+	}
+	return codewords
+}
+
+// readData splits interleaved codewords into blocks, checks error correction
+// of each block and returns data of all blocks. Blocks of the same version
+// differ in length by one data codeword at most, longer blocks go last
+func readData(t *testing.T, c *Code, codewords []byte) []byte {
+	t.Helper()
+
+	numBlocks := numErrorCorrectionBlocks[c.version]
+	eccLen := eccCodewordsPerBlock[c.version]
+	dataLen := numDataCodewords(c.version)
+	numLong := dataLen % numBlocks
+
+	blocks := make([][]byte, numBlocks)
+	k := 0
+	for i := 0; i <= dataLen/numBlocks; i++ {
+		for j := range blocks {
+			if i < dataLen/numBlocks || j >= numBlocks-numLong {
+				blocks[j] = append(blocks[j], codewords[k])
+				k++
+			}
+		}
+	}
+
+	var data []byte
+	divisor := reedSolomonDivisor(eccLen)
+	for j, block := range blocks {
+		ecc := make([]byte, eccLen)
+		for i := range ecc {
+			ecc[i] = codewords[dataLen+i*numBlocks+j]
+		}
+		assert.Equal(t, string(ecc), string(reedSolomonRemainder(block, divisor)))
+		data = append(data, block...)
+	}
+
+	return data
+}
+
+func TestSVG(t *testing.T) {
+	c, err := Encode("hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	svg := c.SVG(4)
+	assert.StringContains(t, svg, `viewBox="0 0 29 29"`)
+	assert.StringContains(t, svg, "M4,4h1v1h-1z")
+}
+
+func b2i(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -1,41 +1,123 @@
 package rate
 
 import (
+	"math"
 	"sync"
 	"time"
 )
 
-type RateLimiter struct {
-	interval  time.Duration
-	limit     int
-	requests  int
-	lastCheck time.Time
+/*
+	Rate is a token bucket rate limiter. Every key (client address, user id,
+	route class...) has its own bucket, so one noisy client doesn't throttle
+	the others.
+*/
+
+// Limit is Rate requests per Period on average with up to Burst requests
+// at once. Burst is Rate if it's not set
+type Limit struct {
+	Rate   int
+	Period time.Duration
+	Burst  int
+}
+
+// Result is the decision for one request
+type Result struct {
+	Allowed bool
+
+	// Limit is size of the bucket and Remaining is number of requests that
+	// can be made right now
+	Limit     int
+	Remaining int
+
+	// RetryAfter is time until the next request is allowed, it's 0 for
+	// allowed requests. Reset is time until the bucket is full again
+	RetryAfter time.Duration
+	Reset      time.Duration
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+type Limiter struct {
+	// Now returns current time, tests replace it
+	Now func() time.Time
+
+	burst     float64
+	perToken  time.Duration // time in which one token is added
+	buckets   map[string]*bucket
+	lastSweep time.Time
 	mu        sync.Mutex
 }
 
-func NewRateLimiter(interval time.Duration, limit int) *RateLimiter {
-	return &RateLimiter{
-		interval:  interval,
-		limit:     limit,
-		requests:  0,
-		lastCheck: time.Now(),
+// NewLimiter returns limiter that allows given limit for every key
+func NewLimiter(limit Limit) *Limiter {
+	burst := limit.Burst
+	if burst <= 0 {
+		burst = limit.Rate
+	}
+
+	return &Limiter{
+		Now:      time.Now,
+		burst:    float64(burst),
+		perToken: limit.Period / time.Duration(limit.Rate),
+		buckets:  make(map[string]*bucket),
 	}
 }
 
-func (rl *RateLimiter) Limit() bool {
-	rl.mu.Lock()
-	defer rl.mu.Unlock()
+// Allow takes token from the bucket of the key. Request is allowed if there
+// was a token
+func (l *Limiter) Allow(key string) Result {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.Now()
+	l.sweep(now)
 
-	if time.Since(rl.lastCheck) > rl.interval {
-		rl.requests = 0
-		rl.lastCheck = time.Now()
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
 	}
 
-	if rl.requests >= rl.limit {
-		return true
+	// Tokens added since the last request, bucket can't overflow
+	b.tokens = math.Min(l.burst, b.tokens+float64(now.Sub(b.last))/float64(l.perToken))
+	b.last = now
+
+	res := Result{Limit: int(l.burst)}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = l.timeFor(1 - b.tokens)
 	}
 
-	rl.requests++
+	res.Remaining = int(b.tokens)
+	res.Reset = l.timeFor(l.burst - b.tokens)
+
+	return res
+}
 
-	return false
+// timeFor returns time in which given number of tokens is added
+func (l *Limiter) timeFor(tokens float64) time.Duration {
+	return time.Duration(math.Ceil(tokens * float64(l.perToken)))
+}
+
+// sweep deletes buckets that are full again. They are the same as buckets
+// of new keys, so limiter doesn't grow with every client it has seen.
+// Caller must hold the lock
+func (l *Limiter) sweep(now time.Time) {
+	refill := l.timeFor(l.burst)
+	if now.Sub(l.lastSweep) < refill {
+		return
+	}
+	l.lastSweep = now
+
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= l.timeFor(l.burst-b.tokens) {
+			delete(l.buckets, key)
+		}
+	}
 }
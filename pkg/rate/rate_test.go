@@ -0,0 +1,101 @@
+package rate
+
+import (
+	"forum/internal/assert"
+	"testing"
+	"time"
+)
+
+// clock is time of tests that moves only when asked
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+func (c *clock) Add(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func newTestLimiter(limit Limit) (*Limiter, *clock) {
+	c := &clock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	l := NewLimiter(limit)
+	l.Now = c.Now
+	return l, c
+}
+
+func TestBurst(t *testing.T) {
+	l, _ := newTestLimiter(Limit{Rate: 1, Period: time.Second, Burst: 3})
+
+	for i := 2; i >= 0; i-- {
+		res := l.Allow("a")
+		assert.Equal(t, res.Allowed, true)
+		assert.Equal(t, res.Remaining, i)
+		assert.Equal(t, res.Limit, 3)
+	}
+
+	res := l.Allow("a")
+	assert.Equal(t, res.Allowed, false)
+	assert.Equal(t, res.Remaining, 0)
+	assert.Equal(t, res.RetryAfter, time.Second)
+	assert.Equal(t, res.Reset, 3*time.Second)
+}
+
+func TestRefill(t *testing.T) {
+	l, c := newTestLimiter(Limit{Rate: 10, Period: time.Minute})
+
+	for i := 0; i < 10; i++ {
+		l.Allow("a")
+	}
+	assert.Equal(t, l.Allow("a").Allowed, false)
+
+	// One token is added every 6 seconds
+	c.Add(3 * time.Second)
+	res := l.Allow("a")
+	assert.Equal(t, res.Allowed, false)
+	assert.Equal(t, res.RetryAfter, 3*time.Second)
+
+	c.Add(3 * time.Second)
+	assert.Equal(t, l.Allow("a").Allowed, true)
+	assert.Equal(t, l.Allow("a").Allowed, false)
+
+	// Bucket doesn't overflow after long pause
+	c.Add(time.Hour)
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, l.Allow("a").Allowed, true)
+	}
+	assert.Equal(t, l.Allow("a").Allowed, false)
+}
+
+func TestKeys(t *testing.T) {
+	l, _ := newTestLimiter(Limit{Rate: 1, Period: time.Second})
+
+	assert.Equal(t, l.Allow("a").Allowed, true)
+	assert.Equal(t, l.Allow("a").Allowed, false)
+
+	// Other clients aren't affected
+	assert.Equal(t, l.Allow("b").Allowed, true)
+}
+
+func TestSweep(t *testing.T) {
+	l, c := newTestLimiter(Limit{Rate: 2, Period: time.Second})
+
+	l.Allow("a")
+	l.Allow("b")
+	l.Allow("b")
+	assert.Equal(t, len(l.buckets), 2)
+
+	// Bucket of "a" is full after 0.5s, bucket of "b" only after 1s
+	c.Add(700 * time.Millisecond)
+	l.Allow("c")
+	c.Add(400 * time.Millisecond)
+	l.Allow("c")
+
+	_, okA := l.buckets["a"]
+	_, okB := l.buckets["b"]
+	assert.Equal(t, okA, false)
+	assert.Equal(t, okB, false)
+	assert.Equal(t, len(l.buckets), 1)
+}
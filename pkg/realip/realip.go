@@ -0,0 +1,186 @@
+package realip
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+/*
+	Realip finds address of the client behind reverse proxies. Forwarding
+	headers are easy to fake, so they are read only from trusted proxies:
+	the chain of addresses is walked from the right (the hop closest to the
+	server) and the first address that isn't a trusted proxy is the client.
+*/
+
+type contextKey struct{}
+
+// Resolver finds address of the client of the request
+type Resolver struct {
+	trusted []netip.Prefix
+}
+
+// New returns resolver that trusts forwarding headers sent by proxies from
+// given networks. Without networks it always returns address of the peer
+func New(trusted []netip.Prefix) *Resolver {
+	return &Resolver{trusted: trusted}
+}
+
+// ParsePrefixes parses comma-separated list of networks in CIDR notation.
+// Single addresses are networks of one address
+func ParsePrefixes(s string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		if !strings.Contains(field, "/") {
+			addr, err := netip.ParseAddr(field)
+			if err != nil {
+				return nil, fmt.Errorf("invalid proxy address %q: %w", field, err)
+			}
+			addr = addr.Unmap()
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy network %q: %w", field, err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+
+	return prefixes, nil
+}
+
+// Middleware saves address of the client in request context, so it can be
+// read with FromRequest
+func (r *Resolver) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ctx := context.WithValue(req.Context(), contextKey{}, r.ClientIP(req))
+		next.ServeHTTP(w, req.WithContext(ctx))
+	})
+}
+
+// FromRequest returns address of the client saved by Middleware. Requests
+// that didn't pass through it get address of the peer
+func FromRequest(req *http.Request) string {
+	if ip, ok := req.Context().Value(contextKey{}).(string); ok {
+		return ip
+	}
+	return peerIP(req)
+}
+
+// ClientIP returns address of the client of the request.
+//
+// Forwarding headers are read only if the peer is a trusted proxy.
+// Forwarded header is preferred over X-Forwarded-For, X-Real-IP is read
+// only if there are none of them
+func (r *Resolver) ClientIP(req *http.Request) string {
+	peer, err := netip.ParseAddr(peerIP(req))
+	if err != nil {
+		return peerIP(req)
+	}
+	peer = peer.Unmap()
+
+	if !r.isTrusted(peer) {
+		return peer.String()
+	}
+
+	hops := forwardedFor(req.Header)
+	if hops == nil {
+		hops = splitList(req.Header.Values("X-Forwarded-For"))
+	}
+	if hops == nil {
+		hops = splitList(req.Header.Values("X-Real-IP"))
+	}
+
+	client := peer
+	for i := len(hops) - 1; i >= 0; i-- {
+		// Address after unknown or obfuscated hop can't be trusted, the
+		// last trusted proxy is the best guess then
+		addr, ok := parseHop(hops[i])
+		if !ok {
+			break
+		}
+
+		client = addr
+		if !r.isTrusted(addr) {
+			break
+		}
+	}
+
+	return client.String()
+}
+
+func (r *Resolver) isTrusted(addr netip.Addr) bool {
+	for _, prefix := range r.trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// peerIP returns address of the peer without port
+func peerIP(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
+
+// forwardedFor returns "for" parameters of Forwarded header (RFC 7239) in
+// order of hops. Hop without "for" parameter is returned as empty string
+func forwardedFor(h http.Header) []string {
+	var hops []string
+
+	for _, elem := range splitList(h.Values("Forwarded")) {
+		hop := ""
+		for _, pair := range strings.Split(elem, ";") {
+			key, val, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if ok && strings.EqualFold(key, "for") {
+				hop = strings.Trim(val, `"`)
+			}
+		}
+		hops = append(hops, hop)
+	}
+
+	return hops
+}
+
+// splitList splits comma-separated values of the header
+func splitList(values []string) []string {
+	var list []string
+
+	for _, v := range values {
+		for _, item := range strings.Split(v, ",") {
+			list = append(list, strings.TrimSpace(item))
+		}
+	}
+
+	return list
+}
+
+// parseHop parses address of the hop that can have port and IPv6 address
+// in brackets
+func parseHop(hop string) (netip.Addr, bool) {
+	if addrPort, err := netip.ParseAddrPort(hop); err == nil {
+		return addrPort.Addr().Unmap(), true
+	}
+
+	addr, err := netip.ParseAddr(strings.TrimSuffix(strings.TrimPrefix(hop, "["), "]"))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+
+	return addr.Unmap(), true
+}
@@ -0,0 +1,126 @@
+package realip
+
+import (
+	"forum/internal/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	trusted, err := ParsePrefixes("10.0.0.0/8, 192.168.1.1, ::1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := New(trusted)
+
+	tests := []struct {
+		name    string
+		remote  string
+		headers map[string]string
+		want    string
+	}{
+		{
+			name:   "direct client",
+			remote: "203.0.113.5:51234",
+			want:   "203.0.113.5",
+		},
+		{
+			name:    "headers of untrusted peer are ignored",
+			remote:  "203.0.113.5:51234",
+			headers: map[string]string{"X-Forwarded-For": "198.51.100.1"},
+			want:    "203.0.113.5",
+		},
+		{
+			name:    "x-forwarded-for",
+			remote:  "10.0.0.2:80",
+			headers: map[string]string{"X-Forwarded-For": "198.51.100.1"},
+			want:    "198.51.100.1",
+		},
+		{
+			name:    "spoofed hop before trusted chain",
+			remote:  "10.0.0.2:80",
+			headers: map[string]string{"X-Forwarded-For": "1.2.3.4, 198.51.100.1, 10.0.0.3"},
+			want:    "198.51.100.1",
+		},
+		{
+			name:    "all hops trusted",
+			remote:  "10.0.0.2:80",
+			headers: map[string]string{"X-Forwarded-For": "10.0.0.4, 192.168.1.1"},
+			want:    "10.0.0.4",
+		},
+		{
+			name:   "forwarded is preferred",
+			remote: "[::1]:80",
+			headers: map[string]string{
+				"Forwarded":       `for=198.51.100.1;proto=https, for="[2001:db8::1]:4711"`,
+				"X-Forwarded-For": "198.51.100.9",
+			},
+			want: "2001:db8::1",
+		},
+		{
+			name:    "obfuscated hop",
+			remote:  "10.0.0.2:80",
+			headers: map[string]string{"Forwarded": "for=198.51.100.1, for=_hidden, for=10.0.0.3"},
+			want:    "10.0.0.3",
+		},
+		{
+			name:    "x-real-ip",
+			remote:  "192.168.1.1:80",
+			headers: map[string]string{"X-Real-IP": "198.51.100.1"},
+			want:    "198.51.100.1",
+		},
+		{
+			name:    "invalid header",
+			remote:  "10.0.0.2:80",
+			headers: map[string]string{"X-Forwarded-For": "not an address"},
+			want:    "10.0.0.2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remote
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+
+			assert.Equal(t, r.ClientIP(req), tt.want)
+		})
+	}
+}
+
+func TestParsePrefixes(t *testing.T) {
+	prefixes, err := ParsePrefixes("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(prefixes), 0)
+
+	if _, err := ParsePrefixes("10.0.0.0/8,nginx"); err == nil {
+		t.Error("expected error for invalid network")
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	trusted, err := ParsePrefixes("10.0.0.0/8")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got string
+	h := New(trusted).Middleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		got = FromRequest(req)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.0.0.2:80"
+	req.Header.Set("X-Forwarded-For", "198.51.100.1")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, got, "198.51.100.1")
+
+	// Request that didn't pass through middleware gets address of the peer
+	assert.Equal(t, FromRequest(req), "10.0.0.2")
+}
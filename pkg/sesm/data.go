@@ -2,9 +2,11 @@ package sesm
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
 	"errors"
 	"fmt"
-	"forum/pkg/sesm/sqlite3store"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -21,31 +23,55 @@ func (sm *SessionManager) Load(ctx context.Context, sessionID string) (context.C
 	}
 
 	if sessionID == "" {
-		return context.WithValue(ctx, sm.ContextKey, newSessionData(sm.Lifetime)), nil
+		return context.WithValue(ctx, sm.ContextKey, sm.newSessionData()), nil
 	}
 
-	userID, userRole, expiry, err := sm.Store.StoreFind(ctx, sessionID)
+	rec, err := sm.Store.StoreFind(ctx, sessionID)
 	if err != nil {
-		if errors.Is(err, sqlite3store.ErrNotFound) {
+		if errors.Is(err, ErrNotFound) {
 			// This is the case when session is deleted but still is in cookie (can be done only by manual deletion)
-			sd := newSessionData(time.Second)
+			sd := sm.newSessionData()
 			sd.status = Destroyed
 			return context.WithValue(ctx, sm.ContextKey, sd), nil
 		}
 		return nil, err
 	}
 
+	values, err := decodeValues(rec.Data)
+	if err != nil {
+		return nil, fmt.Errorf("sesm: decode values: %w", err)
+	}
+
 	sd := &sessionData{
 		sessionID:  sessionID,
-		expiryTime: expiry,
-		userID:     userID,
-		userRole:   userRole,
+		expiryTime: rec.Expiry,
+		deadline:   rec.Deadline,
+		remember:   rec.Remember,
+		userID:     rec.UserID,
+		userRole:   rec.UserRole,
+		values:     values,
+		userAgent:  rec.UserAgent,
+		ip:         rec.IP,
+		createdAt:  rec.CreatedAt,
+		lastSeen:   rec.LastSeen,
 		status:     Unmodified,
 	}
 
 	return context.WithValue(ctx, sm.ContextKey, sd), nil
 }
 
+// csrfTokenKey is the key of CSRF token in session values
+const csrfTokenKey = "sesm.csrfToken"
+
+// createCSRFToken generates random token that can't be guessed by other sites
+func createCSRFToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
 // createSessionID generates session id using uuid package
 func createSessionID() (string, error) {
 	id, err := uuid.NewV4()
@@ -70,28 +96,48 @@ type sessionData struct {
 	status     Status
 	userID     int
 	userRole   string
+	values     map[string]any
+	userAgent  string
+	ip         string
+	createdAt  time.Time
+	lastSeen   time.Time
 	expiryTime time.Time
+	deadline   time.Time
+	remember   bool
 	mu         sync.Mutex
+
+	// CSRF token of guest without saved session read from cookie, and
+	// whether it was created during this request and has to be sent
+	guestToken    string
+	guestTokenNew bool
 }
 
-// newSessionData returs sessionData with given lifetime and default values
-func newSessionData(lifetime time.Duration) *sessionData {
-	return &sessionData{
-		status:     Unmodified,
-		expiryTime: time.Now().Local().Add(lifetime),
+// newSessionData returs sessionData of session that starts now with default
+// values
+func (sm *SessionManager) newSessionData() *sessionData {
+	sd := &sessionData{
+		status: Unmodified,
+		values: make(map[string]any),
 	}
+	sm.start(sd)
+	sd.lastSeen = sd.createdAt
+	return sd
 }
 
-// RenewToken updates token for given context, deleting old one (if exists)
-func (sm *SessionManager) RenewToken(ctx context.Context, userID int) error {
+// RenewToken replaces id of the session, so id that could be known before
+// login can't be used after it. Old session is deleted, other sessions of
+// the user stay, so user can be logged in on several devices
+func (sm *SessionManager) RenewToken(ctx context.Context) error {
 	sd := sm.getSessionDataFromContext(ctx)
 
 	sd.mu.Lock()
 	defer sd.mu.Unlock()
 
-	err := sm.Store.StoreDeleteAll(ctx, userID)
-	if err != nil {
-		return err
+	// Guest session could be saved before login
+	if sd.sessionID != "" {
+		if err := sm.Store.StoreDelete(ctx, sd.sessionID); err != nil {
+			return err
+		}
 	}
 
 	newSessionID, err := createSessionID()
@@ -100,12 +146,44 @@ func (sm *SessionManager) RenewToken(ctx context.Context, userID int) error {
 	}
 
 	sd.sessionID = newSessionID
-	sd.expiryTime = time.Now().Add(sm.Lifetime)
+	sm.start(sd)
 	sd.status = Modified
 
+	// Token could be seen before login, so the new session gets another one
+	delete(sd.values, csrfTokenKey)
+
 	return nil
 }
 
+// PutRemember sets whether session started by the next RenewToken is
+// remembered, that is it lives longer and its cookie is kept after browser
+// is closed.
+//
+// It sets session status to Modified, unless session was destroyed during
+// this request.
+func (sm *SessionManager) PutRemember(ctx context.Context, remember bool) {
+	sd := sm.getSessionDataFromContext(ctx)
+
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+
+	sd.remember = remember
+	sm.modify(sd)
+}
+
+// modify sets session status to Modified, unless session was destroyed
+// during this request. Caller must hold the lock of session data
+func (sm *SessionManager) modify(sd *sessionData) {
+	switch {
+	case sd.status != Destroyed:
+		sd.status = Modified
+	case sd.sessionID == "":
+		// Cookie of deleted or expired session, so new session is started
+		sm.start(sd)
+		sd.status = Modified
+	}
+}
+
 // DeleteToken deletes token from database
 func (sm *SessionManager) DeleteToken(ctx context.Context) error {
 	sd := sm.getSessionDataFromContext(ctx)
@@ -125,6 +203,27 @@ func (sm *SessionManager) DeleteToken(ctx context.Context) error {
 	return nil
 }
 
+// DeleteAllTokens deletes all tokens of given user from database, so the user
+// is logged out on all devices. Current session is destroyed too if it belongs
+// to that user
+func (sm *SessionManager) DeleteAllTokens(ctx context.Context, userID int) error {
+	sd := sm.getSessionDataFromContext(ctx)
+
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+
+	err := sm.Store.StoreDeleteAll(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if sd.userID == userID {
+		sd.status = Destroyed
+	}
+
+	return nil
+}
+
 // Status returns current status of session data
 func (sm *SessionManager) Status(ctx context.Context) Status {
 	sd := sm.getSessionDataFromContext(ctx)
@@ -172,6 +271,83 @@ func (sm *SessionManager) PutUserRoleWithoutStatusChange(ctx context.Context, us
 	sd.status = Unmodified
 }
 
+// CSRFToken returns token that forms of the session have to send back.
+//
+// Token of logged in user or of guest session that is saved anyway is kept
+// in session values, it's created on first use and sets session status to
+// Modified. Other guests get token in cookie instead (double submit), so
+// pages viewed by guests don't create sessions.
+func (sm *SessionManager) CSRFToken(ctx context.Context) (string, error) {
+	sd := sm.getSessionDataFromContext(ctx)
+
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+
+	if token, _ := sd.values[csrfTokenKey].(string); token != "" {
+		return token, nil
+	}
+
+	if !sd.hasStoredToken() && sd.guestToken != "" {
+		return sd.guestToken, nil
+	}
+
+	token, err := createCSRFToken()
+	if err != nil {
+		return "", err
+	}
+
+	if sd.hasStoredToken() {
+		sd.values[csrfTokenKey] = token
+		sm.modify(sd)
+	} else {
+		sd.guestToken = token
+		sd.guestTokenNew = true
+	}
+
+	return token, nil
+}
+
+// VerifyCSRFToken reports whether token sent by form or script is CSRF
+// token of the session. Guest that has no token in session is checked
+// against token from cookie
+func (sm *SessionManager) VerifyCSRFToken(ctx context.Context, token string) bool {
+	sd := sm.getSessionDataFromContext(ctx)
+
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+
+	expected, _ := sd.values[csrfTokenKey].(string)
+	if expected == "" && sd.userID == 0 {
+		expected = sd.guestToken
+	}
+
+	return expected != "" && subtle.ConstantTimeCompare([]byte(token), []byte(expected)) == 1
+}
+
+// hasStoredToken reports whether CSRF token is kept in session values, that
+// is when session belongs to user or is saved for another reason. Caller
+// must hold the lock of session data
+func (sd *sessionData) hasStoredToken() bool {
+	if sd.status == Destroyed {
+		return false
+	}
+	return sd.userID != 0 || sd.sessionID != "" || sd.status == Modified
+}
+
+// newGuestToken returns CSRF token of guest if it was created during this
+// request and has to be saved in cookie
+func (sm *SessionManager) newGuestToken(ctx context.Context) string {
+	sd := sm.getSessionDataFromContext(ctx)
+
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+
+	if !sd.guestTokenNew {
+		return ""
+	}
+	return sd.guestToken
+}
+
 // GetUserRole reads userRole from session data.
 func (sm *SessionManager) GetUserRole(ctx context.Context) string {
 	sd := sm.getSessionDataFromContext(ctx)
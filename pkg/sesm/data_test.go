@@ -0,0 +1,115 @@
+package sesm
+
+import (
+	"context"
+	"forum/internal/assert"
+	"testing"
+)
+
+func TestCSRFTokenGuest(t *testing.T) {
+	sm := New()
+	sm.Store = memStore{}
+
+	ctx, err := sm.Load(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := sm.CSRFToken(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Token goes to cookie, so there is no session to save
+	assert.Equal(t, sm.Status(ctx), Unmodified)
+	assert.Equal(t, sm.newGuestToken(ctx), token)
+	assert.Equal(t, sm.VerifyCSRFToken(ctx, token), true)
+	assert.Equal(t, sm.VerifyCSRFToken(ctx, "wrong"), false)
+
+	// The next request brings the token back in cookie
+	ctx, err = sm.Load(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sm.getSessionDataFromContext(ctx).guestToken = token
+
+	again, err := sm.CSRFToken(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, again, token)
+	assert.Equal(t, sm.newGuestToken(ctx), "")
+	assert.Equal(t, sm.VerifyCSRFToken(ctx, ""), false)
+}
+
+func TestCSRFTokenSession(t *testing.T) {
+	sm := New()
+	sm.Store = memStore{}
+
+	ctx, err := sm.Load(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sm.PutUserID(ctx, 1)
+
+	token, err := sm.CSRFToken(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, sm.newGuestToken(ctx), "")
+
+	id, _, err := sm.commit(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, err = sm.Load(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Token of user can't be replaced by guest cookie
+	sm.getSessionDataFromContext(ctx).guestToken = "guest"
+
+	again, err := sm.CSRFToken(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, again, token)
+	assert.Equal(t, sm.Status(ctx), Unmodified)
+	assert.Equal(t, sm.VerifyCSRFToken(ctx, token), true)
+	assert.Equal(t, sm.VerifyCSRFToken(ctx, "guest"), false)
+}
+
+func TestRenewTokenReplacesCSRFToken(t *testing.T) {
+	sm := New()
+	sm.Store = memStore{}
+
+	ctx, err := sm.Load(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sm.PutUserID(ctx, 1)
+
+	before, err := sm.CSRFToken(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sm.Put(ctx, "name", "bob")
+	if err := sm.RenewToken(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	after, err := sm.CSRFToken(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if before == after {
+		t.Errorf("got the same CSRF token after RenewToken")
+	}
+	assert.Equal(t, sm.GetString(ctx, "name"), "bob")
+}
@@ -0,0 +1,137 @@
+package sesm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"time"
+)
+
+// lastSeenInterval is how often last use of the session is saved, so
+// sessions aren't written to store on every request
+const lastSeenInterval = time.Minute
+
+// Session is session of the user shown on the list of active sessions. It's
+// identified by handle, because session id must stay secret. Times are in
+// local time zone
+type Session struct {
+	Handle    string
+	UserAgent string
+	IP        string
+	CreatedAt time.Time
+	LastSeen  time.Time
+	Expiry    time.Time
+	Current   bool // session of the request
+}
+
+// touch saves device of the request in session data and updates time of
+// last use.
+//
+// It sets status of saved session to Modified when device changed or last
+// use was saved long ago.
+func (sm *SessionManager) touch(ctx context.Context, ip, userAgent string) {
+	sd := sm.getSessionDataFromContext(ctx)
+
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+
+	changed := sd.ip != ip || sd.userAgent != userAgent
+	stale := time.Since(sd.lastSeen) > lastSeenInterval
+
+	sd.ip, sd.userAgent = ip, userAgent
+	if !changed && !stale {
+		return
+	}
+	sd.lastSeen = time.Now().UTC()
+
+	if sd.sessionID != "" && sd.status == Unmodified {
+		sd.status = Modified
+	}
+}
+
+// ListSessions returns unexpired sessions of the user, current one first and
+// then recently used ones
+func (sm *SessionManager) ListSessions(ctx context.Context, userID int) ([]Session, error) {
+	records, err := sm.Store.StoreFindAll(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	current := sm.getSessionDataFromContext(ctx).sessionID
+
+	sessions := make([]Session, 0, len(records))
+	for id, rec := range records {
+		sessions = append(sessions, Session{
+			Handle:    sessionHandle(id),
+			UserAgent: rec.UserAgent,
+			IP:        rec.IP,
+			CreatedAt: rec.CreatedAt.Local(),
+			LastSeen:  rec.LastSeen.Local(),
+			Expiry:    rec.Expiry.Local(),
+			Current:   id == current,
+		})
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		if sessions[i].Current != sessions[j].Current {
+			return sessions[i].Current
+		}
+		return sessions[i].LastSeen.After(sessions[j].LastSeen)
+	})
+
+	return sessions, nil
+}
+
+// RevokeSession deletes session of the user with given handle. Current
+// session is destroyed too if it's revoked.
+//
+// It returns ErrNotFound if user has no such session.
+func (sm *SessionManager) RevokeSession(ctx context.Context, userID int, handle string) error {
+	records, err := sm.Store.StoreFindAll(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	for id := range records {
+		if sessionHandle(id) != handle {
+			continue
+		}
+
+		sd := sm.getSessionDataFromContext(ctx)
+		if id == sd.sessionID {
+			return sm.DeleteToken(ctx)
+		}
+		return sm.Store.StoreDelete(ctx, id)
+	}
+
+	return ErrNotFound
+}
+
+// RevokeOtherSessions deletes all sessions of the user except current one
+func (sm *SessionManager) RevokeOtherSessions(ctx context.Context, userID int) error {
+	records, err := sm.Store.StoreFindAll(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	current := sm.getSessionDataFromContext(ctx).sessionID
+
+	for id := range records {
+		if id == current {
+			continue
+		}
+		if err := sm.Store.StoreDelete(ctx, id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sessionHandle returns identifier of the session that can be shown in
+// pages, session id can't be derived from it
+func sessionHandle(sessionID string) string {
+	sum := sha256.Sum256([]byte(sessionID))
+	return hex.EncodeToString(sum[:16])
+}
@@ -3,6 +3,7 @@ package sesm
 import (
 	"context"
 	"log"
+	"net"
 	"net/http"
 	"time"
 )
@@ -13,21 +14,52 @@ import (
 */
 
 type SessionManager struct {
-	Store      Store
-	Lifetime   time.Duration
+	Store Store
+
+	// IdleTimeout is how long session lives without requests and Lifetime
+	// is how long it lives at most
+	IdleTimeout time.Duration
+	Lifetime    time.Duration
+
+	// Timeouts of sessions started with "remember me". Cookie of such
+	// sessions is kept after browser is closed
+	RememberIdleTimeout time.Duration
+	RememberLifetime    time.Duration
+
 	CookieName string
 	ContextKey contextKey
+
+	// CSRFCookieName is the cookie that keeps CSRF token of guests without
+	// saved session, see CSRFToken
+	CSRFCookieName string
+
+	// ClientIP returns address of the client that is saved with session
+	ClientIP func(req *http.Request) string
 }
 
 // New returns pointer to new SessionManager struct
 func New() *SessionManager {
 	return &SessionManager{
-		Lifetime:   12 * time.Hour,
-		ContextKey: generateContextKey(),
-		CookieName: "session",
+		IdleTimeout:         2 * time.Hour,
+		Lifetime:            12 * time.Hour,
+		RememberIdleTimeout: 14 * 24 * time.Hour,
+		RememberLifetime:    30 * 24 * time.Hour,
+		ContextKey:          generateContextKey(),
+		CookieName:          "session",
+		CSRFCookieName:      "csrf_token",
+		ClientIP:            RemoteIP,
 	}
 }
 
+// RemoteIP returns address of the client that connected to the server
+func RemoteIP(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
+
 // LoadAndSave is a middleware that loads session from cookie puts it
 // to the request's context.
 func (sm *SessionManager) LoadAndSave(next http.Handler) http.Handler {
@@ -48,6 +80,13 @@ func (sm *SessionManager) LoadAndSave(next http.Handler) http.Handler {
 			return
 		}
 
+		sm.touch(ctx, sm.ClientIP(req), req.UserAgent())
+		sm.extend(ctx)
+
+		if cookie, err := req.Cookie(sm.CSRFCookieName); err == nil {
+			sm.getSessionDataFromContext(ctx).guestToken = cookie.Value
+		}
+
 		sessionReq := req.WithContext(ctx)
 
 		sessionWriter := &sessionWriter{
@@ -74,26 +113,29 @@ func (sm *SessionManager) commitAndWriteSessionCookie(w http.ResponseWriter, r *
 
 	switch sm.Status(ctx) {
 	case Modified:
-		token, expiry, err := sm.commit(ctx)
+		token, rec, err := sm.commit(ctx)
 		if err != nil {
 			log.Print("Commit session:", err.Error())
 			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 			return
 		}
 
-		sm.writeSessionCookie(w, token, expiry)
+		sm.writeSessionCookie(w, token, rec.Expiry, rec.Remember)
 	case Destroyed:
-		sm.writeSessionCookie(w, "", time.Time{})
+		sm.writeSessionCookie(w, "", time.Time{}, false)
 	}
 
+	if token := sm.newGuestToken(ctx); token != "" {
+		sm.writeCSRFCookie(w, token)
+	}
 }
 
 // commit attempts to save changes in database.
 //
 // If given context doesn't hold session it creates new one.
 //
-// It retures saved token, expiration date and error (if encountered).
-func (sm *SessionManager) commit(ctx context.Context) (string, time.Time, error) {
+// It retures saved token, saved record and error (if encountered).
+func (sm *SessionManager) commit(ctx context.Context) (string, Record, error) {
 	sd := sm.getSessionDataFromContext(ctx)
 
 	sd.mu.Lock()
@@ -102,24 +144,42 @@ func (sm *SessionManager) commit(ctx context.Context) (string, time.Time, error)
 	if sd.sessionID == "" {
 		var err error
 		if sd.sessionID, err = createSessionID(); err != nil {
-			return "", time.Time{}, err
+			return "", Record{}, err
 		}
+		sd.createdAt = time.Now().UTC()
 	}
 
-	userID := sd.userID
-	userRole := sd.userRole
-	expiry := sd.expiryTime
+	data, err := encodeValues(sd.values)
+	if err != nil {
+		return "", Record{}, err
+	}
 
-	if err := sm.Store.StoreCommit(ctx, sd.sessionID, userID, userRole, expiry); err != nil {
-		return "", time.Time{}, err
+	rec := Record{
+		UserID:    sd.userID,
+		UserRole:  sd.userRole,
+		Expiry:    sd.expiryTime,
+		Deadline:  sd.deadline,
+		Remember:  sd.remember,
+		Data:      data,
+		UserAgent: sd.userAgent,
+		IP:        sd.ip,
+		CreatedAt: sd.createdAt,
+		LastSeen:  sd.lastSeen,
 	}
 
-	return sd.sessionID, expiry, nil
+	if err := sm.Store.StoreCommit(ctx, sd.sessionID, rec); err != nil {
+		return "", Record{}, err
+	}
+
+	return sd.sessionID, rec, nil
 }
 
 // writeSessionCookie creates new cookie and and saves it in response.
+//
+// Only persistent cookie gets expiration date, other cookies are deleted
+// by browser when it's closed. Zero expiry deletes cookie.
 func (sm *SessionManager) writeSessionCookie(w http.ResponseWriter,
-	token string, expiry time.Time) {
+	token string, expiry time.Time, persistent bool) {
 
 	cookie := &http.Cookie{
 		Name:     sm.CookieName,
@@ -128,21 +188,88 @@ func (sm *SessionManager) writeSessionCookie(w http.ResponseWriter,
 		HttpOnly: true,
 		SameSite: http.SameSiteLaxMode,
 		Path:     "/",
-		// I don't know why but chrome shows expiration date for 6 hours earlier
-		// and so I put expiry to 6 hours later to keep same expiration date
-		// as in database
-		Expires: expiry.Add(6 * time.Hour),
 	}
 
-	if expiry.IsZero() {
+	switch {
+	case expiry.IsZero():
 		cookie.Expires = time.Unix(1, 0)
 		cookie.MaxAge = -1
+	case persistent:
+		cookie.Expires = expiry
 	}
 
 	w.Header().Add("Set-Cookie", cookie.String())
 	w.Header().Add("Cache-Control", `no-cache="Set-Cookie"`)
 }
 
+// timeouts returns idle timeout and lifetime of the session
+func (sm *SessionManager) timeouts(remember bool) (time.Duration, time.Duration) {
+	if remember {
+		return sm.RememberIdleTimeout, sm.RememberLifetime
+	}
+	return sm.IdleTimeout, sm.Lifetime
+}
+
+// start sets timeouts of the session that starts now. Caller must hold the
+// lock of session data
+func (sm *SessionManager) start(sd *sessionData) {
+	idle, lifetime := sm.timeouts(sd.remember)
+
+	now := time.Now().UTC()
+	sd.createdAt = now
+	sd.expiryTime = now.Add(idle)
+	sd.deadline = now.Add(lifetime)
+}
+
+// extend moves expiry of the session forward when less than half of idle
+// timeout is left, so session isn't saved on every request. Expiry isn't
+// moved past deadline of the session.
+//
+// It sets status of saved session to Modified when expiry is moved.
+func (sm *SessionManager) extend(ctx context.Context) {
+	sd := sm.getSessionDataFromContext(ctx)
+
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+
+	if sd.sessionID == "" || sd.status == Destroyed {
+		return
+	}
+
+	idle, _ := sm.timeouts(sd.remember)
+	now := time.Now().UTC()
+
+	if sd.expiryTime.Sub(now) > idle/2 {
+		return
+	}
+
+	expiry := now.Add(idle)
+	if !sd.deadline.IsZero() && expiry.After(sd.deadline) {
+		expiry = sd.deadline
+	}
+	if !expiry.After(sd.expiryTime) {
+		return
+	}
+
+	sd.expiryTime = expiry
+	sd.status = Modified
+}
+
+// writeCSRFCookie saves CSRF token of guest in cookie. It isn't readable
+// by scripts, pages get the token from template data
+func (sm *SessionManager) writeCSRFCookie(w http.ResponseWriter, token string) {
+	cookie := &http.Cookie{
+		Name:     sm.CSRFCookieName,
+		Value:    token,
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+		Path:     "/",
+	}
+
+	w.Header().Add("Set-Cookie", cookie.String())
+}
+
 // sessionWriter overrides ResponseWriter's methods to save changes
 // in session data before executing Write or WriteHeader methods
 // (essentially, when handler finishes it's work)
@@ -4,12 +4,11 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"forum/pkg/sesm"
 	"log"
 	"time"
 )
 
-var ErrNotFound = errors.New("sqlite3store: session not found")
-
 type SQLite3Store struct {
 	db *sql.DB
 }
@@ -20,37 +19,86 @@ func New(db *sql.DB) *SQLite3Store {
 	return s
 }
 
-func (s *SQLite3Store) StoreFind(ctx context.Context, sessionID string) (int, string, time.Time, error) {
+func (s *SQLite3Store) StoreFind(ctx context.Context, sessionID string) (sesm.Record, error) {
 	query := `
-		SELECT user_id, user_role, expiry 
-		FROM sessions 
-		WHERE session_id = $1 AND datetime('now', 'localtime') < expiry
+		SELECT COALESCE(user_id, 0), user_role, expiry, deadline, remember, data, COALESCE(user_agent, ''), COALESCE(ip, ''),
+			created_at, last_seen
+		FROM sessions
+		WHERE session_id = $1 AND datetime('now') < expiry
 	`
-	var userID int
-	var userRole string
-	var expiry time.Time
-	row := s.db.QueryRow(query, sessionID)
 
-	err := row.Scan(&userID, &userRole, &expiry)
+	rec, err := scanRecord(s.db.QueryRow(query, sessionID))
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return 0, "", time.Time{}, ErrNotFound
+		if errors.Is(err, sql.ErrNoRows) {
+			return sesm.Record{}, sesm.ErrNotFound
 		}
-		return 0, "", time.Time{}, err
+		return sesm.Record{}, err
 	}
 
-	return userID, userRole, expiry, nil
+	return rec, nil
 }
 
-func (s *SQLite3Store) StoreCommit(ctx context.Context, sessionID string, userID int, userRole string, expiry time.Time) error {
+func (s *SQLite3Store) StoreFindAll(ctx context.Context, userID int) (map[string]sesm.Record, error) {
 	query := `
-		REPLACE INTO sessions (session_id, user_role, user_id, expiry) 
-		VALUES($1, $2, $3, datetime($4))
+		SELECT session_id, COALESCE(user_id, 0), user_role, expiry, deadline, remember, data, COALESCE(user_agent, ''),
+			COALESCE(ip, ''), created_at, last_seen
+		FROM sessions
+		WHERE user_id = $1 AND datetime('now') < expiry
 	`
 
-	formattedExpiry := expiry.Format("2006-01-02T15:04:05.999")
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := make(map[string]sesm.Record)
+
+	for rows.Next() {
+		var sessionID string
+		rec, err := scanRecord(rows, &sessionID)
+		if err != nil {
+			return nil, err
+		}
+		records[sessionID] = rec
+	}
+
+	return records, rows.Err()
+}
+
+// scanner is implemented by both sql.Row and sql.Rows
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// scanRecord scans columns of session record, they may follow given columns
+func scanRecord(row scanner, dest ...any) (sesm.Record, error) {
+	var rec sesm.Record
+	var deadline, createdAt, lastSeen sql.NullTime
+
+	dest = append(dest, &rec.UserID, &rec.UserRole, &rec.Expiry, &deadline, &rec.Remember, &rec.Data, &rec.UserAgent, &rec.IP,
+		&createdAt, &lastSeen)
+
+	if err := row.Scan(dest...); err != nil {
+		return sesm.Record{}, err
+	}
+	rec.Deadline, rec.CreatedAt, rec.LastSeen = deadline.Time, createdAt.Time, lastSeen.Time
+
+	return rec, nil
+}
+
+// StoreCommit saves session. Guest sessions (needed to keep state of external
+// login) are saved without user. Times are saved in UTC
+func (s *SQLite3Store) StoreCommit(ctx context.Context, sessionID string, rec sesm.Record) error {
+	query := `
+		REPLACE INTO sessions (session_id, user_role, user_id, expiry, deadline, remember, data, user_agent, ip, created_at,
+			last_seen) 
+		VALUES($1, $2, NULLIF($3, 0), datetime($4), datetime($5), $6, $7, NULLIF($8, ''), NULLIF($9, ''), datetime($10),
+			datetime($11))
+	`
 
-	_, err := s.db.Exec(query, sessionID, userRole, userID, formattedExpiry)
+	_, err := s.db.Exec(query, sessionID, rec.UserRole, rec.UserID, formatTime(rec.Expiry), formatTime(rec.Deadline), rec.Remember,
+		rec.Data, rec.UserAgent, rec.IP, formatTime(rec.CreatedAt), formatTime(rec.LastSeen))
 	if err != nil {
 		return err
 	}
@@ -58,6 +106,15 @@ func (s *SQLite3Store) StoreCommit(ctx context.Context, sessionID string, userID
 	return nil
 }
 
+// formatTime formats time in UTC for datetime function. Zero time is saved
+// as NULL, it's unknown for sessions created before devices were recorded
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format("2006-01-02T15:04:05.999")
+}
+
 func (s *SQLite3Store) StoreDeleteAll(ctx context.Context, userID int) error {
 	query := `
 		DELETE FROM sessions WHERE user_id = $1
@@ -74,6 +131,24 @@ func (s *SQLite3Store) StoreDelete(ctx context.Context, sessionID string) error
 	return err
 }
 
+// PurgeAll deletes every session and returns number of deleted sessions
+func (s *SQLite3Store) PurgeAll(ctx context.Context) (int64, error) {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM sessions")
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// PurgeExpired deletes expired sessions and returns number of deleted sessions
+func (s *SQLite3Store) PurgeExpired(ctx context.Context) (int64, error) {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM sessions WHERE expiry < datetime('now')")
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
 func (s *SQLite3Store) startCleanup(interval time.Duration) {
 	ticker := time.NewTicker(interval)
 	for range ticker.C {
@@ -85,6 +160,6 @@ func (s *SQLite3Store) startCleanup(interval time.Duration) {
 }
 
 func (s *SQLite3Store) deleteExpired() error {
-	_, err := s.db.Exec("DELETE FROM sessions WHERE expiry < datetime('now', 'localtime')")
+	_, err := s.db.Exec("DELETE FROM sessions WHERE expiry < datetime('now')")
 	return err
 }
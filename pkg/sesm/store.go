@@ -2,12 +2,40 @@ package sesm
 
 import (
 	"context"
+	"errors"
 	"time"
 )
 
+// ErrNotFound is returned by store when session doesn't exist or is expired
+var ErrNotFound = errors.New("sesm: session not found")
+
+// Record is session data saved in store. UserID is 0 for guest sessions
+type Record struct {
+	UserID   int
+	UserRole string
+
+	// Expiry is moved forward while session is used, but never past
+	// Deadline. Remembered sessions have longer timeouts
+	Expiry   time.Time
+	Deadline time.Time
+	Remember bool
+
+	// Values put in session, encoded by session manager
+	Data []byte
+
+	// Device of the session, when it was created and last used
+	UserAgent string
+	IP        string
+	CreatedAt time.Time
+	LastSeen  time.Time
+}
+
 type Store interface {
-	StoreFind(context.Context, string) (int, string, time.Time, error)
-	StoreCommit(context.Context, string, int, string, time.Time) error
-	StoreDeleteAll(context.Context, int) error
-	StoreDelete(context.Context, string) error
+	StoreFind(ctx context.Context, sessionID string) (Record, error)
+	StoreCommit(ctx context.Context, sessionID string, rec Record) error
+	StoreDeleteAll(ctx context.Context, userID int) error
+	StoreDelete(ctx context.Context, sessionID string) error
+
+	// StoreFindAll returns unexpired sessions of the user by their ids
+	StoreFindAll(ctx context.Context, userID int) (map[string]Record, error)
 }
@@ -0,0 +1,139 @@
+package sesm
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+)
+
+// Put saves value under the key in session data. Values are encoded with
+// gob, so types other than basic ones must be registered with gob.Register.
+//
+// It sets session status to Modified, unless session was destroyed during
+// this request.
+func (sm *SessionManager) Put(ctx context.Context, key string, val any) {
+	sd := sm.getSessionDataFromContext(ctx)
+
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+
+	sd.values[key] = val
+	sm.modify(sd)
+}
+
+// Get returns value saved under the key or nil if there is no such value
+func (sm *SessionManager) Get(ctx context.Context, key string) any {
+	sd := sm.getSessionDataFromContext(ctx)
+
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+
+	return sd.values[key]
+}
+
+// GetString returns string saved under the key or empty string if there is
+// no such string
+func (sm *SessionManager) GetString(ctx context.Context, key string) string {
+	s, _ := sm.Get(ctx, key).(string)
+	return s
+}
+
+// GetInt returns int saved under the key or 0 if there is no such int
+func (sm *SessionManager) GetInt(ctx context.Context, key string) int {
+	n, _ := sm.Get(ctx, key).(int)
+	return n
+}
+
+// GetBool returns bool saved under the key or false if there is no such bool
+func (sm *SessionManager) GetBool(ctx context.Context, key string) bool {
+	b, _ := sm.Get(ctx, key).(bool)
+	return b
+}
+
+// Exists reports whether there is value under the key
+func (sm *SessionManager) Exists(ctx context.Context, key string) bool {
+	sd := sm.getSessionDataFromContext(ctx)
+
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+
+	_, ok := sd.values[key]
+	return ok
+}
+
+// Pop returns value saved under the key and removes it, so it can be read
+// only once. It returns nil if there is no such value.
+//
+// It sets session status to Modified if value is removed, unless session was
+// destroyed during this request.
+func (sm *SessionManager) Pop(ctx context.Context, key string) any {
+	sd := sm.getSessionDataFromContext(ctx)
+
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+
+	return sm.pop(sd, key)
+}
+
+// PopString returns string saved under the key and removes it. It returns
+// empty string if there is no such string
+func (sm *SessionManager) PopString(ctx context.Context, key string) string {
+	s, _ := sm.Pop(ctx, key).(string)
+	return s
+}
+
+// Remove deletes value saved under the key.
+//
+// It sets session status to Modified if value is removed, unless session was
+// destroyed during this request.
+func (sm *SessionManager) Remove(ctx context.Context, key string) {
+	sd := sm.getSessionDataFromContext(ctx)
+
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+
+	sm.pop(sd, key)
+}
+
+// pop removes value under the key and returns it. Caller must hold the lock
+// of session data
+func (sm *SessionManager) pop(sd *sessionData, key string) any {
+	val, ok := sd.values[key]
+	if !ok {
+		return nil
+	}
+
+	delete(sd.values, key)
+	sm.modify(sd)
+
+	return val
+}
+
+// encodeValues encodes values of the session for store. Session without
+// values is saved without data
+func encodeValues(values map[string]any) ([]byte, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(values); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// decodeValues decodes values of the session encoded by encodeValues
+func decodeValues(data []byte) (map[string]any, error) {
+	values := make(map[string]any)
+	if len(data) == 0 {
+		return values, nil
+	}
+
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&values); err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
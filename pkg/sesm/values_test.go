@@ -0,0 +1,157 @@
+package sesm
+
+import (
+	"context"
+	"encoding/gob"
+	"forum/internal/assert"
+	"testing"
+)
+
+// memStore keeps sessions in memory
+type memStore map[string]Record
+
+func (s memStore) StoreFind(ctx context.Context, sessionID string) (Record, error) {
+	rec, ok := s[sessionID]
+	if !ok {
+		return Record{}, ErrNotFound
+	}
+	return rec, nil
+}
+
+func (s memStore) StoreCommit(ctx context.Context, sessionID string, rec Record) error {
+	s[sessionID] = rec
+	return nil
+}
+
+func (s memStore) StoreDeleteAll(ctx context.Context, userID int) error {
+	for id, rec := range s {
+		if rec.UserID == userID {
+			delete(s, id)
+		}
+	}
+	return nil
+}
+
+func (s memStore) StoreDelete(ctx context.Context, sessionID string) error {
+	delete(s, sessionID)
+	return nil
+}
+
+func (s memStore) StoreFindAll(ctx context.Context, userID int) (map[string]Record, error) {
+	records := make(map[string]Record)
+	for id, rec := range s {
+		if rec.UserID == userID {
+			records[id] = rec
+		}
+	}
+	return records, nil
+}
+
+type point struct {
+	X, Y int
+}
+
+func init() {
+	gob.Register(point{})
+}
+
+func TestValues(t *testing.T) {
+	sm := New()
+	sm.Store = memStore{}
+
+	ctx, err := sm.Load(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sm.Put(ctx, "name", "bob")
+	sm.Put(ctx, "count", 3)
+	sm.Put(ctx, "admin", true)
+	sm.Put(ctx, "point", point{1, 2})
+
+	id, _, err := sm.commit(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Values are read from store by the next request
+	ctx, err = sm.Load(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, sm.GetString(ctx, "name"), "bob")
+	assert.Equal(t, sm.GetInt(ctx, "count"), 3)
+	assert.Equal(t, sm.GetBool(ctx, "admin"), true)
+	assert.Equal(t, sm.Get(ctx, "point").(point), point{1, 2})
+	assert.Equal(t, sm.Status(ctx), Unmodified)
+
+	// Value of another type reads as zero value
+	assert.Equal(t, sm.GetInt(ctx, "name"), 0)
+	assert.Equal(t, sm.GetString(ctx, "missing"), "")
+
+	assert.Equal(t, sm.PopString(ctx, "name"), "bob")
+	assert.Equal(t, sm.Exists(ctx, "name"), false)
+	assert.Equal(t, sm.Status(ctx), Modified)
+
+	sm.Remove(ctx, "count")
+
+	_, _, err = sm.commit(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, err = sm.Load(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, sm.Exists(ctx, "name"), false)
+	assert.Equal(t, sm.Exists(ctx, "count"), false)
+	assert.Equal(t, sm.GetBool(ctx, "admin"), true)
+}
+
+func TestPopMissing(t *testing.T) {
+	sm := New()
+	sm.Store = memStore{}
+
+	ctx, err := sm.Load(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, sm.Pop(ctx, "missing"), nil)
+	sm.Remove(ctx, "missing")
+
+	// Nothing was removed, so there is nothing to save
+	assert.Equal(t, sm.Status(ctx), Unmodified)
+}
+
+func TestPutKeepsDestroyedSession(t *testing.T) {
+	sm := New()
+	sm.Store = memStore{}
+
+	ctx, err := sm.Load(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sm.Put(ctx, "name", "bob")
+	id, _, err := sm.commit(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, err = sm.Load(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := sm.DeleteToken(ctx); err != nil {
+		t.Fatal(err)
+	}
+	sm.Put(ctx, "name", "alice")
+
+	// Logged out session must not be saved again
+	assert.Equal(t, sm.Status(ctx), Destroyed)
+}
@@ -0,0 +1,80 @@
+package totp
+
+import (
+	"encoding/base32"
+	"forum/internal/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test vectors of RFC 6238 (SHA1)
+func TestCode(t *testing.T) {
+	key := []byte("12345678901234567890")
+
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, code(key, Counter(time.Unix(tt.unix, 0)), 8), tt.code)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	now := time.Unix(59, 0)
+
+	current, err := Code(secret, Counter(now))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, current, "287082")
+
+	counter, ok := Validate(secret, current, now)
+	assert.Equal(t, ok, true)
+	assert.Equal(t, counter, Counter(now))
+
+	// Code of previous period is accepted, older ones aren't
+	_, ok = Validate(secret, current, now.Add(Period))
+	assert.Equal(t, ok, true)
+	_, ok = Validate(secret, current, now.Add(2*Period))
+	assert.Equal(t, ok, false)
+
+	// Secret is accepted in lower case and with spaces, as users type it
+	_, ok = Validate(strings.ToLower(secret[:4]+" "+secret[4:]), current, now)
+	assert.Equal(t, ok, true)
+
+	_, ok = Validate(secret, "28708", now)
+	assert.Equal(t, ok, false)
+	_, ok = Validate("not base32!", current, now)
+	assert.Equal(t, ok, false)
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("Forum", "john doe", "JBSWY3DPEHPK3PXP")
+
+	assert.StringContains(t, uri, "otpauth://totp/Forum:john%20doe?")
+	assert.StringContains(t, uri, "secret=JBSWY3DPEHPK3PXP")
+	assert.StringContains(t, uri, "issuer=Forum")
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, len(secret), 32)
+
+	_, err = Code(secret, 1)
+	assert.Equal(t, err, nil)
+}
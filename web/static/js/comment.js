@@ -1,33 +1,37 @@
 document.addEventListener('DOMContentLoaded', function () {
-    var form = document.getElementById('commentForm');
+    // Main comment form and reply forms under every comment
+    var forms = document.querySelectorAll('form.comment-form');
 
-    form.addEventListener('submit', function (event) {
-        event.preventDefault();
+    forms.forEach(function (form) {
+        form.addEventListener('submit', function (event) {
+            event.preventDefault();
 
-        var formData = new FormData(form);
+            var formData = new FormData(form);
 
-        var xhr = new XMLHttpRequest();
-        xhr.open('POST', form.action);
+            var xhr = new XMLHttpRequest();
+            xhr.open('POST', form.action);
 
-        
-        xhr.setRequestHeader('Content-Type', 'application/x-www-form-urlencoded');
 
-        xhr.onload = function () {
-            if (xhr.status === 200) {
-                console.log(xhr.responseText)
-                window.location.href = xhr.responseText;
-            } else {
-                console.error('Request failed. Status: ' + xhr.status);
-                var errorMsg = form.querySelector('.error-msg');
-                if (errorMsg) {
-                    errorMsg.textContent = xhr.responseText; 
+            xhr.setRequestHeader('Content-Type', 'application/x-www-form-urlencoded');
+            xhr.setRequestHeader('X-CSRF-Token', document.querySelector('meta[name="csrf-token"]').content);
+
+            xhr.onload = function () {
+                if (xhr.status === 200) {
+                    console.log(xhr.responseText)
+                    window.location.href = xhr.responseText;
+                } else {
+                    console.error('Request failed. Status: ' + xhr.status);
+                    var errorMsg = form.querySelector('.error-msg');
+                    if (errorMsg) {
+                        errorMsg.textContent = xhr.responseText;
+                    }
                 }
-            }
-        };
+            };
+
 
-        
-        var encodedFormData = new URLSearchParams(formData).toString();
+            var encodedFormData = new URLSearchParams(formData).toString();
 
-        xhr.send(encodedFormData);
+            xhr.send(encodedFormData);
+        });
     });
 });
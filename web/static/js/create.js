@@ -8,6 +8,7 @@ document.addEventListener('DOMContentLoaded', function () {
 
         var xhr = new XMLHttpRequest();
         xhr.open('POST', form.action);
+        xhr.setRequestHeader('X-CSRF-Token', document.querySelector('meta[name="csrf-token"]').content);
 
         xhr.onload = function () {
             if (xhr.status === 200) {
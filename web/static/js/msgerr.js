@@ -2,12 +2,14 @@ function LoginErr(form) {
     var xhr = new XMLHttpRequest();
     xhr.open("POST", "/user/login", true);
     xhr.setRequestHeader("Content-Type", "application/x-www-form-urlencoded");
+    xhr.setRequestHeader("X-CSRF-Token", document.querySelector('meta[name="csrf-token"]').content);
     xhr.onreadystatechange = function () {
         if (xhr.readyState === 4) {
             if (xhr.status === 200) {
                 
                 console.log('OK');
-                window.location.href = "/"; 
+                // Login may continue on another page, e.g. two-factor code
+                window.location.href = xhr.responseURL || "/"; 
             } else {
                 console.log(xhr.status);
                 var errorMsg = form.querySelector('.error-msg');
@@ -36,6 +38,7 @@ function RegErr(form) {
     var xhr = new XMLHttpRequest();
     xhr.open("POST", "/user/signup", true);
     xhr.setRequestHeader("Content-Type", "application/x-www-form-urlencoded");
+    xhr.setRequestHeader("X-CSRF-Token", document.querySelector('meta[name="csrf-token"]').content);
     xhr.onreadystatechange = function () {
         if (xhr.readyState === 4) {
             if (xhr.status === 200) {
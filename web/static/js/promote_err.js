@@ -5,7 +5,8 @@ document.addEventListener('DOMContentLoaded', function () {
 
     function sendRequest() {
         var xhr = new XMLHttpRequest();
-        xhr.open('GET', promoteLink.href, true);
+        xhr.open('POST', promoteLink.href, true);
+        xhr.setRequestHeader('X-CSRF-Token', document.querySelector('meta[name="csrf-token"]').content);
         xhr.onload = function () {
           
             var errorElement = document.getElementById('small-err');